@@ -2,19 +2,30 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/moyu-x/classified-file/internal"
 	"github.com/moyu-x/classified-file/internal/app"
+	"github.com/moyu-x/classified-file/pkg/classifier"
 	"github.com/moyu-x/classified-file/pkg/config"
 	"github.com/spf13/cobra"
 )
 
 var classifyCmd = &cobra.Command{
-	Use:   "classify <directories...> <destination>",
+	Use:   "classify <directories...> <destination> | --files-from <file|-> <destination>",
 	Short: "按文件类型分类文件",
 	Long: `遍历指定目录中的所有文件，使用 filetype 判断文件类型，并将文件归类写入目标目录。
 每种文件类型一个文件夹，每个类型中每 500 个文件作为一个目录。
-文件名重复时自动重命名（添加自增序列）。`,
-	Args: cobra.MinimumNArgs(2),
+使用 --layout 可按日期组织目录，如 {category}/{year}/{month}，日期优先取自 EXIF 拍摄时间或视频创建时间。
+默认复制文件，使用 --mode 可改为移动、硬链接或符号链接。
+内容无法识别的文件依次按扩展名和文本内容推断类型，仍无法识别时放入 unknown 目录。
+使用 --rename 可按模板重命名文件，如 {date:2006-01-02}_{time}_{hash8}.{ext}。
+使用 --template 可为分类指定路径模板，如 audio=audio/{artist}/{album}/{track:02} - {title}.{ext}，
+音频标签取自 ID3、FLAC/Ogg 的 Vorbis 注释和 MP4 元数据，没有标签的文件仍按 --layout 和 --rename 归类。
+使用 --fix-extensions 时，扩展名与内容不符或缺少扩展名的文件在目标目录中使用识别出的扩展名。
+文件名重复时自动重命名（添加自增序列）。
+使用 --files-from 时从文件或标准输入读取待分类的文件列表，只需指定目标目录。`,
+	Args: cobra.MinimumNArgs(1),
 	RunE: runClassify,
 }
 
@@ -29,14 +40,60 @@ func runClassify(cmd *cobra.Command, args []string) error {
 
 	filesPerDir, _ := cmd.Flags().GetInt("files-per-dir")
 	verbose, _ := cmd.Flags().GetBool("verbose")
+	mode, _ := cmd.Flags().GetString("mode")
+	layout := stringFlag(cmd, "layout", cfg.Classify.Layout)
+	rename := stringFlag(cmd, "rename", cfg.Classify.Rename)
+	templates, err := categoryTemplates(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	unknown := stringFlag(cmd, "unknown", cfg.Classify.Unknown)
+	fixExt := cfg.Classify.FixExtensions
+	if cmd.Flags().Changed("fix-extensions") {
+		fixExt, _ = cmd.Flags().GetBool("fix-extensions")
+	}
+	skipUnknown := cfg.Classify.SkipUnknown
+	if cmd.Flags().Changed("skip-unknown") {
+		skipUnknown, _ = cmd.Flags().GetBool("skip-unknown")
+	}
+	if skipUnknown {
+		unknown = ""
+	} else if unknown == "" {
+		return fmt.Errorf("--unknown 不能为空，跳过未知类型文件请使用 --skip-unknown")
+	}
+	scanOpts, err := scannerOptions(cmd, cfg)
+	if err != nil {
+		return err
+	}
+
+	if scanOpts.FilesFrom != "" && len(sourceDirs) > 0 {
+		return fmt.Errorf("使用 --files-from 时只需指定目标目录")
+	}
+	if scanOpts.FilesFrom == "" && len(sourceDirs) == 0 {
+		return fmt.Errorf("至少需要指定一个源目录和一个目标目录")
+	}
+
+	switch internal.ClassifyMode(mode) {
+	case internal.ClassifyCopy, internal.ClassifyMove, internal.ClassifyHardlink, internal.ClassifySymlink:
+	default:
+		return fmt.Errorf("无效的 --mode 取值: %s（可选 copy、move、hardlink 或 symlink）", mode)
+	}
 
 	opts := &app.ClassifyOptions{
 		SourceDirs:  sourceDirs,
 		DestDir:     destDir,
 		FilesPerDir: filesPerDir,
+		Mode:        internal.ClassifyMode(mode),
+		Categories:  categoryRules(cfg),
+		Layout:      layout,
+		Rename:      rename,
+		Templates:   templates,
+		FixExt:      fixExt,
+		Unknown:     unknown,
 		Verbose:     verbose,
 		LogLevel:    cfg.Logging.Level,
 		LogFile:     cfg.Logging.File,
+		Scan:        scanOpts,
 	}
 
 	stats, err := app.RunClassify(opts)
@@ -46,12 +103,54 @@ func runClassify(cmd *cobra.Command, args []string) error {
 
 	fmt.Println(stats.String())
 
-	return nil
+	return checkStrict(cmd, stats.ScanErrors)
+}
+
+// categoryRules 将配置文件中的自定义分类转换为分类规则
+func categoryRules(cfg *config.Config) []classifier.Rule {
+	rules := make([]classifier.Rule, 0, len(cfg.Classify.Categories))
+	for _, c := range cfg.Classify.Categories {
+		rules = append(rules, classifier.Rule{
+			Name:       c.Name,
+			MIME:       c.MIME,
+			Extensions: c.Extensions,
+			Globs:      c.Globs,
+			Priority:   c.Priority,
+		})
+	}
+	return rules
+}
+
+// categoryTemplates 合并配置文件和 --template 参数中的分类路径模板，参数覆盖同一分类的配置
+func categoryTemplates(cmd *cobra.Command, cfg *config.Config) (map[string]string, error) {
+	templates := make(map[string]string, len(cfg.Classify.Templates))
+	for category, tmpl := range cfg.Classify.Templates {
+		templates[classifier.CategoryKey(category)] = tmpl
+	}
+
+	values, _ := cmd.Flags().GetStringArray("template")
+	for _, value := range values {
+		category, tmpl, ok := strings.Cut(value, "=")
+		if !ok || strings.TrimSpace(category) == "" {
+			return nil, fmt.Errorf("无效的 --template 取值: %s（格式为 分类=路径模板）", value)
+		}
+		templates[classifier.CategoryKey(category)] = tmpl
+	}
+	return templates, nil
 }
 
 func init() {
 	classifyCmd.Flags().Int("files-per-dir", 500, "每个目录的文件数（默认: 500）")
 	classifyCmd.Flags().Bool("verbose", false, "显示详细日志")
+	classifyCmd.Flags().StringP("mode", "m", "copy", "操作模式: copy 复制、move 移动、hardlink 硬链接或 symlink 符号链接")
+	classifyCmd.Flags().String("layout", classifier.DefaultLayout, "目标目录布局，可用 {category}、{year}、{month}、{day}、{date:格式} 和 {part}")
+	classifyCmd.Flags().String("rename", "", "文件名模板，如 {date:2006-01-02}_{time}_{hash8}.{ext}，为空时保留原文件名")
+	classifyCmd.Flags().StringArray("template", nil, "分类专用的路径模板，格式为 分类=模板，如 audio=audio/{artist}/{album}/{track:02} - {title}.{ext}，可重复指定")
+	classifyCmd.Flags().Bool("fix-extensions", false, "扩展名与内容不符或缺少扩展名时使用按内容识别出的扩展名（默认使用配置文件）")
+	classifyCmd.Flags().String("unknown", "unknown", "无法识别类型的文件放入的分类目录")
+	classifyCmd.Flags().Bool("skip-unknown", false, "跳过无法识别类型的文件，保留在原位置")
+	addScannerFlags(classifyCmd)
+	addWatchFlags(classifyCmd)
 
 	rootCmd.AddCommand(classifyCmd)
 }
@@ -8,15 +8,17 @@ import (
 	"github.com/moyu-x/classified-file/pkg/deduplicator"
 	"github.com/moyu-x/classified-file/internal"
 	"github.com/moyu-x/classified-file/pkg/logger"
+	"github.com/moyu-x/classified-file/pkg/scanner"
 	"github.com/spf13/cobra"
 )
 
 var dedupCmd = &cobra.Command{
-	Use:   "dedup <directories...>",
+	Use:   "dedup <directories...> | --files-from <file|->",
 	Short: "检测并删除/移动重复文件",
 	Long: `遍历指定目录中的所有文件，使用 xxHash 计算哈希值并检测重复文件。
-重复文件将被删除或移动到指定目录，哈希值存储在 SQLite 数据库中。`,
-	Args: cobra.MinimumNArgs(1),
+重复文件将被删除或移动到指定目录，哈希值存储在 SQLite 数据库中。
+使用 --files-from 时从文件或标准输入读取待处理的文件列表，不再遍历目录。`,
+	Args: cobra.ArbitraryArgs,
 	RunE: runDedup,
 }
 
@@ -32,6 +34,48 @@ func runDedup(cmd *cobra.Command, args []string) error {
 	dryRun, _ := cmd.Flags().GetBool("dry-run")
 	resume, _ := cmd.Flags().GetBool("resume")
 	reset, _ := cmd.Flags().GetBool("reset")
+	dbPath, _ := cmd.Flags().GetString("db")
+	dbBackend, _ := cmd.Flags().GetString("db-backend")
+	wait, _ := cmd.Flags().GetBool("wait")
+	stream, _ := cmd.Flags().GetBool("stream")
+	noCount, _ := cmd.Flags().GetBool("no-count")
+	archives, _ := cmd.Flags().GetBool("archives")
+	dirs, _ := cmd.Flags().GetString("dirs")
+	scanOpts, err := scannerOptions(cmd, cfg)
+	if err != nil {
+		return err
+	}
+
+	if scanOpts.FilesFrom != "" {
+		if len(args) > 0 {
+			return fmt.Errorf("使用 --files-from 时不能再指定目录")
+		}
+		if resume || reset {
+			return fmt.Errorf("--files-from 不支持 --resume 和 --reset")
+		}
+		if dirs != "" {
+			return fmt.Errorf("--files-from 不支持 --dirs")
+		}
+	} else if len(args) == 0 {
+		return fmt.Errorf("至少需要指定一个目录，或使用 --files-from 读取文件列表")
+	}
+
+	if scanOpts.Watch && internal.DirMode(dirs) == internal.DirsReport {
+		return fmt.Errorf("--watch 不能与 --dirs report 同时使用")
+	}
+
+	switch internal.DirMode(dirs) {
+	case "", internal.DirsReport, internal.DirsApply:
+	default:
+		return fmt.Errorf("无效的 --dirs 取值: %s（可选 report 或 apply）", dirs)
+	}
+
+	countMode := internal.CountUpfront
+	if noCount {
+		countMode = internal.CountNone
+	} else if stream {
+		countMode = internal.CountStream
+	}
 
 	opts := &app.DedupOptions{
 		SourceDirs: args,
@@ -41,8 +85,15 @@ func runDedup(cmd *cobra.Command, args []string) error {
 		DryRun:     dryRun,
 		Resume:     resume,
 		Reset:      reset,
+		DBPath:     dbPath,
+		DBBackend:  dbBackend,
+		Wait:       wait,
+		CountMode:  countMode,
+		Archives:   archives,
+		DirMode:    internal.DirMode(dirs),
 		LogLevel:   cfg.Logging.Level,
 		LogFile:    cfg.Logging.File,
+		Scan:       scanOpts,
 	}
 
 	stats, err := app.RunDedup(opts)
@@ -52,7 +103,7 @@ func runDedup(cmd *cobra.Command, args []string) error {
 
 	printFinalStats(stats, args)
 
-	return nil
+	return checkStrict(cmd, stats.ScanErrors)
 }
 
 func init() {
@@ -61,42 +112,75 @@ func init() {
 	dedupCmd.Flags().StringP("mode", "m", "delete", "操作模式: delete 或 move")
 	dedupCmd.Flags().StringP("target-dir", "t", "", "移动模式的目标目录")
 	dedupCmd.Flags().String("db", "", "数据库路径")
+	dedupCmd.Flags().String("db-backend", "", "存储后端: sqlite、bolt 或 memory（默认使用配置文件）")
 	dedupCmd.Flags().String("log-level", "info", "日志级别")
 	dedupCmd.Flags().BoolP("verbose", "v", false, "显示哈希值（默认显示文件详情）")
 	dedupCmd.Flags().Bool("dry-run", false, "预览模式，不实际修改文件")
 	dedupCmd.Flags().BoolP("resume", "r", false, "恢复模式：跳过已扫描的文件")
 	dedupCmd.Flags().BoolP("reset", "R", false, "重置模式：清除进度文件，重新扫描")
+	dedupCmd.Flags().Bool("wait", false, "数据库或目录被其他进程锁定时等待释放，而不是立即退出")
+	dedupCmd.Flags().Bool("stream", false, "流式扫描：边发现边处理，总数实时更新，不再预先统计文件数量")
+	dedupCmd.Flags().Bool("no-count", false, "不统计文件总数，单次遍历直接处理（进度显示为 [n/?]）")
+	dedupCmd.Flags().Bool("archives", false, "索引 zip、tar、tar.gz、tar.bz2 压缩包中的文件，已保存在压缩包中的散落文件按重复处理（压缩包本身不会被修改）")
+	dedupCmd.Flags().String("dirs", "", "目录级去重: report 只报告内容重复的目录和包含关系，apply 按 --mode 整体删除或移动完全相同的目录后继续逐文件去重")
+	addScannerFlags(dedupCmd)
+	addWatchFlags(dedupCmd)
 
 	rootCmd.AddCommand(dedupCmd)
 }
 
-func formatBytes(bytes int64) string {
-	const unit = 1024
-	if bytes < unit {
-		return fmt.Sprintf("%d B", bytes)
-	}
-	div, exp := int64(unit), 0
-	for n := bytes / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
-	}
-	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
-}
-
 func printFinalStats(stats *internal.ProcessStats, dirs []string) {
 	elapsed := stats.EndTime.Sub(stats.StartTime)
 
 	logger.Get().Info().Msg("========== 处理完成 ==========")
-	logger.Get().Info().Msgf("扫描目录数: %d", len(dirs))
-	for i, dir := range dirs {
-		logger.Get().Info().Msgf("  [%d] %s", i+1, dir)
+	// 使用 --files-from 时没有扫描目录
+	if len(dirs) > 0 {
+		logger.Get().Info().Msgf("扫描目录数: %d", len(dirs))
+		for i, dir := range dirs {
+			logger.Get().Info().Msgf("  [%d] %s", i+1, dir)
+		}
 	}
 	logger.Get().Info().Msgf("总文件数: %d", stats.TotalProcessed)
 	logger.Get().Info().Msgf("新增记录: %d 个文件", stats.Added)
+	logger.Get().Info().Msgf("已有记录: %d 个文件", stats.AlreadyIndexed)
 	logger.Get().Info().Msgf("重复文件: %d 个文件", stats.Deleted+stats.Moved)
 	logger.Get().Info().Msgf("  - 已删除: %d 个", stats.Deleted)
 	logger.Get().Info().Msgf("  - 已移动: %d 个", stats.Moved)
-	logger.Get().Info().Msgf("释放空间: %s", formatBytes(stats.FreedSpace))
+	logger.Get().Info().Msgf("  - 硬链接跳过: %d 个", stats.HardLinked)
+	logger.Get().Info().Msgf("硬链接组: %d 组", len(stats.HardLinkSets))
+	logger.Get().Info().Msgf("释放空间: %s", internal.FormatBytes(stats.FreedSpace))
+	if stats.DanglingLinks > 0 {
+		logger.Get().Info().Msgf("悬空符号链接: %d 个", stats.DanglingLinks)
+	}
+	if stats.ScanErrors > 0 {
+		logger.Get().Info().Msgf("跳过（错误）: %d 个", stats.ScanErrors)
+	}
+	if stats.Busy > 0 {
+		logger.Get().Info().Msgf("跳过（正在写入）: %d 个", stats.Busy)
+	}
+	if stats.Archives > 0 {
+		logger.Get().Info().Msgf("压缩包: %d 个，成员 %d 个，其中 %d 个与其他文件重复（仅报告）",
+			stats.Archives, stats.ArchiveMembers, stats.ArchiveDups)
+	}
+	for i, group := range stats.DuplicateDirs {
+		label := "内容相同"
+		if group.NamesDiffer {
+			label = "内容相同，文件名不同"
+		}
+		logger.Get().Info().Msgf("重复目录组 [%d] (%d 个文件, %s, %s):", i+1, group.Files, internal.FormatBytes(group.Size), label)
+		for _, path := range group.Paths {
+			logger.Get().Info().Msgf("  %s", path)
+		}
+	}
+	for _, pair := range stats.SupersetDirs {
+		logger.Get().Info().Msgf("目录包含: %s 包含 %s 的全部 %d 个文件", pair.Superset, pair.Subset, pair.Files)
+	}
+	if stats.DirsRemoved > 0 {
+		logger.Get().Info().Msgf("重复目录: 已删除或移动 %d 个", stats.DirsRemoved)
+	}
+	for _, kind := range scanner.SkippedKinds(stats.Skipped) {
+		logger.Get().Info().Msgf("跳过（%s）: %d 个", scanner.SkipLabel(kind), stats.Skipped[kind])
+	}
 	logger.Get().Info().Msgf("总耗时: %v", elapsed)
 	logger.Get().Info().Msg("============================")
 }
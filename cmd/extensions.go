@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/moyu-x/classified-file/internal/app"
+	"github.com/moyu-x/classified-file/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+var checkExtensionsCmd = &cobra.Command{
+	Use:   "check-extensions <directories...> | --files-from <file|->",
+	Short: "检查扩展名与文件内容是否一致",
+	Long: `按文件开头的魔数识别文件的真实类型，列出扩展名与内容不符（如实际为 PNG 或 WebP 的 .jpg）
+或缺少扩展名的文件。同一格式的常见写法和基于同一容器的格式（如 .jpeg、.apk、.nef）视为一致，
+无法按内容识别的文件和 ELF 等通常没有扩展名的可执行文件不检查。
+使用 --rename 将这些文件重命名为识别出的扩展名，目标文件已存在时添加自增序号。`,
+	RunE: runCheckExtensions,
+}
+
+func runCheckExtensions(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	rename, _ := cmd.Flags().GetBool("rename")
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	scanOpts, err := scannerOptions(cmd, cfg)
+	if err != nil {
+		return err
+	}
+
+	if scanOpts.FilesFrom != "" && len(args) > 0 {
+		return fmt.Errorf("使用 --files-from 时不能再指定目录")
+	}
+	if scanOpts.FilesFrom == "" && len(args) == 0 {
+		return fmt.Errorf("至少需要指定一个目录")
+	}
+
+	stats, err := app.RunCheckExtensions(&app.CheckExtensionsOptions{
+		Dirs:     args,
+		Rename:   rename,
+		Verbose:  verbose,
+		LogLevel: cfg.Logging.Level,
+		LogFile:  cfg.Logging.File,
+		Scan:     scanOpts,
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := range stats.Mismatches {
+		fmt.Println(stats.Mismatches[i].String())
+	}
+	fmt.Println(stats.String())
+
+	return checkStrict(cmd, stats.ScanErrors)
+}
+
+func init() {
+	checkExtensionsCmd.Flags().Bool("rename", false, "将扩展名不符或缺少扩展名的文件重命名为识别出的扩展名")
+	checkExtensionsCmd.Flags().Bool("verbose", false, "显示详细日志")
+	addScannerFlags(checkExtensionsCmd)
+
+	rootCmd.AddCommand(checkExtensionsCmd)
+}
@@ -36,9 +36,70 @@ func runInit(cmd *cobra.Command, args []string) error {
 
 database:
   path: "~/.classified-file/hashes.db"
+  # 存储后端: sqlite、bolt（大规模目录）或 memory（一次性扫描）
+  backend: "sqlite"
 
 scanner:
+  # 是否跟随符号链接（会检测循环链接）
   follow_symlinks: false
+  # 是否包含隐藏文件和目录（以 . 开头）
+  include_hidden: true
+  # 除 .classifiedignore 外是否同时遵循 .gitignore
+  gitignore: false
+  # 并发读取目录的数量，网络挂载等高延迟文件系统上可适当调大
+  workers: 1
+  # 并发遍历时是否保持与顺序遍历相同的处理顺序（决定重复文件中保留哪一个）
+  ordered: true
+  # 是否只扫描根目录所在的文件系统（不进入挂载点）
+  one_file_system: false
+  # 文件最后一次修改后需保持不变的时间（如 2s），为空时不检查
+  stable_for: ""
+  # 是否跳过被其他进程以写方式打开的文件（仅 Linux）
+  check_open: false
+
+# 文件过滤（dedup 和 classify 共用，命令行参数会追加或覆盖这里的设置）
+filter:
+  # glob 模式，支持 **；不含 / 的模式匹配文件名，含 / 的模式匹配相对扫描目录的路径
+  include: []
+  exclude: []
+  # 匹配完整路径的正则表达式
+  include_regex: []
+  exclude_regex: []
+  # 排除的目录名
+  exclude_dirs: []
+  # 文件大小，如 10K、1.5MB、2G
+  min_size: ""
+  max_size: ""
+  # 文件年龄（按修改时间），如 12h、7d、2w
+  min_age: ""
+  max_age: ""
+
+# 文件分类的自定义规则（classify 使用），按 priority 从大到小匹配，相同时先于内置规则
+# mime 支持通配符（如 font/*），extensions 为按内容识别出的扩展名，globs 匹配文件名（不区分大小写）
+# 内容无法识别的文件依次按扩展名和文本内容推断类型，未匹配的已知类型归入 other
+classify:
+  categories: []
+  # - name: "ebooks"
+  #   mime: ["application/epub+zip"]
+  #   globs: ["*.mobi", "*.azw3"]
+  # - name: "raw-photos"
+  #   extensions: ["cr2", "nef", "dng"]
+  #   priority: 10
+  # 目标目录布局，可用 {category}、{year}、{month}、{day}、{date:2006-01-02} 和 {part}（每 files-per-dir 个文件一个 part_NNNN 目录，只能放在最后）
+  # 日期依次取自 EXIF 拍摄时间（图片）、容器创建时间（MP4/MOV）和修改时间
+  layout: "{category}/{part}"
+  # 文件名模板，如 "{date:2006-01-02}_{time}_{hash8}.{ext}"，为空时保留原文件名
+  rename: ""
+  # 各分类专用的路径模板（目录布局/文件名模板），优先于 layout 和 rename
+  # 可用音频标签 {artist}、{albumartist}、{album}、{title}、{track:02}、{disc}，没有标签的文件仍使用 layout 和 rename
+  templates: {}
+  #   audio: "audio/{artist}/{album}/{track:02} - {title}.{ext}"
+  # 扩展名与内容不符（如实际为 PNG 的 .jpg）或缺少扩展名时，目标文件使用按内容识别出的扩展名
+  fix_extensions: false
+  # 仍无法识别类型的文件放入的分类目录
+  unknown: "unknown"
+  # 是否跳过无法识别类型的文件（保留在原位置）
+  skip_unknown: false
 
 logging:
   level: "info"
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/moyu-x/classified-file/internal/app"
+	"github.com/moyu-x/classified-file/pkg/config"
+	"github.com/moyu-x/classified-file/pkg/filter"
+	"github.com/moyu-x/classified-file/pkg/watcher"
+	"github.com/spf13/cobra"
+)
+
+// addScannerFlags 为 dedup、classify 和 check-extensions 添加共用的目录遍历参数
+func addScannerFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("follow-symlinks", false, "跟随符号链接（默认使用配置文件）")
+	cmd.Flags().Bool("include-hidden", true, "包含隐藏文件和目录（默认使用配置文件）")
+	cmd.Flags().Bool("gitignore", false, "除 .classifiedignore 外同时遵循 .gitignore（默认使用配置文件）")
+	cmd.Flags().Bool("one-file-system", false, "只扫描根目录所在的文件系统，不进入挂载点（默认使用配置文件）")
+	cmd.Flags().Int("workers", 1, "并发读取目录的数量，大于 1 时并行遍历子目录（默认使用配置文件）")
+	cmd.Flags().Bool("ordered", true, "并发遍历时保持顺序遍历的处理顺序（默认使用配置文件）")
+
+	cmd.Flags().StringArray("include", nil, "只处理匹配的文件，glob 模式，支持 **（可重复）")
+	cmd.Flags().StringArray("exclude", nil, "排除匹配的文件和目录，glob 模式，支持 **（可重复）")
+	cmd.Flags().StringArray("include-regex", nil, "只处理完整路径匹配正则表达式的文件（可重复）")
+	cmd.Flags().StringArray("exclude-regex", nil, "排除完整路径匹配正则表达式的文件（可重复）")
+	cmd.Flags().StringArray("exclude-dir", nil, "排除的目录名，支持 glob（可重复）")
+	cmd.Flags().String("min-size", "", "最小文件大小，如 10K、1.5MB")
+	cmd.Flags().String("max-size", "", "最大文件大小，如 100MB、2G")
+	cmd.Flags().String("min-age", "", "最小文件年龄（按修改时间），如 30m、12h、7d")
+	cmd.Flags().String("max-age", "", "最大文件年龄（按修改时间），如 7d、2w")
+
+	cmd.Flags().String("files-from", "", "从文件读取待处理的文件列表而不遍历目录，- 表示标准输入")
+	cmd.Flags().BoolP("null", "0", false, "文件列表以 NUL 分隔（配合 find -print0 使用）")
+
+	cmd.Flags().String("stable-for", "", "文件最后一次修改后需保持不变的时间，未满的文件在本轮扫描结束后复查，仍有变化则跳过，如 2s（默认使用配置文件）")
+	cmd.Flags().Bool("check-open", false, "跳过被其他进程以写方式打开的文件，仅 Linux（默认使用配置文件）")
+
+	cmd.Flags().String("error-report", "", "将被跳过的文件和目录（路径、操作、错误码）以 JSON 写入指定文件")
+	cmd.Flags().Bool("strict", false, "严格模式：有任何文件或目录因错误被跳过时以非零状态退出")
+}
+
+// addWatchFlags 为支持监视模式的 dedup 和 classify 添加监视参数
+func addWatchFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("watch", false, "首次扫描完成后继续监视目录，处理新建或移入的文件，Ctrl+C 结束")
+	cmd.Flags().Duration("settle", watcher.DefaultSettle, "监视模式下文件最后一次变化后等待的时间，避免处理仍在写入的文件")
+}
+
+// scannerOptions 合并配置文件和命令行参数：开关和数值参数命令行显式指定时优先，列表参数追加到配置文件之后
+func scannerOptions(cmd *cobra.Command, cfg *config.Config) (app.ScanOptions, error) {
+	opts := app.ScanOptions{
+		FollowSymlinks: cfg.Scanner.FollowSymlinks,
+		IncludeHidden:  cfg.Scanner.IncludeHidden,
+		UseGitIgnore:   cfg.Scanner.GitIgnore,
+		Workers:        cfg.Scanner.Workers,
+		OneFileSystem:  cfg.Scanner.OneFileSystem,
+		Ordered:        cfg.Scanner.Ordered,
+		CheckOpen:      cfg.Scanner.CheckOpen,
+	}
+
+	if cmd.Flags().Changed("follow-symlinks") {
+		opts.FollowSymlinks, _ = cmd.Flags().GetBool("follow-symlinks")
+	}
+	if cmd.Flags().Changed("include-hidden") {
+		opts.IncludeHidden, _ = cmd.Flags().GetBool("include-hidden")
+	}
+	if cmd.Flags().Changed("gitignore") {
+		opts.UseGitIgnore, _ = cmd.Flags().GetBool("gitignore")
+	}
+	if cmd.Flags().Changed("one-file-system") {
+		opts.OneFileSystem, _ = cmd.Flags().GetBool("one-file-system")
+	}
+	if cmd.Flags().Changed("workers") {
+		opts.Workers, _ = cmd.Flags().GetInt("workers")
+	}
+	if cmd.Flags().Changed("ordered") {
+		opts.Ordered, _ = cmd.Flags().GetBool("ordered")
+	}
+	if cmd.Flags().Changed("check-open") {
+		opts.CheckOpen, _ = cmd.Flags().GetBool("check-open")
+	}
+
+	opts.Filter.Include = appendFlag(cmd, "include", cfg.Filter.Include)
+	opts.Filter.Exclude = appendFlag(cmd, "exclude", cfg.Filter.Exclude)
+	opts.Filter.IncludeRegex = appendFlag(cmd, "include-regex", cfg.Filter.IncludeRegex)
+	opts.Filter.ExcludeRegex = appendFlag(cmd, "exclude-regex", cfg.Filter.ExcludeRegex)
+	opts.Filter.ExcludeDirs = appendFlag(cmd, "exclude-dir", cfg.Filter.ExcludeDirs)
+
+	opts.ErrorReport, _ = cmd.Flags().GetString("error-report")
+	opts.FilesFrom, _ = cmd.Flags().GetString("files-from")
+	opts.NullDelimited, _ = cmd.Flags().GetBool("null")
+	// 没有注册监视参数的命令（check-extensions）取零值
+	opts.Watch, _ = cmd.Flags().GetBool("watch")
+	opts.Settle, _ = cmd.Flags().GetDuration("settle")
+	if opts.Watch && opts.FilesFrom != "" {
+		return opts, fmt.Errorf("--watch 不能与 --files-from 同时使用")
+	}
+
+	var err error
+	if opts.Filter.MinSize, err = filter.ParseSize(stringFlag(cmd, "min-size", cfg.Filter.MinSize)); err != nil {
+		return opts, err
+	}
+	if opts.Filter.MaxSize, err = filter.ParseSize(stringFlag(cmd, "max-size", cfg.Filter.MaxSize)); err != nil {
+		return opts, err
+	}
+	if opts.Filter.MinAge, err = filter.ParseAge(stringFlag(cmd, "min-age", cfg.Filter.MinAge)); err != nil {
+		return opts, err
+	}
+	if opts.Filter.MaxAge, err = filter.ParseAge(stringFlag(cmd, "max-age", cfg.Filter.MaxAge)); err != nil {
+		return opts, err
+	}
+	if opts.StableFor, err = filter.ParseAge(stringFlag(cmd, "stable-for", cfg.Scanner.StableFor)); err != nil {
+		return opts, err
+	}
+
+	return opts, nil
+}
+
+func appendFlag(cmd *cobra.Command, name string, base []string) []string {
+	values, _ := cmd.Flags().GetStringArray(name)
+	return append(append([]string{}, base...), values...)
+}
+
+func stringFlag(cmd *cobra.Command, name, fallback string) string {
+	if !cmd.Flags().Changed(name) {
+		return fallback
+	}
+	value, _ := cmd.Flags().GetString(name)
+	return value
+}
+
+// checkStrict 严格模式下有文件被跳过时返回错误
+func checkStrict(cmd *cobra.Command, scanErrors int) error {
+	strict, _ := cmd.Flags().GetBool("strict")
+	if strict && scanErrors > 0 {
+		return fmt.Errorf("严格模式：%d 个文件或目录因错误被跳过", scanErrors)
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package app
 import (
 	"fmt"
 
+	"github.com/moyu-x/classified-file/internal"
 	"github.com/moyu-x/classified-file/pkg/classifier"
 	"github.com/moyu-x/classified-file/pkg/logger"
 )
@@ -11,9 +12,17 @@ type ClassifyOptions struct {
 	SourceDirs  []string
 	DestDir     string
 	FilesPerDir int
+	Mode        internal.ClassifyMode
+	Categories  []classifier.Rule // 自定义分类规则，优先于内置规则
+	Layout      string            // 目标目录布局，为空时使用默认布局
+	Rename      string            // 文件名模板，为空时保留原文件名
+	Templates   map[string]string // 各分类专用的路径模板
+	FixExt      bool              // 是否修正与内容不符或缺少的扩展名
+	Unknown     string            // 无法识别类型的文件放入的分类，为空时跳过
 	Verbose     bool
 	LogLevel    string
 	LogFile     string
+	Scan        ScanOptions
 }
 
 func RunClassify(opts *ClassifyOptions) (*classifier.ClassifierStats, error) {
@@ -28,16 +37,90 @@ func RunClassify(opts *ClassifyOptions) (*classifier.ClassifierStats, error) {
 
 	logger.Get().Info().Msg("加载配置完成")
 
-	logger.Get().Info().Msgf("源目录数: %d", len(opts.SourceDirs))
-	for i, dir := range opts.SourceDirs {
-		logger.Get().Info().Msgf("  [%d] %s", i+1, dir)
+	if opts.Scan.FilesFrom == "" {
+		logger.Get().Info().Msgf("源目录数: %d", len(opts.SourceDirs))
+		for i, dir := range opts.SourceDirs {
+			logger.Get().Info().Msgf("  [%d] %s", i+1, dir)
+		}
 	}
 	logger.Get().Info().Msgf("目标目录: %s", opts.DestDir)
 
+	walker, err := newWalker(opts.Scan)
+	if err != nil {
+		return nil, err
+	}
+
 	cls := classifier.NewClassifierWithCustomFilesPerDir(opts.FilesPerDir)
+	cls.SetWalker(walker)
+	if opts.Mode != "" {
+		cls.SetMode(opts.Mode)
+		logger.Get().Info().Msgf("操作模式: %s", opts.Mode)
+	}
+	if len(opts.Categories) > 0 {
+		rules, err := classifier.NewRules(classifier.WithDefaults(opts.Categories))
+		if err != nil {
+			return nil, fmt.Errorf("加载分类规则失败: %w", err)
+		}
+		cls.SetRules(rules)
+		logger.Get().Info().Msgf("自定义分类规则: %d", len(opts.Categories))
+	}
+	if opts.Layout != "" {
+		layout, err := classifier.ParseLayout(opts.Layout)
+		if err != nil {
+			return nil, fmt.Errorf("解析目录布局失败: %w", err)
+		}
+		cls.SetLayout(layout)
+		logger.Get().Info().Msgf("目录布局: %s", layout)
+	}
+	if opts.Rename != "" {
+		naming, err := classifier.ParseNameTemplate(opts.Rename)
+		if err != nil {
+			return nil, fmt.Errorf("解析文件名模板失败: %w", err)
+		}
+		cls.SetNameTemplate(naming)
+		logger.Get().Info().Msgf("文件名模板: %s", naming)
+	}
+	if len(opts.Templates) > 0 {
+		templates := make(map[string]*classifier.PathTemplate, len(opts.Templates))
+		for category, s := range opts.Templates {
+			tmpl, err := classifier.ParsePathTemplate(s)
+			if err != nil {
+				return nil, fmt.Errorf("解析分类 %s 的路径模板失败: %w", category, err)
+			}
+			templates[category] = tmpl
+			logger.Get().Info().Msgf("分类 %s 的路径模板: %s", category, tmpl)
+		}
+		cls.SetCategoryTemplates(templates)
+	}
+	if opts.FixExt {
+		cls.SetFixExtensions(true)
+		logger.Get().Info().Msg("修正与内容不符或缺少的扩展名")
+	}
+	if err := classifier.ValidateCategory(opts.Unknown); err != nil {
+		return nil, err
+	}
+	cls.SetUnknownCategory(opts.Unknown)
+	if opts.Unknown == "" {
+		logger.Get().Info().Msg("跳过无法识别类型的文件")
+	}
+	cls.SetStability(newStabilityChecker(opts.Scan))
 	logger.Get().Info().Msgf("每目录文件数: %d", opts.FilesPerDir)
 
-	stats, err := cls.Classify(opts.SourceDirs, opts.DestDir)
+	watchCtx, stopWatch := watchContext(opts.Scan)
+	defer stopWatch()
+	cls.SetWatch(watchCtx, opts.Scan.Settle)
+
+	var stats *classifier.ClassifierStats
+	if opts.Scan.FilesFrom != "" {
+		var files []string
+		if files, err = loadFileList(opts.Scan); err != nil {
+			return nil, err
+		}
+		stats, err = cls.ClassifyFiles(files, opts.DestDir)
+	} else {
+		stats, err = cls.Classify(opts.SourceDirs, opts.DestDir)
+	}
+	writeErrorReport(walker, opts.Scan.ErrorReport)
 	if err != nil {
 		return nil, fmt.Errorf("文件分类失败: %w", err)
 	}
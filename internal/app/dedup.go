@@ -1,11 +1,13 @@
 package app
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/moyu-x/classified-file/pkg/config"
 	"github.com/moyu-x/classified-file/pkg/database"
 	"github.com/moyu-x/classified-file/pkg/deduplicator"
+	"github.com/moyu-x/classified-file/pkg/lock"
 	"github.com/moyu-x/classified-file/internal"
 	"github.com/moyu-x/classified-file/pkg/logger"
 )
@@ -15,12 +17,18 @@ type DedupOptions struct {
 	Mode       string
 	TargetDir  string
 	DBPath     string
+	DBBackend  string
 	LogLevel   string
 	LogFile    string
 	Verbose    bool
 	DryRun     bool
 	Resume     bool
 	Reset      bool
+	Wait       bool
+	CountMode  internal.CountMode
+	Archives   bool
+	DirMode    internal.DirMode
+	Scan       ScanOptions
 }
 
 func RunDedup(opts *DedupOptions) (*internal.ProcessStats, error) {
@@ -39,9 +47,38 @@ func RunDedup(opts *DedupOptions) (*internal.ProcessStats, error) {
 	}
 
 	logger.Get().Info().Msg("加载配置完成")
-	logger.Get().Info().Msgf("数据库路径: %s", cfg.Database.Path)
 
-	db, err := database.NewDatabase(cfg.Database.Path)
+	backend := cfg.Database.Backend
+	if opts.DBBackend != "" {
+		backend = opts.DBBackend
+	}
+
+	dbPath := cfg.Database.Path
+	if opts.DBPath != "" {
+		dbPath = opts.DBPath
+	} else if backend == database.BackendBolt && dbPath == internal.DefaultDatabasePath {
+		dbPath = internal.DefaultBoltPath
+	}
+
+	logger.Get().Info().Msgf("存储后端: %s", backend)
+	if backend != database.BackendMemory {
+		logger.Get().Info().Msgf("数据库路径: %s", dbPath)
+	}
+
+	if backend != database.BackendMemory {
+		expandedPath, err := database.ExpandPath(dbPath)
+		if err != nil {
+			return nil, err
+		}
+
+		dbLock, err := lock.Acquire(expandedPath+".lock", opts.Wait)
+		if err != nil {
+			return nil, lockError(err)
+		}
+		defer dbLock.Release()
+	}
+
+	db, err := database.NewStore(backend, dbPath)
 	if err != nil {
 		return nil, err
 	}
@@ -62,12 +99,54 @@ func RunDedup(opts *DedupOptions) (*internal.ProcessStats, error) {
 		logger.Get().Info().Msg("=== 预览模式，不会实际修改文件 ===")
 	}
 
+	walker, err := newWalker(opts.Scan)
+	if err != nil {
+		return nil, err
+	}
+
 	dedup := deduplicator.NewDeduplicator(db, internal.OperationMode(opts.Mode), opts.TargetDir, opts.Verbose)
+	dedup.SetLockWait(opts.Wait)
+	if opts.CountMode != "" {
+		dedup.SetCountMode(opts.CountMode)
+	}
+	dedup.SetWalker(walker)
+	dedup.SetStability(newStabilityChecker(opts.Scan))
+	dedup.SetScanArchives(opts.Archives)
+	if opts.Archives {
+		logger.Get().Info().Msg("索引压缩包成员: zip、tar、tar.gz、tar.bz2（压缩包不会被修改）")
+	}
+
+	dedup.SetDirMode(opts.DirMode)
+	if opts.DirMode != "" {
+		logger.Get().Info().Msgf("目录级去重: %s", opts.DirMode)
+	}
+
+	watchCtx, stopWatch := watchContext(opts.Scan)
+	defer stopWatch()
+	dedup.SetWatch(watchCtx, opts.Scan.Settle)
 
-	stats, err := dedup.Process(opts.SourceDirs, opts.Resume, opts.Reset)
+	var stats *internal.ProcessStats
+	if opts.Scan.FilesFrom != "" {
+		var files []string
+		if files, err = loadFileList(opts.Scan); err != nil {
+			return nil, err
+		}
+		stats, err = dedup.ProcessFiles(files)
+	} else {
+		stats, err = dedup.Process(opts.SourceDirs, opts.Resume, opts.Reset)
+	}
+	writeErrorReport(walker, opts.Scan.ErrorReport)
 	if err != nil {
-		return nil, err
+		return nil, lockError(err)
 	}
 
 	return stats, nil
 }
+
+func lockError(err error) error {
+	var lockedErr *lock.LockedError
+	if errors.As(err, &lockedErr) {
+		return fmt.Errorf("%w（可使用 --wait 等待锁释放）", err)
+	}
+	return err
+}
@@ -0,0 +1,57 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/moyu-x/classified-file/pkg/classifier"
+	"github.com/moyu-x/classified-file/pkg/logger"
+)
+
+type CheckExtensionsOptions struct {
+	Dirs     []string
+	Rename   bool // 将扩展名不符的文件重命名为识别出的扩展名
+	Verbose  bool
+	LogLevel string
+	LogFile  string
+	Scan     ScanOptions
+}
+
+func RunCheckExtensions(opts *CheckExtensionsOptions) (*classifier.ExtensionStats, error) {
+	logLevel := opts.LogLevel
+	if opts.Verbose {
+		logLevel = "debug"
+	}
+
+	if err := logger.Init(logLevel, opts.LogFile); err != nil {
+		return nil, err
+	}
+
+	walker, err := newWalker(opts.Scan)
+	if err != nil {
+		return nil, err
+	}
+
+	cls := classifier.NewClassifier()
+	cls.SetWalker(walker)
+	cls.SetStability(newStabilityChecker(opts.Scan))
+	if opts.Rename {
+		logger.Get().Info().Msg("将扩展名不符的文件重命名为识别出的扩展名")
+	}
+
+	var stats *classifier.ExtensionStats
+	if opts.Scan.FilesFrom != "" {
+		var files []string
+		if files, err = loadFileList(opts.Scan); err != nil {
+			return nil, err
+		}
+		stats, err = cls.CheckExtensionFiles(files, opts.Rename)
+	} else {
+		stats, err = cls.CheckExtensions(opts.Dirs, opts.Rename)
+	}
+	writeErrorReport(walker, opts.Scan.ErrorReport)
+	if err != nil {
+		return nil, fmt.Errorf("检查扩展名失败: %w", err)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,105 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/moyu-x/classified-file/pkg/filter"
+	"github.com/moyu-x/classified-file/pkg/logger"
+	"github.com/moyu-x/classified-file/pkg/scanner"
+	"github.com/moyu-x/classified-file/pkg/stability"
+)
+
+// ScanOptions dedup 和 classify 共用的目录遍历选项
+type ScanOptions struct {
+	FollowSymlinks bool
+	IncludeHidden  bool
+	UseGitIgnore   bool
+	Workers        int
+	OneFileSystem  bool
+	Ordered        bool
+	Filter         filter.Options
+	ErrorReport    string        // 错误报告输出路径，为空时不写入
+	FilesFrom      string        // 文件列表路径，"-" 表示标准输入，为空时遍历目录
+	NullDelimited  bool          // 文件列表按 NUL 分隔
+	Watch          bool          // 首次扫描后继续监视目录
+	Settle         time.Duration // 监视模式下文件的稳定等待时间
+	StableFor      time.Duration // 文件最后一次修改后需保持不变的时间，为 0 时不检查
+	CheckOpen      bool          // 跳过被其他进程以写方式打开的文件
+}
+
+// newStabilityChecker 创建处理文件前的写入检查，未启用时返回 nil
+func newStabilityChecker(opts ScanOptions) *stability.Checker {
+	checker := &stability.Checker{Interval: opts.StableFor, CheckOpen: opts.CheckOpen}
+	if !checker.Enabled() {
+		return nil
+	}
+	logger.Get().Info().Msgf("写入检查: 稳定时间 %v, 检查打开的文件: %v", opts.StableFor, opts.CheckOpen)
+	return checker
+}
+
+// watchContext 监视模式下返回收到中断信号时结束的上下文，否则返回 nil
+func watchContext(opts ScanOptions) (context.Context, context.CancelFunc) {
+	if !opts.Watch {
+		return nil, func() {}
+	}
+	logger.Get().Info().Msgf("监视模式: 文件稳定 %v 后处理", opts.Settle)
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// loadFileList 读取 --files-from 指定的文件列表
+func loadFileList(opts ScanOptions) ([]string, error) {
+	files, err := scanner.LoadFileList(opts.FilesFrom, opts.NullDelimited)
+	if err != nil {
+		return nil, err
+	}
+
+	source := opts.FilesFrom
+	if source == scanner.FileListStdin {
+		source = "标准输入"
+	}
+	logger.Get().Info().Msgf("文件列表: %s（%d 个路径）", source, len(files))
+	return files, nil
+}
+
+func newWalker(opts ScanOptions) (*scanner.FileWalker, error) {
+	fileFilter, err := filter.New(opts.Filter)
+	if err != nil {
+		return nil, fmt.Errorf("解析过滤条件失败: %w", err)
+	}
+
+	walker := scanner.NewFileWalker()
+	walker.FollowSymlinks = opts.FollowSymlinks
+	walker.IncludeHidden = opts.IncludeHidden
+	walker.UseGitIgnore = opts.UseGitIgnore
+	walker.Filter = fileFilter
+	walker.Workers = opts.Workers
+	walker.OneFileSystem = opts.OneFileSystem
+	walker.Ordered = opts.Ordered
+
+	logger.Get().Info().Msgf("跟随符号链接: %v", opts.FollowSymlinks)
+	logger.Get().Info().Msgf("包含隐藏文件: %v", opts.IncludeHidden)
+	logger.Get().Info().Msgf("遵循 .gitignore: %v", opts.UseGitIgnore)
+	logger.Get().Info().Msgf("限制在单一文件系统: %v", opts.OneFileSystem)
+	if opts.Workers > 1 {
+		logger.Get().Info().Msgf("并发遍历: %d 个 worker, 保持顺序: %v", opts.Workers, opts.Ordered)
+	}
+	if fileFilter != nil {
+		logger.Get().Info().Msgf("过滤条件: %+v", opts.Filter)
+	}
+
+	return walker, nil
+}
+
+func writeErrorReport(walker *scanner.FileWalker, path string) {
+	if path == "" {
+		return
+	}
+	if err := walker.Errors.WriteFile(path); err != nil {
+		logger.Get().Error().Err(err).Msgf("写入错误报告失败: %s", path)
+	}
+}
@@ -4,6 +4,9 @@ const (
 	// 数据库默认路径
 	DefaultDatabasePath = "~/.classified-file/hashes.db"
 
+	// 键值数据库默认路径
+	DefaultBoltPath = "~/.classified-file/hashes.bolt"
+
 	// 配置文件默认路径
 	DefaultConfigPath = "~/.classified-file/config.yaml"
 
@@ -10,17 +10,79 @@ const (
 	ModeMove   OperationMode = "move"
 )
 
+// 文件计数模式
+type CountMode string
+
+const (
+	// CountUpfront 先完整遍历统计总数，再开始处理
+	CountUpfront CountMode = "upfront"
+	// CountStream 发现与处理并发进行，总数随发现实时更新
+	CountStream CountMode = "stream"
+	// CountNone 不统计总数，单次遍历直接处理
+	CountNone CountMode = "none"
+)
+
+// 分类时文件放入目标目录的方式
+type ClassifyMode string
+
+const (
+	ClassifyCopy     ClassifyMode = "copy"
+	ClassifyMove     ClassifyMode = "move"
+	ClassifyHardlink ClassifyMode = "hardlink"
+	ClassifySymlink  ClassifyMode = "symlink"
+)
+
+// 目录级去重模式
+type DirMode string
+
+const (
+	// DirsReport 只报告内容重复的目录和包含关系，不修改文件
+	DirsReport DirMode = "report"
+	// DirsApply 按操作模式删除或移动完全相同的目录，再继续逐文件去重
+	DirsApply DirMode = "apply"
+)
+
 // 处理统计
 type ProcessStats struct {
 	TotalProcessed int
 	Added          int
 	Deleted        int
 	Moved          int
+	AlreadyIndexed int
+	HardLinked     int
+	HardLinkSets   [][]string
+	DanglingLinks  int
+	ScanErrors     int
+	Skipped        map[string]int // 按类型统计跳过的特殊文件和挂载点
+	Archives       int            // 已索引的压缩包数量
+	ArchiveMembers int            // 已索引的压缩包成员数量
+	ArchiveDups    int            // 内容已存在于其他位置的压缩包成员（只报告，不修改）
+	DuplicateDirs  []DirGroup     // 内容重复的目录组
+	SupersetDirs   []DirSuperset  // 一个目录包含另一个目录全部文件内容的目录对
+	DirsRemoved    int            // 作为整体删除或移动的重复目录数量
+	Busy           int            // 仍在写入而被跳过的文件数量
 	FreedSpace     int64
 	StartTime      time.Time
 	EndTime        time.Time
 }
 
+// DirGroup 一组内容相同的目录，Paths 按路径排序，第一个为保留的目录
+type DirGroup struct {
+	Hash  string
+	Paths []string
+	Files int   // 每个目录中的文件数
+	Size  int64 // 每个目录中文件的总大小
+	// NamesDiffer 为 true 表示忽略文件名和目录名后内容才相同
+	NamesDiffer bool
+}
+
+// DirSuperset 目录 Superset 包含目录 Subset 中全部文件的内容
+type DirSuperset struct {
+	Superset string
+	Subset   string
+	Files    int // Subset 中的文件数
+}
+
 // 文件记录
 type FileRecord struct {
 	ID        int64
@@ -28,6 +90,22 @@ type FileRecord struct {
 	FilePath  string
 	FileSize  int64
 	CreatedAt int64
+	Metadata  *FileMetadata `json:",omitempty"` // 图片和视频的元数据，没有时为 nil
+}
+
+// 文件元数据，未读取到的字段为零值
+type FileMetadata struct {
+	TakenAt     int64  // 拍摄或创建时间（Unix 秒）
+	TakenSource string // 时间来源：exif 或 container
+	Make        string
+	Model       string
+	Orientation int
+	Width       int
+	Height      int
+	HasGPS      bool
+	Latitude    float64
+	Longitude   float64
+	Altitude    float64
 }
 
 // 进度更新
@@ -0,0 +1,171 @@
+package archive
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// Separator 分隔压缩包路径和成员路径，如 backup.zip!/dir/file.jpg
+const Separator = "!/"
+
+type format int
+
+const (
+	formatNone format = iota
+	formatZip
+	formatTar
+	formatTarGz
+	formatTarBz2
+)
+
+// 按扩展名识别压缩包格式，较长的扩展名在前
+var extensions = []struct {
+	ext    string
+	format format
+}{
+	{".tar.gz", formatTarGz},
+	{".tar.bz2", formatTarBz2},
+	{".tgz", formatTarGz},
+	{".tbz2", formatTarBz2},
+	{".tbz", formatTarBz2},
+	{".tar", formatTar},
+	{".zip", formatZip},
+}
+
+func detect(filePath string) format {
+	lower := strings.ToLower(filePath)
+	for _, e := range extensions {
+		if strings.HasSuffix(lower, e.ext) {
+			return e.format
+		}
+	}
+	return formatNone
+}
+
+// IsArchive 根据扩展名判断文件是否为支持的压缩包（zip、tar、tar.gz、tar.bz2）
+func IsArchive(filePath string) bool {
+	return detect(filePath) != formatNone
+}
+
+// MemberPath 返回压缩包成员的虚拟路径
+func MemberPath(archivePath, name string) string {
+	return archivePath + Separator + name
+}
+
+// IsMemberPath 判断路径是否为压缩包成员的虚拟路径
+func IsMemberPath(p string) bool {
+	return strings.Contains(p, Separator)
+}
+
+// SplitMemberPath 将虚拟路径拆分为压缩包路径和成员路径
+func SplitMemberPath(p string) (archivePath, name string, ok bool) {
+	i := strings.Index(p, Separator)
+	if i < 0 {
+		return p, "", false
+	}
+	return p[:i], p[i+len(Separator):], true
+}
+
+// Member 是压缩包中的一个普通文件
+type Member struct {
+	// Name 是成员在压缩包中的路径，以 / 分隔
+	Name string
+	Size int64
+}
+
+// FS 是以只读 fs.FS 形式打开的压缩包，成员路径为规范化后以 / 分隔的路径，与 Walk 交付的 Member.Name 相同
+// 可交给 scanner.WalkFS、hasher.CalculateHashFS、classifier.ClassifyFS 等处理，使用完毕后需调用 Close
+type FS struct {
+	fs.FS
+	closer io.Closer
+	// members 为按压缩包中顺序排列的普通文件成员
+	members []Member
+}
+
+// Open 打开压缩包，只读不修改压缩包
+func Open(archivePath string) (*FS, error) {
+	switch f := detect(archivePath); f {
+	case formatZip:
+		return openZip(archivePath)
+	case formatTar, formatTarGz, formatTarBz2:
+		t, err := openTar(archivePath, f)
+		if err != nil {
+			return nil, err
+		}
+		members := make([]Member, 0, len(t.order))
+		for _, entry := range t.order {
+			members = append(members, Member{Name: entry.name, Size: entry.info.Size()})
+		}
+		return &FS{FS: t, closer: t, members: members}, nil
+	default:
+		return nil, fmt.Errorf("不支持的压缩包格式: %s", archivePath)
+	}
+}
+
+// Close 关闭压缩包
+func (a *FS) Close() error {
+	return a.closer.Close()
+}
+
+// Walk 依次读取压缩包中的普通文件成员，只读不修改压缩包
+// 目录、符号链接等非普通文件成员会被跳过，嵌套的压缩包不会展开
+func Walk(archivePath string, fn func(member Member, r io.Reader) error) error {
+	fsys, err := Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer fsys.Close()
+
+	// 按压缩包中的顺序读取，tar 压缩流只需读取一遍
+	for _, member := range fsys.members {
+		file, err := fsys.Open(member.Name)
+		if err != nil {
+			return fmt.Errorf("读取压缩包成员失败 %s: %w", member.Name, err)
+		}
+		err = fn(member, file)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func openZip(archivePath string) (*FS, error) {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("打开 zip 文件失败: %w", err)
+	}
+
+	// zip.Reader 本身就是 fs.FS，同名成员以第一个为准
+	var members []Member
+	seen := make(map[string]bool)
+	for _, file := range reader.File {
+		if !file.Mode().IsRegular() {
+			continue
+		}
+
+		name, ok := cleanName(file.Name)
+		if !ok || seen[name] {
+			continue
+		}
+		seen[name] = true
+		members = append(members, Member{Name: name, Size: int64(file.UncompressedSize64)})
+	}
+
+	return &FS{FS: &reader.Reader, closer: reader, members: members}, nil
+}
+
+// cleanName 规范化成员路径，去掉开头的 / 和 ./，路径为空时返回 false
+func cleanName(name string) (string, bool) {
+	name = path.Clean("/" + strings.ReplaceAll(name, "\\", "/"))
+	name = strings.TrimPrefix(name, "/")
+	if name == "" || !fs.ValidPath(name) {
+		return "", false
+	}
+	return name, true
+}
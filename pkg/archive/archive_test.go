@@ -0,0 +1,283 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+var testMembers = map[string]string{
+	"a.txt":         "alpha",
+	"dir/b.jpg":     "bravo",
+	"./dir/c/d.txt": "delta",
+}
+
+func createZip(t *testing.T, archivePath string, members map[string]string) {
+	t.Helper()
+
+	file, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	defer file.Close()
+
+	writer := zip.NewWriter(file)
+	if _, err := writer.Create("dir/"); err != nil {
+		t.Fatalf("Failed to add directory: %v", err)
+	}
+	for name, content := range members {
+		w, err := writer.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to add member: %v", err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("Failed to write member: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close archive: %v", err)
+	}
+}
+
+func createTar(t *testing.T, archivePath string, members map[string]string, compress bool) {
+	t.Helper()
+
+	file, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	defer file.Close()
+
+	var out io.Writer = file
+	var gz *gzip.Writer
+	if compress {
+		gz = gzip.NewWriter(file)
+		out = gz
+	}
+
+	writer := tar.NewWriter(out)
+	if err := writer.WriteHeader(&tar.Header{Name: "dir/", Typeflag: tar.TypeDir, Mode: 0755}); err != nil {
+		t.Fatalf("Failed to add directory: %v", err)
+	}
+	if err := writer.WriteHeader(&tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "a.txt"}); err != nil {
+		t.Fatalf("Failed to add symlink: %v", err)
+	}
+	for name, content := range members {
+		header := &tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))}
+		if err := writer.WriteHeader(header); err != nil {
+			t.Fatalf("Failed to add member: %v", err)
+		}
+		if _, err := writer.Write([]byte(content)); err != nil {
+			t.Fatalf("Failed to write member: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close archive: %v", err)
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			t.Fatalf("Failed to close gzip: %v", err)
+		}
+	}
+}
+
+func TestWalk(t *testing.T) {
+	tempDir := t.TempDir()
+
+	archives := map[string]func(string){
+		"backup.zip":    func(p string) { createZip(t, p, testMembers) },
+		"backup.tar":    func(p string) { createTar(t, p, testMembers, false) },
+		"backup.tar.gz": func(p string) { createTar(t, p, testMembers, true) },
+		"backup.TGZ":    func(p string) { createTar(t, p, testMembers, true) },
+	}
+
+	want := map[string]string{
+		"a.txt":       "alpha",
+		"dir/b.jpg":   "bravo",
+		"dir/c/d.txt": "delta",
+	}
+
+	for name, create := range archives {
+		t.Run(name, func(t *testing.T) {
+			archivePath := filepath.Join(tempDir, name)
+			create(archivePath)
+
+			got := make(map[string]string)
+			err := Walk(archivePath, func(member Member, r io.Reader) error {
+				data, err := io.ReadAll(r)
+				if err != nil {
+					return err
+				}
+				if member.Size != int64(len(data)) {
+					t.Errorf("Member %s size = %d, want %d", member.Name, member.Size, len(data))
+				}
+				got[member.Name] = string(data)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Walk() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Walk() members = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestOpen(t *testing.T) {
+	tempDir := t.TempDir()
+
+	archives := map[string]func(string){
+		"backup.zip":    func(p string) { createZip(t, p, testMembers) },
+		"backup.tar":    func(p string) { createTar(t, p, testMembers, false) },
+		"backup.tar.gz": func(p string) { createTar(t, p, testMembers, true) },
+	}
+
+	for name, create := range archives {
+		t.Run(name, func(t *testing.T) {
+			archivePath := filepath.Join(tempDir, name)
+			create(archivePath)
+
+			fsys, err := Open(archivePath)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			defer fsys.Close()
+
+			if err := fstest.TestFS(fsys, "a.txt", "dir/b.jpg", "dir/c/d.txt"); err != nil {
+				t.Errorf("TestFS() error = %v", err)
+			}
+
+			// 逆序读取成员时 tar 压缩流需要重新定位
+			for _, member := range []struct{ name, want string }{
+				{"dir/c/d.txt", "delta"},
+				{"a.txt", "alpha"},
+				{"dir/b.jpg", "bravo"},
+			} {
+				data, err := fs.ReadFile(fsys, member.name)
+				if err != nil {
+					t.Fatalf("ReadFile(%s) error = %v", member.name, err)
+				}
+				if string(data) != member.want {
+					t.Errorf("ReadFile(%s) = %q, want %q", member.name, data, member.want)
+				}
+			}
+		})
+	}
+}
+
+func TestOpen_ConcurrentTarReads(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "backup.tar.gz")
+	createTar(t, archivePath, testMembers, true)
+
+	fsys, err := Open(archivePath)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer fsys.Close()
+
+	first, err := fsys.Open("a.txt")
+	if err != nil {
+		t.Fatalf("Open(a.txt) error = %v", err)
+	}
+	defer first.Close()
+	second, err := fsys.Open("dir/c/d.txt")
+	if err != nil {
+		t.Fatalf("Open(dir/c/d.txt) error = %v", err)
+	}
+	defer second.Close()
+
+	// 交替读取两个同时打开的成员，各自的读取位置互不影响
+	buf := make([]byte, 2)
+	var a, d []byte
+	for len(a) < len("alpha") || len(d) < len("delta") {
+		if n, _ := first.Read(buf); n > 0 {
+			a = append(a, buf[:n]...)
+		}
+		if n, _ := second.Read(buf); n > 0 {
+			d = append(d, buf[:n]...)
+		}
+	}
+	if string(a) != "alpha" || string(d) != "delta" {
+		t.Errorf("Interleaved reads = %q, %q, want alpha, delta", a, d)
+	}
+}
+
+func TestOpen_Unsupported(t *testing.T) {
+	if _, err := Open("file.rar"); err == nil {
+		t.Error("Expected error for unsupported archive")
+	}
+}
+
+func TestWalk_Unsupported(t *testing.T) {
+	if err := Walk("file.rar", func(Member, io.Reader) error { return nil }); err == nil {
+		t.Error("Expected error for unsupported archive")
+	}
+}
+
+func TestIsArchive(t *testing.T) {
+	tests := map[string]bool{
+		"a.zip":     true,
+		"a.ZIP":     true,
+		"a.tar":     true,
+		"a.tar.gz":  true,
+		"a.tgz":     true,
+		"a.tar.bz2": true,
+		"a.tbz2":    true,
+		"a.gz":      false,
+		"a.rar":     false,
+		"zip":       false,
+	}
+
+	for name, want := range tests {
+		if got := IsArchive(name); got != want {
+			t.Errorf("IsArchive(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestMemberPath(t *testing.T) {
+	p := MemberPath("/data/backup.zip", "dir/file.jpg")
+	if p != "/data/backup.zip!/dir/file.jpg" {
+		t.Errorf("MemberPath() = %q", p)
+	}
+
+	if !IsMemberPath(p) || IsMemberPath("/data/file.jpg") {
+		t.Error("IsMemberPath() returned unexpected result")
+	}
+
+	archivePath, name, ok := SplitMemberPath(p)
+	if !ok || archivePath != "/data/backup.zip" || name != "dir/file.jpg" {
+		t.Errorf("SplitMemberPath() = %q, %q, %v", archivePath, name, ok)
+	}
+}
+
+func TestCleanName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{"a.txt", "a.txt", true},
+		{"./dir/a.txt", "dir/a.txt", true},
+		{"/abs/a.txt", "abs/a.txt", true},
+		{"../../etc/passwd", "etc/passwd", true},
+		{"dir\\win.txt", "dir/win.txt", true},
+		{"/", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := cleanName(tt.name)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("cleanName(%q) = %q, %v, want %q, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
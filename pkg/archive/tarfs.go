@@ -0,0 +1,355 @@
+package archive
+
+import (
+	"archive/tar"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+)
+
+// tarFS 是 tar、tar.gz、tar.bz2 压缩包的只读 fs.FS
+// 压缩流不能随机访问，读取成员时从上一次读取结束的位置继续向后读取，
+// 按压缩包中的顺序读取成员时整个压缩包只需读取一遍，逆序读取时需要从头重新读取
+type tarFS struct {
+	archivePath string
+	format      format
+	files       map[string]*tarEntry
+	dirs        map[string]*tarDir
+	// order 为按压缩包中顺序排列的普通文件成员，同名成员以最后一个为准
+	order []*tarEntry
+
+	mu     sync.Mutex
+	idle   *tarStream // 空闲的读取位置，供下一次读取成员时复用
+	closed bool
+}
+
+type tarEntry struct {
+	name string
+	info fs.FileInfo
+	// index 为成员在压缩包中的序号，包括被跳过的非普通文件成员
+	index int
+}
+
+type tarDir struct {
+	info     fs.FileInfo
+	children map[string]fs.DirEntry
+}
+
+type tarStream struct {
+	file   *os.File
+	closer io.Closer
+	reader *tar.Reader
+	// next 为下一次调用 Next 返回的成员序号
+	next int
+}
+
+func openTar(archivePath string, f format) (*tarFS, error) {
+	t := &tarFS{
+		archivePath: archivePath,
+		format:      f,
+		files:       make(map[string]*tarEntry),
+		dirs:        map[string]*tarDir{".": newTarDir(".", nil)},
+	}
+
+	stream, err := t.openStream()
+	if err != nil {
+		return nil, err
+	}
+	defer stream.close()
+
+	for {
+		header, err := stream.reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取 tar 成员失败: %w", err)
+		}
+		index := stream.next
+		stream.next++
+
+		name, ok := cleanName(header.Name)
+		if !ok {
+			continue
+		}
+
+		switch header.Typeflag {
+		case tar.TypeReg:
+			t.addFile(name, header, index)
+		case tar.TypeDir:
+			t.addDir(name).info = headerInfo(name, header)
+		}
+	}
+
+	for _, entry := range t.files {
+		t.order = append(t.order, entry)
+	}
+	sort.Slice(t.order, func(i, j int) bool { return t.order[i].index < t.order[j].index })
+
+	return t, nil
+}
+
+func (t *tarFS) addFile(name string, header *tar.Header, index int) {
+	if _, ok := t.dirs[name]; ok {
+		return
+	}
+	entry := &tarEntry{name: name, info: headerInfo(name, header), index: index}
+	t.files[name] = entry
+	t.addDir(path.Dir(name)).children[path.Base(name)] = fs.FileInfoToDirEntry(entry.info)
+}
+
+// addDir 返回目录 name，目录及其上级目录不存在时一并创建
+func (t *tarFS) addDir(name string) *tarDir {
+	if dir, ok := t.dirs[name]; ok {
+		return dir
+	}
+	parent := t.addDir(path.Dir(name))
+	dir := newTarDir(name, parent)
+	// 同名的普通文件成员让位给目录
+	if _, ok := t.files[name]; ok {
+		delete(t.files, name)
+	}
+	t.dirs[name] = dir
+	return dir
+}
+
+func newTarDir(name string, parent *tarDir) *tarDir {
+	dir := &tarDir{
+		info:     dirInfo(path.Base(name)),
+		children: make(map[string]fs.DirEntry),
+	}
+	if parent != nil {
+		parent.children[path.Base(name)] = tarDirEntry{dir}
+	}
+	return dir
+}
+
+// headerInfo 返回以规范化路径命名的成员信息
+func headerInfo(name string, header *tar.Header) fs.FileInfo {
+	h := *header
+	h.Name = name
+	return h.FileInfo()
+}
+
+func (t *tarFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if dir, ok := t.dirs[name]; ok {
+		return &tarDirFile{dir: dir}, nil
+	}
+	if entry, ok := t.files[name]; ok {
+		return &tarFile{fsys: t, entry: entry}, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func (t *tarFS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	if dir, ok := t.dirs[name]; ok {
+		return dir.info, nil
+	}
+	if entry, ok := t.files[name]; ok {
+		return entry.info, nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+func (t *tarFS) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.closed = true
+	if t.idle != nil {
+		t.idle.close()
+		t.idle = nil
+	}
+	return nil
+}
+
+func (t *tarFS) openStream() (*tarStream, error) {
+	file, err := os.Open(t.archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("打开 tar 文件失败: %w", err)
+	}
+
+	stream := &tarStream{file: file}
+	var r io.Reader = file
+	switch t.format {
+	case formatTarGz:
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("解压 gzip 失败: %w", err)
+		}
+		stream.closer = gz
+		r = gz
+	case formatTarBz2:
+		r = bzip2.NewReader(file)
+	}
+	stream.reader = tar.NewReader(r)
+	return stream, nil
+}
+
+// seek 返回位于成员 entry 数据开头的读取流，空闲的读取流尚未越过该成员时直接向后读取
+func (t *tarFS) seek(entry *tarEntry) (*tarStream, error) {
+	t.mu.Lock()
+	stream := t.idle
+	if stream != nil && stream.next <= entry.index {
+		t.idle = nil
+	} else {
+		stream = nil
+	}
+	t.mu.Unlock()
+
+	if stream == nil {
+		var err error
+		if stream, err = t.openStream(); err != nil {
+			return nil, err
+		}
+	}
+
+	for stream.next <= entry.index {
+		if _, err := stream.reader.Next(); err != nil {
+			stream.close()
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, fmt.Errorf("读取 tar 成员失败: %w", err)
+		}
+		stream.next++
+	}
+	return stream, nil
+}
+
+// release 归还读取完毕的读取流，以便按顺序读取下一个成员时复用
+func (t *tarFS) release(stream *tarStream) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		stream.close()
+		return
+	}
+	if t.idle != nil {
+		t.idle.close()
+	}
+	t.idle = stream
+}
+
+func (s *tarStream) close() {
+	if s.closer != nil {
+		s.closer.Close()
+	}
+	s.file.Close()
+}
+
+// tarFile 是打开的普通文件成员，第一次读取时才定位到成员数据
+type tarFile struct {
+	fsys   *tarFS
+	entry  *tarEntry
+	stream *tarStream
+	closed bool
+}
+
+func (f *tarFile) Stat() (fs.FileInfo, error) {
+	return f.entry.info, nil
+}
+
+func (f *tarFile) Read(p []byte) (int, error) {
+	if f.closed {
+		return 0, &fs.PathError{Op: "read", Path: f.entry.info.Name(), Err: fs.ErrClosed}
+	}
+	if f.stream == nil {
+		stream, err := f.fsys.seek(f.entry)
+		if err != nil {
+			return 0, err
+		}
+		f.stream = stream
+	}
+	return f.stream.reader.Read(p)
+}
+
+func (f *tarFile) Close() error {
+	if f.closed {
+		return &fs.PathError{Op: "close", Path: f.entry.info.Name(), Err: fs.ErrClosed}
+	}
+	f.closed = true
+	if f.stream != nil {
+		f.fsys.release(f.stream)
+		f.stream = nil
+	}
+	return nil
+}
+
+// tarDirFile 是打开的目录，包括只由成员路径隐含、压缩包中没有对应成员的目录
+type tarDirFile struct {
+	dir     *tarDir
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *tarDirFile) Stat() (fs.FileInfo, error) {
+	return d.dir.info, nil
+}
+
+func (d *tarDirFile) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.dir.info.Name(), Err: fs.ErrInvalid}
+}
+
+func (d *tarDirFile) Close() error {
+	return nil
+}
+
+func (d *tarDirFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if d.entries == nil {
+		d.entries = make([]fs.DirEntry, 0, len(d.dir.children))
+		for _, entry := range d.dir.children {
+			d.entries = append(d.entries, entry)
+		}
+		sort.Slice(d.entries, func(i, j int) bool { return d.entries[i].Name() < d.entries[j].Name() })
+	}
+
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+	return rest[:n], nil
+}
+
+// tarDirEntry 在读取目录时返回目录的最新信息，使随后遇到的目录成员覆盖隐含目录的信息
+type tarDirEntry struct {
+	dir *tarDir
+}
+
+func (e tarDirEntry) Name() string               { return e.dir.info.Name() }
+func (e tarDirEntry) IsDir() bool                { return true }
+func (e tarDirEntry) Type() fs.FileMode          { return fs.ModeDir }
+func (e tarDirEntry) Info() (fs.FileInfo, error) { return e.dir.info, nil }
+
+// dirInfo 是压缩包中没有对应成员的隐含目录的信息
+type dirInfo string
+
+func (d dirInfo) Name() string       { return string(d) }
+func (d dirInfo) Size() int64        { return 0 }
+func (d dirInfo) Mode() fs.FileMode  { return fs.ModeDir | 0555 }
+func (d dirInfo) ModTime() time.Time { return time.Time{} }
+func (d dirInfo) IsDir() bool        { return true }
+func (d dirInfo) Sys() any           { return nil }
@@ -2,17 +2,25 @@ package classifier
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/h2non/filetype"
 	"github.com/h2non/filetype/types"
+	"github.com/moyu-x/classified-file/internal"
 	"github.com/moyu-x/classified-file/pkg/logger"
+	"github.com/moyu-x/classified-file/pkg/metadata"
 	"github.com/moyu-x/classified-file/pkg/scanner"
+	"github.com/moyu-x/classified-file/pkg/stability"
+	"github.com/moyu-x/classified-file/pkg/watcher"
 )
 
 const (
@@ -25,6 +33,18 @@ type Classifier struct {
 	filesPerDir    int
 	fileCounters   map[string]int
 	fileCountersMu sync.Mutex
+
+	watchCtx      context.Context
+	watchSettle   time.Duration
+	stability     *stability.Checker
+	deferred      stability.Deferred // 最近被修改、推迟到本轮处理结束后复查的文件
+	mode          internal.ClassifyMode
+	rules         *Rules
+	layout        *Layout
+	naming        *NameTemplate            // 为 nil 时保留原文件名
+	templates     map[string]*PathTemplate // 分类专用的路径模板
+	fixExtensions bool                     // 是否修正与内容不符或缺少的扩展名
+	unknown       string                   // 无法识别类型的文件放入的分类，为空时跳过
 }
 
 type ClassifierStats struct {
@@ -32,6 +52,18 @@ type ClassifierStats struct {
 	Processed      int
 	Failed         int
 	UnknownType    int
+	Fallback       int            // 按扩展名或文本内容识别类型的文件数
+	DateSources    map[string]int // 目录布局或文件名使用日期时各日期来源（exif、container、mtime）的文件数
+	Untagged       int            // 分类模板使用音频标签但文件没有标签、改用通用布局的文件数
+	FixedExt       int            // 修正了扩展名的文件数
+	DanglingLinks  int
+	ScanErrors     int
+	Skipped        map[string]int
+	Busy           int
+	Moved          int   // 移动的文件数（源文件已删除）
+	Linked         int   // 创建硬链接或符号链接的文件数
+	BytesMoved     int64 // 同一文件系统内直接重命名的字节数
+	BytesCopied    int64 // 实际复制的字节数（复制模式，或跨文件系统移动）
 }
 
 func NewClassifier() *Classifier {
@@ -39,6 +71,10 @@ func NewClassifier() *Classifier {
 		walker:       scanner.NewFileWalker(),
 		filesPerDir:  FilesPerDir,
 		fileCounters: make(map[string]int),
+		mode:         internal.ClassifyCopy,
+		rules:        defaultRules,
+		layout:       defaultLayout,
+		unknown:      UnknownCategory,
 	}
 }
 
@@ -47,9 +83,30 @@ func NewClassifierWithCustomFilesPerDir(filesPerDir int) *Classifier {
 		walker:       scanner.NewFileWalker(),
 		filesPerDir:  filesPerDir,
 		fileCounters: make(map[string]int),
+		mode:         internal.ClassifyCopy,
+		rules:        defaultRules,
+		layout:       defaultLayout,
+		unknown:      UnknownCategory,
 	}
 }
 
+// SetWalker 设置遍历目录使用的 FileWalker
+func (c *Classifier) SetWalker(walker *scanner.FileWalker) {
+	c.walker = walker
+}
+
+// SetStability 设置分类文件前的写入检查，为 nil 时不检查
+func (c *Classifier) SetStability(checker *stability.Checker) {
+	c.stability = checker
+}
+
+// SetWatch 设置监视模式：首次分类完成后继续监视源目录，分类新建或移入的文件，直到 ctx 结束
+// ctx 为 nil 时不监视，settle 为文件稳定等待时间
+func (c *Classifier) SetWatch(ctx context.Context, settle time.Duration) {
+	c.watchCtx = ctx
+	c.watchSettle = settle
+}
+
 func (c *Classifier) Classify(sourceDirs []string, destDir string) (*ClassifierStats, error) {
 	logger.Get().Info().Msgf("开始分类文件，共 %d 个源目录", len(sourceDirs))
 	logger.Get().Info().Msgf("目标目录: %s", destDir)
@@ -59,6 +116,14 @@ func (c *Classifier) Classify(sourceDirs []string, destDir string) (*ClassifierS
 		return nil, fmt.Errorf("创建目标目录: %w", err)
 	}
 
+	// 目标目录位于源目录中时不遍历目标目录，否则会再次处理已经分类的文件
+	for _, sourceDir := range sourceDirs {
+		if sameDir(sourceDir, destDir) {
+			return nil, fmt.Errorf("目标目录不能与源目录相同: %s", destDir)
+		}
+	}
+	c.walker.SkipPath(destDir)
+
 	stats := &ClassifierStats{}
 
 	for _, sourceDir := range sourceDirs {
@@ -66,22 +131,152 @@ func (c *Classifier) Classify(sourceDirs []string, destDir string) (*ClassifierS
 			return stats, err
 		}
 	}
+	c.classifyDeferred(destDir, stats)
 
-	logger.Get().Info().Msg("文件分类完成")
+	if c.watchCtx != nil {
+		w := watcher.New(c.walker, c.watchSettle)
+		w.Exclude(destDir)
+		err := w.Run(c.watchCtx, sourceDirs, func(filePath string, info os.FileInfo) {
+			c.classifyLocal(filePath, info, destDir, stats)
+			c.classifyDeferred(destDir, stats)
+		})
+		if err != nil {
+			return stats, err
+		}
+	}
+
+	c.finish(stats)
 	return stats, nil
 }
 
-func (c *Classifier) classifyDirectory(sourceDir, destDir string, stats *ClassifierStats) error {
-	logger.Get().Info().Msgf("处理目录: %s", sourceDir)
+// ClassifyFiles 分类给定的文件列表而不遍历目录，用于 --files-from
+func (c *Classifier) ClassifyFiles(files []string, destDir string) (*ClassifierStats, error) {
+	logger.Get().Info().Msgf("开始分类文件，文件列表共 %d 个路径", len(files))
+	logger.Get().Info().Msgf("目标目录: %s", destDir)
 
-	err := c.walker.Walk(sourceDir, func(filePath string, info os.FileInfo) error {
-		stats.TotalProcessed++
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		logger.Get().Error().Err(err).Msg("创建目标目录失败")
+		return nil, fmt.Errorf("创建目标目录: %w", err)
+	}
+
+	stats := &ClassifierStats{}
+
+	err := c.walker.WalkList(files, func(filePath string, info os.FileInfo) error {
+		c.classifyLocal(filePath, info, destDir, stats)
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+	c.classifyDeferred(destDir, stats)
+
+	c.finish(stats)
+	return stats, nil
+}
+
+// ClassifyFS 分类 fsys 中的所有文件，源文件只读取不修改，可用于压缩包、内存文件系统等非本地目录树
+func (c *Classifier) ClassifyFS(fsys fs.FS, destDir string) (*ClassifierStats, error) {
+	logger.Get().Info().Msgf("开始分类文件系统中的文件，目标目录: %s", destDir)
+
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		logger.Get().Error().Err(err).Msg("创建目标目录失败")
+		return nil, fmt.Errorf("创建目标目录: %w", err)
+	}
+
+	stats := &ClassifierStats{}
+
+	err := c.walker.WalkFS(fsys, func(name string, entry fs.DirEntry) error {
+		c.classifyFile(fsFile(fsys, name), name, false, destDir, stats)
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	c.finish(stats)
+	return stats, nil
+}
+
+// sameDir 判断两个路径是否为同一目录
+func sameDir(a, b string) bool {
+	aInfo, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bInfo, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(aInfo, bInfo)
+}
+
+// openFunc 打开待分类的源文件
+type openFunc func() (fs.File, error)
+
+func localFile(path string) openFunc {
+	return func() (fs.File, error) {
+		return os.Open(path)
+	}
+}
+
+func fsFile(fsys fs.FS, name string) openFunc {
+	return func() (fs.File, error) {
+		return fsys.Open(name)
+	}
+}
+
+func (c *Classifier) finish(stats *ClassifierStats) {
+	stats.DanglingLinks = len(c.walker.DanglingLinks())
+	stats.ScanErrors = c.walker.Errors.Len()
+	stats.Skipped = c.walker.Skipped()
+
+	logger.Get().Info().Msg("文件分类完成")
+}
+
+// classifyFile 分类单个文件，filePath 用于日志、错误报告和目标文件名
+// local 为 true 时 filePath 是本地路径，可以移动或链接，否则只能复制
+func (c *Classifier) classifyFile(open openFunc, filePath string, local bool, destDir string, stats *ClassifierStats) {
+	stats.TotalProcessed++
+
+	if err := c.processFile(open, filePath, local, destDir, stats); err != nil {
+		logger.Get().Error().Err(err).Msgf("处理文件失败: %s", filePath)
+		c.walker.Errors.Add(filePath, scanner.OpClassify, err)
+		stats.Failed++
+	}
+}
+
+// classifyLocal 分类本地文件，仍在写入的文件会被跳过，最近被修改的文件推迟到本轮结束后复查
+func (c *Classifier) classifyLocal(filePath string, info os.FileInfo, destDir string, stats *ClassifierStats) {
+	if err := c.stability.Check(filePath, info); err != nil {
+		if errors.Is(err, stability.ErrRecent) {
+			c.deferred.Add(filePath, info)
+			return
+		}
+		logger.Get().Warn().Err(err).Msgf("跳过正在写入的文件: %s", filePath)
+		stats.Busy++
+		return
+	}
+
+	c.classifyFile(localFile(filePath), filePath, true, destDir, stats)
+}
 
-		if err := c.processFile(filePath, destDir, stats); err != nil {
-			logger.Get().Error().Err(err).Msgf("处理文件失败: %s", filePath)
-			stats.Failed++
+// classifyDeferred 复查推迟的文件，稳定的文件按正常流程分类
+func (c *Classifier) classifyDeferred(destDir string, stats *ClassifierStats) {
+	c.stability.Recheck(&c.deferred, func(filePath string, info os.FileInfo, err error) {
+		if err != nil {
+			logger.Get().Warn().Err(err).Msgf("跳过正在写入的文件: %s", filePath)
+			stats.Busy++
+			return
 		}
+		c.classifyFile(localFile(filePath), filePath, true, destDir, stats)
+	})
+}
 
+func (c *Classifier) classifyDirectory(sourceDir, destDir string, stats *ClassifierStats) error {
+	logger.Get().Info().Msgf("处理目录: %s", sourceDir)
+
+	err := c.walker.Walk(sourceDir, func(filePath string, info os.FileInfo) error {
+		c.classifyLocal(filePath, info, destDir, stats)
 		return nil
 	})
 
@@ -93,104 +288,118 @@ func (c *Classifier) classifyDirectory(sourceDir, destDir string, stats *Classif
 	return nil
 }
 
-func (c *Classifier) processFile(filePath, destDir string, stats *ClassifierStats) error {
-	fileType, err := c.detectFileType(filePath)
+func (c *Classifier) processFile(open openFunc, filePath string, local bool, destDir string, stats *ClassifierStats) error {
+	head, full, err := c.readHead(open)
 	if err != nil {
 		logger.Get().Error().Err(err).Msgf("检测文件类型失败: %s", filePath)
 		return err
 	}
 
+	fileType, _ := filetype.Match(head)
+	var mismatch *ExtensionMismatch
+	if c.fixExtensions {
+		mismatch = checkExtension(filePath, fileType)
+	}
 	if fileType == types.Unknown {
-		logger.Get().Debug().Msgf("未知文件类型: %s", filePath)
-		stats.UnknownType++
-		return nil
+		if fileType = fallbackType(filePath, head, full); fileType != types.Unknown {
+			logger.Get().Debug().Msgf("按扩展名或文本内容识别为 %s: %s", fileType.MIME.Value, filePath)
+			stats.Fallback++
+		}
 	}
 
-	category := c.getFileCategory(fileType)
-	categoryDir := filepath.Join(destDir, category)
-	if err := os.MkdirAll(categoryDir, 0755); err != nil {
-		return fmt.Errorf("创建类型目录: %w", err)
+	category, matched := c.rules.Match(fileType, filePath)
+	if !matched {
+		category = OtherCategory
+		if fileType == types.Unknown {
+			stats.UnknownType++
+			if c.unknown == "" {
+				logger.Get().Debug().Msgf("未知文件类型，跳过: %s", filePath)
+				return nil
+			}
+			logger.Get().Debug().Msgf("未知文件类型: %s", filePath)
+			category = c.unknown
+		}
 	}
 
-	subDirIndex, err := c.getSubDirIndex(categoryDir)
+	src := &sourceFile{open: open, path: filePath, fileType: fileType, mismatch: mismatch}
+	layout, naming, untagged, err := c.pathTemplate(src, category)
 	if err != nil {
 		return err
 	}
-
-	subDirName := fmt.Sprintf("part_%04d", subDirIndex)
-	targetSubDir := filepath.Join(categoryDir, subDirName)
-
-	if err := os.MkdirAll(targetSubDir, 0755); err != nil {
-		return fmt.Errorf("创建子目录: %w", err)
+	targetSubDir, err := c.targetDir(layout, src, category, destDir)
+	if err != nil {
+		return err
 	}
 
-	fileName := filepath.Base(filePath)
-	targetPath := filepath.Join(targetSubDir, fileName)
-
-	targetPath, err = c.handleDuplicate(targetPath)
+	targetPath, err := c.targetPath(naming, src, category, targetSubDir)
 	if err != nil {
 		return err
 	}
 
-	if err := c.copyFile(filePath, targetPath); err != nil {
-		return fmt.Errorf("复制文件: %w", err)
+	if err := c.transfer(open, filePath, local, targetPath, stats); err != nil {
+		return err
 	}
 
 	stats.Processed++
-	logger.Get().Debug().Msgf("已处理: %s -> %s (%s)", filePath, targetPath, category)
+	if mismatch != nil {
+		stats.FixedExt++
+		logger.Get().Info().Msgf("修正扩展名: %s", mismatch)
+	}
+	if untagged {
+		stats.Untagged++
+		logger.Get().Debug().Msgf("没有音频标签，使用通用布局: %s", filePath)
+	}
+	if src.dateSource != "" {
+		if stats.DateSources == nil {
+			stats.DateSources = make(map[string]int)
+		}
+		stats.DateSources[src.dateSource]++
+		logger.Get().Debug().Msgf("已处理: %s -> %s (%s，日期来源 %s)", filePath, targetPath, category, src.dateSource)
+	} else {
+		logger.Get().Debug().Msgf("已处理: %s -> %s (%s)", filePath, targetPath, category)
+	}
 
 	return nil
 }
 
-func (c *Classifier) getFileCategory(fileType types.Type) string {
-	mime := fileType.MIME.Value
-
-	// Check MIME type prefix
-	if len(mime) >= 5 {
-		prefix := mime[:5]
-		switch prefix {
-		case "image":
-			return "image"
-		case "video":
-			return "video"
-		case "audio":
-			return "audio"
-		}
-	}
+func (c *Classifier) detectFileType(filePath string) (types.Type, error) {
+	return c.detectType(localFile(filePath))
+}
 
-	// Check for documents and other types based on extension
-	ext := fileType.Extension
-	switch ext {
-	case "pdf", "doc", "docx", "xls", "xlsx", "ppt", "pptx", "txt", "rtf", "odt", "ods", "odp":
-		return "document"
-	case "zip", "tar", "gz", "bz2", "rar", "7z", "xz":
-		return "archive"
+func (c *Classifier) detectType(open openFunc) (types.Type, error) {
+	head, _, err := c.readHead(open)
+	if err != nil {
+		return types.Unknown, err
 	}
 
-	return "other"
+	return filetype.Match(head)
 }
 
-func (c *Classifier) detectFileType(filePath string) (types.Type, error) {
-	file, err := os.Open(filePath)
+// readHead 读取文件开头最多 BufferSize 字节，full 表示已读到文件末尾
+func (c *Classifier) readHead(open openFunc) ([]byte, bool, error) {
+	file, err := open()
 	if err != nil {
-		return types.Unknown, err
+		return nil, false, err
 	}
 	defer file.Close()
 
 	buffer := make([]byte, BufferSize)
-	n, err := file.Read(buffer)
-	if err != nil && err != io.EOF {
-		return types.Unknown, err
+	n, err := io.ReadFull(file, buffer)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return buffer[:n], true, nil
+	}
+	if err != nil {
+		return nil, false, err
 	}
 
-	return filetype.Match(buffer[:n])
+	return buffer[:n], false, nil
 }
 
 func (c *Classifier) getSubDirIndex(categoryDir string) (int, error) {
 	c.fileCountersMu.Lock()
 	defer c.fileCountersMu.Unlock()
 
-	key := filepath.Base(categoryDir)
+	key := categoryDir
 	currentCount, exists := c.fileCounters[key]
 
 	if !exists {
@@ -247,28 +456,44 @@ func (c *Classifier) handleDuplicate(targetPath string) (string, error) {
 }
 
 func (c *Classifier) copyFile(src, dst string) error {
-	sourceFile, err := os.Open(src)
+	_, err := c.copyFrom(localFile(src), dst)
+	return err
+}
+
+// copyFrom 复制文件内容和权限，返回写入的字节数
+func (c *Classifier) copyFrom(open openFunc, dst string) (int64, error) {
+	sourceFile, err := open()
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer sourceFile.Close()
 
 	destFile, err := os.Create(dst)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer destFile.Close()
 
-	if _, _, err = copyBuffer(destFile, sourceFile); err != nil {
-		return err
+	return copyInto(destFile, sourceFile)
+}
+
+// copyInto 将 src 的内容和权限复制到已打开的 dst，返回写入的字节数
+func copyInto(dst *os.File, src fs.File) (int64, error) {
+	_, written, err := copyBuffer(dst, src)
+	if err != nil {
+		return written, err
 	}
 
-	sourceInfo, err := os.Stat(src)
+	sourceInfo, err := src.Stat()
 	if err != nil {
-		return err
+		return written, err
 	}
 
-	return os.Chmod(dst, sourceInfo.Mode())
+	// fs.FS 中的文件（如内存文件系统）可能不带权限位，此时保留创建时的默认权限
+	if sourceInfo.Mode().Perm() == 0 {
+		return written, nil
+	}
+	return written, dst.Chmod(sourceInfo.Mode())
 }
 
 func copyBuffer(dst io.Writer, src io.Reader) (int64, int64, error) {
@@ -369,6 +594,11 @@ func (c *Classifier) GetFileType(filePath string) (string, error) {
 	return fileType.Extension, nil
 }
 
+// Metadata 读取图片或视频的拍摄时间、相机、方向、GPS 和尺寸，不支持的格式返回空的 Metadata
+func (c *Classifier) Metadata(filePath string) (*metadata.Metadata, error) {
+	return metadata.ReadFile(filePath)
+}
+
 func (s *ClassifierStats) String() string {
 	var buf bytes.Buffer
 
@@ -377,6 +607,43 @@ func (s *ClassifierStats) String() string {
 	buf.WriteString(fmt.Sprintf("已处理: %d\n", s.Processed))
 	buf.WriteString(fmt.Sprintf("失败: %d\n", s.Failed))
 	buf.WriteString(fmt.Sprintf("未知类型: %d\n", s.UnknownType))
+	if s.Fallback > 0 {
+		buf.WriteString(fmt.Sprintf("按扩展名或文本识别: %d\n", s.Fallback))
+	}
+	if s.Moved > 0 {
+		buf.WriteString(fmt.Sprintf("已移动: %d (重命名 %s)\n", s.Moved, internal.FormatBytes(s.BytesMoved)))
+	}
+	if s.Linked > 0 {
+		buf.WriteString(fmt.Sprintf("已链接: %d\n", s.Linked))
+	}
+	buf.WriteString(fmt.Sprintf("复制数据: %s\n", internal.FormatBytes(s.BytesCopied)))
+	if len(s.DateSources) > 0 {
+		buf.WriteString("日期来源:")
+		for _, source := range []string{string(metadata.SourceEXIF), string(metadata.SourceContainer), DateSourceMtime} {
+			if n := s.DateSources[source]; n > 0 {
+				buf.WriteString(fmt.Sprintf(" %s %d", source, n))
+			}
+		}
+		buf.WriteString("\n")
+	}
+	if s.FixedExt > 0 {
+		buf.WriteString(fmt.Sprintf("修正扩展名: %d\n", s.FixedExt))
+	}
+	if s.Untagged > 0 {
+		buf.WriteString(fmt.Sprintf("无音频标签（使用通用布局）: %d\n", s.Untagged))
+	}
+	if s.DanglingLinks > 0 {
+		buf.WriteString(fmt.Sprintf("悬空符号链接: %d\n", s.DanglingLinks))
+	}
+	if s.ScanErrors > 0 {
+		buf.WriteString(fmt.Sprintf("跳过（错误）: %d\n", s.ScanErrors))
+	}
+	if s.Busy > 0 {
+		buf.WriteString(fmt.Sprintf("跳过（正在写入）: %d\n", s.Busy))
+	}
+	for _, kind := range scanner.SkippedKinds(s.Skipped) {
+		buf.WriteString(fmt.Sprintf("跳过（%s）: %d\n", scanner.SkipLabel(kind), s.Skipped[kind]))
+	}
 
 	if s.TotalProcessed > 0 {
 		successRate := float64(s.Processed) / float64(s.TotalProcessed) * 100
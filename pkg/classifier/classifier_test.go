@@ -1,10 +1,14 @@
 package classifier
 
 import (
+	"bytes"
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 )
 
 func TestClassifier_Classify(t *testing.T) {
@@ -23,7 +27,7 @@ func TestClassifier_Classify(t *testing.T) {
 		"test.pdf":      "%PDF-1.4",
 		"test.mp3":      "ID3\x04\x00\x00\x00\x00\x00\x00",
 		"test.zip":      "PK\x03\x04",
-		"test.unknown":  "random content",
+		"test.unknown":  "\x00\x01random content",
 		"sub/test2.jpg": "\xff\xd8\xff\xe0\x00\x10JFIF",
 	}
 
@@ -45,8 +49,8 @@ func TestClassifier_Classify(t *testing.T) {
 		t.Fatalf("Classify() error = %v", err)
 	}
 
-	if stats.Processed != 6 {
-		t.Errorf("Expected 6 processed files, got %d", stats.Processed)
+	if stats.Processed != 7 {
+		t.Errorf("Expected 7 processed files, got %d", stats.Processed)
 	}
 
 	if stats.UnknownType != 1 {
@@ -57,7 +61,7 @@ func TestClassifier_Classify(t *testing.T) {
 		t.Errorf("Expected 7 total files, got %d", stats.TotalProcessed)
 	}
 
-	expectedDirs := []string{"image", "audio", "archive"}
+	expectedDirs := []string{"image", "audio", "archive", "unknown"}
 	for _, dir := range expectedDirs {
 		typeDir := filepath.Join(destDir, dir)
 		if _, err := os.Stat(typeDir); os.IsNotExist(err) {
@@ -395,3 +399,125 @@ func TestClassifier_Classify_MultipleSourceDirs(t *testing.T) {
 		t.Errorf("Expected 2 processed files, got %d", stats.Processed)
 	}
 }
+
+func TestClassifier_ClassifyFiles(t *testing.T) {
+	tempDir := t.TempDir()
+
+	sourceDir := filepath.Join(tempDir, "source")
+	destDir := filepath.Join(tempDir, "dest")
+
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatalf("创建源目录失败: %v", err)
+	}
+
+	listed := filepath.Join(sourceDir, "listed.png")
+	unlisted := filepath.Join(sourceDir, "unlisted.jpg")
+	if err := os.WriteFile(listed, []byte("\x89PNG\r\n\x1a\n"), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+	if err := os.WriteFile(unlisted, []byte("\xff\xd8\xff\xe0\x00\x10JFIF"), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	cls := NewClassifier()
+
+	stats, err := cls.ClassifyFiles([]string{listed}, destDir)
+	if err != nil {
+		t.Fatalf("ClassifyFiles() error = %v", err)
+	}
+
+	if stats.TotalProcessed != 1 || stats.Processed != 1 {
+		t.Errorf("Expected 1 processed file, got total=%d processed=%d", stats.TotalProcessed, stats.Processed)
+	}
+}
+
+func TestClassifier_ClassifyFS(t *testing.T) {
+	destDir := filepath.Join(t.TempDir(), "dest")
+
+	fsys := fstest.MapFS{
+		"photos/a.jpg":  {Data: []byte("\xff\xd8\xff\xe0\x00\x10JFIF")},
+		"photos/b.png":  {Data: []byte("\x89PNG\r\n\x1a\n"), Mode: 0600},
+		"notes/unknown": {Data: []byte("\x00\x01random content")},
+	}
+
+	cls := NewClassifier()
+
+	stats, err := cls.ClassifyFS(fsys, destDir)
+	if err != nil {
+		t.Fatalf("ClassifyFS() error = %v", err)
+	}
+
+	if stats.TotalProcessed != 3 {
+		t.Errorf("Expected 3 total files, got %d", stats.TotalProcessed)
+	}
+
+	if stats.Processed != 3 {
+		t.Errorf("Expected 3 processed files, got %d", stats.Processed)
+	}
+
+	if stats.UnknownType != 1 {
+		t.Errorf("Expected 1 unknown type file, got %d", stats.UnknownType)
+	}
+
+	copied := filepath.Join(destDir, "image", "part_0000", "a.jpg")
+	data, err := os.ReadFile(copied)
+	if err != nil {
+		t.Fatalf("Expected %s to be copied: %v", copied, err)
+	}
+	if !bytes.Equal(data, fsys["photos/a.jpg"].Data) {
+		t.Errorf("Copied content mismatch for %s", copied)
+	}
+}
+
+func TestClassifier_Classify_Watch(t *testing.T) {
+	sourceDir := t.TempDir()
+	// 目标目录位于源目录中，监视时不能再次处理分类结果
+	destDir := filepath.Join(sourceDir, "sorted")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cls := NewClassifier()
+	cls.SetWatch(ctx, 50*time.Millisecond)
+
+	type result struct {
+		stats *ClassifierStats
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		stats, err := cls.Classify([]string{sourceDir}, destDir)
+		done <- result{stats, err}
+	}()
+
+	// 等待首次分类完成并进入监视
+	time.Sleep(200 * time.Millisecond)
+
+	if err := os.WriteFile(filepath.Join(sourceDir, "new.png"), []byte("\x89PNG\r\n\x1a\n"), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	copied := filepath.Join(destDir, "image", "part_0000", "new.png")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(copied); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", copied)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// 等待目标目录中的变化被忽略
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+
+	res := <-done
+	if res.err != nil {
+		t.Fatalf("Classify() error = %v", res.err)
+	}
+	if res.stats.TotalProcessed != 1 || res.stats.Processed != 1 {
+		t.Errorf("Expected 1 processed file, got total=%d processed=%d", res.stats.TotalProcessed, res.stats.Processed)
+	}
+}
@@ -0,0 +1,302 @@
+package classifier
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/h2non/filetype"
+	"github.com/h2non/filetype/types"
+
+	"github.com/moyu-x/classified-file/pkg/logger"
+	"github.com/moyu-x/classified-file/pkg/scanner"
+	"github.com/moyu-x/classified-file/pkg/stability"
+)
+
+// extensionAliases 是按内容识别出的扩展名可以接受的其他扩展名：同一格式的不同写法、
+// 基于同一容器的格式（如 apk、jar 是 zip，NEF、DNG 是 TIFF）等
+var extensionAliases = map[string][]string{
+	"jpg":  {"jpeg", "jpe", "jfif", "jif"},
+	"tif":  {"tiff", "nef", "nrw", "dng", "arw", "srf", "sr2", "pef", "3fr", "erf", "mef", "mos", "kdc", "dcr", "iiq"},
+	"png":  {"apng"},
+	"bmp":  {"dib"},
+	"ico":  {"cur"},
+	"heif": {"heic", "heics", "heifs", "hif", "avif", "avci"},
+	"jp2":  {"j2k", "jpf", "jpx", "jpm"},
+	"jxr":  {"wdp", "hdp"},
+	"psd":  {"psb"},
+
+	"mp4":  {"m4v", "m4a", "m4b", "m4p", "f4v", "f4a", "mp4v", "3gp", "3g2", "mov"},
+	"m4v":  {"mp4"},
+	"m4a":  {"mp4", "m4b", "m4p"},
+	"mov":  {"qt", "mp4"},
+	"3gp":  {"3g2", "mp4"},
+	"mkv":  {"mka", "mks", "mk3d", "webm"},
+	"webm": {"mkv"},
+	"mpg":  {"mpeg", "mpe", "m1v", "m2v", "vob", "mod"},
+	"wmv":  {"asf", "wma"},
+	"ogg":  {"oga", "ogv", "ogx", "opus", "spx"},
+	"mp3":  {"mpga", "mp2"},
+	"aiff": {"aif", "aifc"},
+	"mid":  {"midi", "kar", "rmi"},
+	"wav":  {"wave"},
+
+	"zip":    {"jar", "war", "ear", "apk", "aab", "aar", "xapk", "ipa", "xpi", "whl", "nupkg", "vsix", "kmz", "cbz", "3mf", "appx", "msix", "odt", "ods", "odp", "odg", "odf", "sketch"},
+	"docx":   {"docm", "dotx", "dotm"},
+	"xlsx":   {"xlsm", "xltx", "xltm"},
+	"pptx":   {"pptm", "potx", "potm", "ppsx", "ppsm"},
+	"doc":    {"dot", "xls", "xlt", "ppt", "pot", "pps", "msi", "msg", "vsd", "pub", "wps"},
+	"xls":    {"xlt", "doc", "ppt", "msi", "msg"},
+	"ppt":    {"pot", "pps", "doc", "xls"},
+	"pdf":    {"ai"},
+	"ps":     {"eps"},
+	"gz":     {"tgz", "gzip", "svgz", "emz"},
+	"bz2":    {"tbz", "tbz2", "tb2"},
+	"xz":     {"txz"},
+	"zst":    {"tzst", "zstd"},
+	"Z":      {"taz"},
+	"rar":    {"cbr"},
+	"7z":     {"cb7"},
+	"tar":    {"ova", "cbt"},
+	"ar":     {"a", "lib"},
+	"exe":    {"dll", "sys", "scr", "cpl", "ocx", "efi", "com", "mui", "drv", "ax"},
+	"sqlite": {"db", "sqlite3", "db3", "s3db", "sl3", "mbtiles", "gpkg"},
+	"ttf":    {"otf", "ttc"},
+	"otf":    {"ttf", "ttc"},
+	"iso":    {"img"},
+	"dcm":    {"dicom"},
+}
+
+// uncheckedTypes 是通常没有扩展名或扩展名不固定的类型，不检查扩展名
+var uncheckedTypes = map[string]bool{
+	"elf":   true,
+	"macho": true,
+}
+
+// ExtensionMismatch 是扩展名与按内容识别出的类型不一致的文件
+type ExtensionMismatch struct {
+	Path     string
+	Ext      string // 原扩展名（不含点），没有扩展名时为空
+	Detected string // 按内容识别出的扩展名
+	MIME     string
+}
+
+// checkExtension 检查文件扩展名是否与按内容识别出的类型一致，一致或无法识别时返回 nil
+func checkExtension(filePath string, fileType types.Type) *ExtensionMismatch {
+	detected := fileType.Extension
+	if fileType == types.Unknown || detected == "" || uncheckedTypes[detected] {
+		return nil
+	}
+
+	ext := strings.TrimPrefix(filepath.Ext(filepath.Base(filePath)), ".")
+	if extensionMatches(strings.ToLower(ext), detected) {
+		return nil
+	}
+	return &ExtensionMismatch{Path: filePath, Ext: ext, Detected: detected, MIME: fileType.MIME.Value}
+}
+
+// extensionMatches 判断扩展名 ext（小写）是否可以用于识别出的类型 detected
+func extensionMatches(ext, detected string) bool {
+	if ext == strings.ToLower(detected) {
+		return true
+	}
+	for _, alias := range extensionAliases[detected] {
+		if ext == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// isKnownExtension 判断 ext 是否为已知的文件扩展名，未知时视为文件名的一部分而不是扩展名
+func isKnownExtension(ext string) bool {
+	ext = strings.ToLower(ext)
+	if ext == "" {
+		return false
+	}
+	if filetype.IsSupported(ext) {
+		return true
+	}
+	if _, ok := extensionTypes[ext]; ok {
+		return true
+	}
+	for _, aliases := range extensionAliases {
+		for _, alias := range aliases {
+			if ext == alias {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FixedName 返回使用识别出的扩展名的文件名：原扩展名是已知扩展名时替换，
+// 否则（如 report.v2）追加
+func (m *ExtensionMismatch) FixedName() string {
+	base := filepath.Base(m.Path)
+	if isKnownExtension(m.Ext) {
+		base = strings.TrimSuffix(base, "."+m.Ext)
+	}
+	return base + "." + m.Detected
+}
+
+func (m *ExtensionMismatch) String() string {
+	if m.Ext == "" {
+		return fmt.Sprintf("%s: 缺少扩展名，识别为 .%s (%s)", m.Path, m.Detected, m.MIME)
+	}
+	return fmt.Sprintf("%s: 扩展名 .%s 与内容不符，识别为 .%s (%s)", m.Path, m.Ext, m.Detected, m.MIME)
+}
+
+// CheckExtension 检查单个文件的扩展名，一致或无法识别类型时返回 nil
+func (c *Classifier) CheckExtension(filePath string) (*ExtensionMismatch, error) {
+	fileType, err := c.detectFileType(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return checkExtension(filePath, fileType), nil
+}
+
+// SetFixExtensions 设置分类时是否把与内容不符或缺少的扩展名改为识别出的扩展名
+func (c *Classifier) SetFixExtensions(fix bool) {
+	c.fixExtensions = fix
+}
+
+// ExtensionStats 是扩展名检查的统计
+type ExtensionStats struct {
+	Checked    int
+	Mismatches []ExtensionMismatch
+	Renamed    int
+	Failed     int
+	Busy       int
+	ScanErrors int
+	Skipped    map[string]int
+}
+
+// CheckExtensions 检查目录中所有文件的扩展名，rename 为 true 时将不一致的文件重命名为识别出的扩展名
+func (c *Classifier) CheckExtensions(dirs []string, rename bool) (*ExtensionStats, error) {
+	stats := &ExtensionStats{}
+	for _, dir := range dirs {
+		logger.Get().Info().Msgf("检查目录: %s", dir)
+		err := c.walker.Walk(dir, func(filePath string, info os.FileInfo) error {
+			c.checkLocal(filePath, info, rename, stats)
+			return nil
+		})
+		if err != nil {
+			logger.Get().Error().Err(err).Msgf("遍历目录失败: %s", dir)
+			return stats, err
+		}
+	}
+	c.checkDeferred(rename, stats)
+
+	c.finishCheck(stats)
+	return stats, nil
+}
+
+// CheckExtensionFiles 检查给定文件列表的扩展名而不遍历目录，用于 --files-from
+func (c *Classifier) CheckExtensionFiles(files []string, rename bool) (*ExtensionStats, error) {
+	stats := &ExtensionStats{}
+	err := c.walker.WalkList(files, func(filePath string, info os.FileInfo) error {
+		c.checkLocal(filePath, info, rename, stats)
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+	c.checkDeferred(rename, stats)
+
+	c.finishCheck(stats)
+	return stats, nil
+}
+
+func (c *Classifier) finishCheck(stats *ExtensionStats) {
+	stats.ScanErrors = c.walker.Errors.Len()
+	stats.Skipped = c.walker.Skipped()
+	logger.Get().Info().Msg("扩展名检查完成")
+}
+
+// checkLocal 检查单个本地文件，仍在写入的文件会被跳过，最近被修改的文件推迟到本轮结束后复查
+func (c *Classifier) checkLocal(filePath string, info os.FileInfo, rename bool, stats *ExtensionStats) {
+	if err := c.stability.Check(filePath, info); err != nil {
+		if errors.Is(err, stability.ErrRecent) {
+			c.deferred.Add(filePath, info)
+			return
+		}
+		logger.Get().Warn().Err(err).Msgf("跳过正在写入的文件: %s", filePath)
+		stats.Busy++
+		return
+	}
+
+	c.checkStable(filePath, rename, stats)
+}
+
+// checkDeferred 复查推迟的文件，稳定的文件按正常流程检查
+func (c *Classifier) checkDeferred(rename bool, stats *ExtensionStats) {
+	c.stability.Recheck(&c.deferred, func(filePath string, info os.FileInfo, err error) {
+		if err != nil {
+			logger.Get().Warn().Err(err).Msgf("跳过正在写入的文件: %s", filePath)
+			stats.Busy++
+			return
+		}
+		c.checkStable(filePath, rename, stats)
+	})
+}
+
+func (c *Classifier) checkStable(filePath string, rename bool, stats *ExtensionStats) {
+	stats.Checked++
+	mismatch, err := c.CheckExtension(filePath)
+	if err != nil {
+		logger.Get().Error().Err(err).Msgf("检测文件类型失败: %s", filePath)
+		c.walker.Errors.Add(filePath, scanner.OpRead, err)
+		stats.Failed++
+		return
+	}
+	if mismatch == nil {
+		return
+	}
+	stats.Mismatches = append(stats.Mismatches, *mismatch)
+	logger.Get().Debug().Msg(mismatch.String())
+	if !rename {
+		return
+	}
+
+	target, err := c.handleDuplicate(filepath.Join(filepath.Dir(filePath), mismatch.FixedName()))
+	if err == nil {
+		err = os.Rename(filePath, target)
+	}
+	if err != nil {
+		logger.Get().Error().Err(err).Msgf("重命名文件失败: %s", filePath)
+		c.walker.Errors.Add(filePath, scanner.OpMove, err)
+		stats.Failed++
+		return
+	}
+	stats.Renamed++
+	logger.Get().Info().Msgf("已重命名: %s -> %s", filePath, target)
+}
+
+func (s *ExtensionStats) String() string {
+	var buf strings.Builder
+
+	buf.WriteString("========== 扩展名检查 ==========\n")
+	buf.WriteString(fmt.Sprintf("检查文件数: %d\n", s.Checked))
+	buf.WriteString(fmt.Sprintf("扩展名不符: %d\n", len(s.Mismatches)))
+	if s.Renamed > 0 {
+		buf.WriteString(fmt.Sprintf("已重命名: %d\n", s.Renamed))
+	}
+	if s.Failed > 0 {
+		buf.WriteString(fmt.Sprintf("失败: %d\n", s.Failed))
+	}
+	if s.ScanErrors > 0 {
+		buf.WriteString(fmt.Sprintf("跳过（错误）: %d\n", s.ScanErrors))
+	}
+	if s.Busy > 0 {
+		buf.WriteString(fmt.Sprintf("跳过（正在写入）: %d\n", s.Busy))
+	}
+	for _, kind := range scanner.SkippedKinds(s.Skipped) {
+		buf.WriteString(fmt.Sprintf("跳过（%s）: %d\n", scanner.SkipLabel(kind), s.Skipped[kind]))
+	}
+	buf.WriteString("==============================")
+
+	return buf.String()
+}
@@ -0,0 +1,149 @@
+package classifier
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// webpData 是 WebP 文件头
+var webpData = []byte("RIFF\x00\x00\x00\x00WEBPVP8 ")
+
+func TestCheckExtension(t *testing.T) {
+	tempDir := t.TempDir()
+	cls := NewClassifier()
+
+	tests := []struct {
+		name  string
+		data  []byte
+		fixed string // 为空表示扩展名正确
+	}{
+		{"photo.png", pngData, ""},
+		{"photo.PNG", pngData, ""},
+		{"photo.jpg", pngData, "photo.png"},
+		{"photo.JPG", webpData, "photo.webp"},
+		{"download", pngData, "download.png"},
+		{"report.v2", pngData, "report.v2.png"},
+		{"app.apk", []byte("PK\x03\x04\x14\x00\x00\x00"), ""},
+		{"notes.txt", []byte("plain text"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(tempDir, tt.name)
+			if err := os.WriteFile(path, tt.data, 0644); err != nil {
+				t.Fatalf("创建测试文件失败: %v", err)
+			}
+			defer os.Remove(path)
+
+			mismatch, err := cls.CheckExtension(path)
+			if err != nil {
+				t.Fatalf("CheckExtension() error = %v", err)
+			}
+			switch {
+			case tt.fixed == "" && mismatch != nil:
+				t.Errorf("Expected no mismatch, got %s", mismatch)
+			case tt.fixed != "" && mismatch == nil:
+				t.Errorf("Expected mismatch fixed to %s", tt.fixed)
+			case tt.fixed != "" && mismatch.FixedName() != tt.fixed:
+				t.Errorf("FixedName() = %s, want %s", mismatch.FixedName(), tt.fixed)
+			}
+		})
+	}
+}
+
+func TestClassifier_CheckExtensions(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string][]byte{
+		"a.jpg":  pngData,
+		"a.png":  webpData,
+		"b":      webpData,
+		"ok.png": pngData,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, name), data, 0644); err != nil {
+			t.Fatalf("创建测试文件失败: %v", err)
+		}
+	}
+
+	report, err := NewClassifier().CheckExtensions([]string{tempDir}, false)
+	if err != nil {
+		t.Fatalf("CheckExtensions() error = %v", err)
+	}
+	if report.Checked != 4 || len(report.Mismatches) != 3 || report.Renamed != 0 {
+		t.Errorf("Expected 4 checked and 3 mismatches, got %+v", report)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "a.jpg")); err != nil {
+		t.Errorf("Expected report-only check to keep files: %v", err)
+	}
+
+	stats, err := NewClassifier().CheckExtensions([]string{tempDir}, true)
+	if err != nil {
+		t.Fatalf("CheckExtensions() error = %v", err)
+	}
+	if stats.Renamed != 3 || stats.Failed != 0 {
+		t.Errorf("Expected 3 renamed, got %+v", stats)
+	}
+
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatalf("读取目录失败: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+
+	// 按名称顺序处理：a.png 仍被占用，a.jpg 改名为 a_1.png
+	want := []string{"a.webp", "a_1.png", "b.webp", "ok.png"}
+	if len(names) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, names)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("Expected %v, got %v", want, names)
+			break
+		}
+	}
+}
+
+func TestClassifier_Classify_FixExtensions(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	destDir := filepath.Join(tempDir, "dest")
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatalf("创建源目录失败: %v", err)
+	}
+	for name, data := range map[string][]byte{"x.jpg": pngData, "download": webpData, "y.png": pngData} {
+		if err := os.WriteFile(filepath.Join(sourceDir, name), data, 0644); err != nil {
+			t.Fatalf("创建测试文件失败: %v", err)
+		}
+	}
+
+	layout, err := ParseLayout("{category}")
+	if err != nil {
+		t.Fatalf("ParseLayout() error = %v", err)
+	}
+	cls := NewClassifier()
+	cls.SetLayout(layout)
+	cls.SetFixExtensions(true)
+
+	stats, err := cls.Classify([]string{sourceDir}, destDir)
+	if err != nil {
+		t.Fatalf("Classify() error = %v", err)
+	}
+	if stats.FixedExt != 2 {
+		t.Errorf("Expected 2 fixed extensions, got %d", stats.FixedExt)
+	}
+
+	for _, name := range []string{"x.png", "download.webp", "y.png"} {
+		if _, err := os.Stat(filepath.Join(destDir, "image", name)); err != nil {
+			t.Errorf("Expected %s in destination: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(sourceDir, "x.jpg")); err != nil {
+		t.Errorf("Expected source file to be kept in copy mode: %v", err)
+	}
+}
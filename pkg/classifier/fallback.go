@@ -0,0 +1,122 @@
+package classifier
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/h2non/filetype/types"
+)
+
+// UnknownCategory 是无法识别类型的文件默认放入的分类
+const UnknownCategory = "unknown"
+
+// extensionType 是按扩展名推断的类型，text 为 true 时要求内容是文本
+type extensionType struct {
+	mime string
+	text bool
+}
+
+// extensionTypes 是内容无法识别时按扩展名推断类型的映射，主要覆盖 filetype 无法识别的文本格式
+var extensionTypes = map[string]extensionType{
+	"txt":  {"text/plain", true},
+	"log":  {"text/plain", true},
+	"md":   {"text/markdown", true},
+	"csv":  {"text/csv", true},
+	"tsv":  {"text/tab-separated-values", true},
+	"html": {"text/html", true},
+	"htm":  {"text/html", true},
+	"css":  {"text/css", true},
+	"xml":  {"application/xml", true},
+	"json": {"application/json", true},
+	"yaml": {"application/yaml", true},
+	"yml":  {"application/yaml", true},
+	"toml": {"application/toml", true},
+	"ini":  {"text/plain", true},
+	"svg":  {"image/svg+xml", true},
+	"srt":  {"text/plain", true},
+	"vtt":  {"text/vtt", true},
+	"go":   {"text/x-go", true},
+	"py":   {"text/x-python", true},
+	"js":   {"text/javascript", true},
+	"ts":   {"text/x-typescript", true},
+	"java": {"text/x-java", true},
+	"c":    {"text/x-c", true},
+	"h":    {"text/x-c", true},
+	"cpp":  {"text/x-c++", true},
+	"hpp":  {"text/x-c++", true},
+	"rs":   {"text/x-rust", true},
+	"sh":   {"text/x-shellscript", true},
+	"rb":   {"text/x-ruby", true},
+	"php":  {"text/x-php", true},
+	"sql":  {"application/sql", true},
+	"iso":  {"application/x-iso9660-image", false},
+	"dmg":  {"application/x-apple-diskimage", false},
+	"img":  {"application/octet-stream", false},
+}
+
+// SetUnknownCategory 设置无法识别类型的文件放入的分类，为空时跳过这些文件
+func (c *Classifier) SetUnknownCategory(name string) {
+	c.unknown = name
+}
+
+// fallbackType 在内容无法识别时依次按扩展名映射和文本检测推断类型，
+// head 是文件开头的内容，full 表示 head 已包含整个文件
+func fallbackType(fileName string, head []byte, full bool) types.Type {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(fileName), "."))
+	text := isText(head, full)
+
+	if t, ok := extensionTypes[ext]; ok && (text || !t.text) {
+		return newType(ext, t.mime)
+	}
+	if text {
+		if ext == "" {
+			ext = "txt"
+		}
+		return newType(ext, "text/plain")
+	}
+	return types.Unknown
+}
+
+// newType 构造类型而不注册，types.NewType 会把扩展名写入 filetype 的全局类型表，
+// 按文件名推断出的扩展名注册后会改变 filetype.GetType 和 isKnownExtension 的结果
+func newType(ext, mime string) types.Type {
+	return types.Type{MIME: types.NewMIME(mime), Extension: ext}
+}
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// isText 判断内容是否为文本：带 BOM，或是不含控制字符的合法 UTF-8，
+// 内容被截断时忽略末尾不完整的字符
+func isText(head []byte, full bool) bool {
+	if len(head) == 0 {
+		return false
+	}
+	if bytes.HasPrefix(head, bomUTF8) || bytes.HasPrefix(head, bomUTF16LE) || bytes.HasPrefix(head, bomUTF16BE) {
+		return true
+	}
+
+	if !full {
+		for i := 0; i < utf8.UTFMax-1 && len(head) > 0; i++ {
+			if r, _ := utf8.DecodeLastRune(head); r != utf8.RuneError {
+				break
+			}
+			head = head[:len(head)-1]
+		}
+	}
+	if !utf8.Valid(head) {
+		return false
+	}
+
+	for _, b := range head {
+		if b < 0x20 && b != '\t' && b != '\n' && b != '\r' && b != '\f' && b != '\v' && b != 0x1b {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,134 @@
+package classifier
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/h2non/filetype"
+	"github.com/h2non/filetype/types"
+)
+
+func TestIsText(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		full bool
+		want bool
+	}{
+		{"ascii", []byte("hello\tworld\r\n"), true, true},
+		{"utf8", []byte("分类文件"), true, true},
+		{"truncated rune", []byte("分类文件")[:5], false, true},
+		{"truncated rune in complete file", []byte("分类文件")[:5], true, false},
+		{"utf16 bom", []byte{0xFF, 0xFE, 'a', 0x00}, true, true},
+		{"nul byte", []byte("a\x00b"), true, false},
+		{"invalid utf8", []byte{0xC3, 0x28}, true, false},
+		{"empty", nil, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isText(tt.data, tt.full); got != tt.want {
+				t.Errorf("isText(%q) = %v, want %v", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFallbackType(t *testing.T) {
+	tests := []struct {
+		fileName string
+		data     string
+		wantExt  string
+		wantMIME string
+	}{
+		{"data.JSON", `{"a": 1}`, "json", "application/json"},
+		{"icon.svg", "<svg></svg>", "svg", "image/svg+xml"},
+		{"README", "plain text", "txt", "text/plain"},
+		{"notes.abc", "plain text", "abc", "text/plain"},
+		{"disk.iso", "\x00\x01\x02", "iso", "application/x-iso9660-image"},
+		{"fake.json", "\x00\x01\x02", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			got := fallbackType(tt.fileName, []byte(tt.data), true)
+			if tt.wantExt == "" {
+				if got != types.Unknown {
+					t.Errorf("fallbackType() = %v, want Unknown", got)
+				}
+				return
+			}
+			if got.Extension != tt.wantExt || got.MIME.Value != tt.wantMIME {
+				t.Errorf("fallbackType() = %s %s, want %s %s", got.Extension, got.MIME.Value, tt.wantExt, tt.wantMIME)
+			}
+		})
+	}
+}
+
+func TestFallbackType_DoesNotRegister(t *testing.T) {
+	before := filetype.GetType("jpg")
+
+	// 扩展名来自文件名，不能写入 filetype 的全局类型表
+	fallbackType("photo.jpg", []byte("plain text"), true)
+	fallbackType("notes.v2", []byte("plain text"), true)
+
+	if got := filetype.GetType("jpg"); got != before {
+		t.Errorf("filetype.GetType(jpg) changed from %v to %v", before.MIME.Value, got.MIME.Value)
+	}
+	if filetype.IsSupported("v2") || isKnownExtension("v2") {
+		t.Errorf("Fallback extension v2 should not become a known extension")
+	}
+
+	m := ExtensionMismatch{Path: "data.v2", Ext: "v2", Detected: "png"}
+	if got := m.FixedName(); got != "data.v2.png" {
+		t.Errorf("FixedName() = %s, want data.v2.png", got)
+	}
+}
+
+func TestClassifier_Classify_Fallback(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	destDir := filepath.Join(tempDir, "dest")
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatalf("创建源目录失败: %v", err)
+	}
+
+	files := map[string]string{
+		"main.go":   "package main\n",
+		"data.csv":  "a,b\n1,2\n",
+		"notes.txt": "plain text",
+		"icon.svg":  "<svg xmlns=\"http://www.w3.org/2000/svg\"/>",
+		"blob.dat":  "\x00\x01\x02\x03",
+		"empty":     "",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(sourceDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("创建测试文件失败: %v", err)
+		}
+	}
+
+	cls := NewClassifier()
+	cls.SetUnknownCategory("misc/unknown")
+
+	stats, err := cls.Classify([]string{sourceDir}, destDir)
+	if err != nil {
+		t.Fatalf("Classify() error = %v", err)
+	}
+	if stats.Processed != len(files) || stats.Fallback != 4 || stats.UnknownType != 2 {
+		t.Errorf("Expected all files processed with 4 fallbacks and 2 unknown, got %+v", stats)
+	}
+
+	for _, path := range []string{
+		filepath.Join("code", "part_0000", "main.go"),
+		filepath.Join("text", "part_0000", "data.csv"),
+		filepath.Join("document", "part_0000", "notes.txt"),
+		filepath.Join("image", "part_0000", "icon.svg"),
+		filepath.Join("misc", "unknown", "part_0000", "blob.dat"),
+		filepath.Join("misc", "unknown", "part_0000", "empty"),
+	} {
+		if _, err := os.Stat(filepath.Join(destDir, path)); err != nil {
+			t.Errorf("Expected %s: %v", path, err)
+		}
+	}
+}
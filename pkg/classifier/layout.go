@@ -0,0 +1,237 @@
+package classifier
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultLayout 是默认的目录布局：每个分类下每 filesPerDir 个文件一个 part_NNNN 目录
+const DefaultLayout = "{category}/{part}"
+
+// DateSourceMtime 表示日期取自文件修改时间
+const DateSourceMtime = "mtime"
+
+const partSuffix = "{part}"
+
+// layoutFields 是目录布局中可以使用的字段，{part} 只能作为最后一级目录单独处理
+var layoutFields = map[string]bool{
+	"category":    true,
+	"year":        true,
+	"month":       true,
+	"day":         true,
+	"date":        true,
+	"artist":      true,
+	"albumartist": true,
+	"album":       true,
+	"title":       true,
+	"track":       true,
+	"disc":        true,
+}
+
+// Layout 是目标目录的布局模板，如 {category}/{year}/{month}
+type Layout struct {
+	dir  *template
+	part bool
+}
+
+// defaultLayout 是编译后的默认布局
+var defaultLayout, _ = ParseLayout(DefaultLayout)
+
+// ParseLayout 解析目录布局，目录之间用 / 分隔，可用字段：
+// {category} 分类，{year}、{month}、{day} 文件日期，{date:格式} 按 Go 时间格式输出日期（默认 2006-01-02），
+// {artist}、{albumartist}、{album}、{title}、{track:N}、{disc:N} 音频标签，
+// {part} 只能作为最后一级目录，按每目录文件数分为 part_NNNN 子目录
+func ParseLayout(s string) (*Layout, error) {
+	s = strings.Trim(strings.TrimSpace(s), "/")
+	if s == "" {
+		return nil, fmt.Errorf("目录布局不能为空")
+	}
+
+	l := &Layout{}
+	if s == partSuffix {
+		l.part, s = true, ""
+	} else if strings.HasSuffix(s, "/"+partSuffix) {
+		l.part, s = true, strings.TrimSuffix(s, "/"+partSuffix)
+	}
+	if strings.Contains(s, partSuffix) {
+		return nil, fmt.Errorf("目录布局 %q 中的 {part} 只能作为最后一级目录", s)
+	}
+	for _, elem := range strings.Split(s, "/") {
+		if elem == ".." {
+			return nil, fmt.Errorf("目录布局不能包含 ..: %s", s)
+		}
+	}
+
+	tmpl, err := parseTemplate(s, layoutFields)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range tmpl.parts {
+		if p.field == "track" || p.field == "disc" {
+			if err := validateWidth(p); err != nil {
+				return nil, err
+			}
+		}
+	}
+	l.dir = tmpl
+
+	return l, nil
+}
+
+// SetLayout 设置目标目录布局，nil 表示使用默认布局
+func (c *Classifier) SetLayout(layout *Layout) {
+	if layout == nil {
+		layout = defaultLayout
+	}
+	c.layout = layout
+}
+
+// expand 返回相对目标目录的目录，不含 part_NNNN
+func (l *Layout) expand(src *sourceFile, category string) (string, error) {
+	rel, err := l.dir.expand(func(field, arg string) (string, error) {
+		if field == "category" {
+			return category, nil
+		}
+		if isTagField(field) {
+			return src.tagValue(field, arg)
+		}
+
+		date, err := src.date()
+		if err != nil {
+			return "", fmt.Errorf("读取文件日期: %w", err)
+		}
+		switch field {
+		case "year":
+			return date.Format("2006"), nil
+		case "month":
+			return date.Format("01"), nil
+		case "day":
+			return date.Format("02"), nil
+		case "date":
+			if arg == "" {
+				arg = "2006-01-02"
+			}
+			return date.Format(arg), nil
+		}
+		return "", fmt.Errorf("未知字段")
+	})
+	if err != nil {
+		return "", err
+	}
+
+	rel = path.Clean("/" + rel)[1:]
+	if rel == "" {
+		return ".", nil
+	}
+	return filepath.FromSlash(rel), nil
+}
+
+func (l *Layout) String() string {
+	if !l.part {
+		return l.dir.String()
+	}
+	if l.dir.String() == "" {
+		return partSuffix
+	}
+	return l.dir.String() + "/" + partSuffix
+}
+
+// targetDir 按布局创建并返回文件的目标目录
+func (c *Classifier) targetDir(layout *Layout, src *sourceFile, category, destDir string) (string, error) {
+	rel, err := layout.expand(src, category)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(destDir, rel)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("创建类型目录: %w", err)
+	}
+	if !layout.part {
+		return dir, nil
+	}
+
+	subDirIndex, err := c.getSubDirIndex(dir)
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Join(dir, fmt.Sprintf("part_%04d", subDirIndex))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("创建子目录: %w", err)
+	}
+
+	return dir, nil
+}
+
+// PathTemplate 是某个分类专用的目标路径模板，由目录布局和文件名模板组成，
+// 如 audio/{artist}/{album}/{track:02} - {title}.{ext}
+type PathTemplate struct {
+	layout *Layout
+	naming *NameTemplate
+}
+
+// ParsePathTemplate 解析分类路径模板，最后一级为文件名模板，之前为目录布局（可用字段见 ParseLayout），
+// 只有文件名时放在 {category} 目录中
+func ParsePathTemplate(s string) (*PathTemplate, error) {
+	s = strings.TrimSpace(s)
+	dir, name := "{category}", s
+	if i := strings.LastIndex(s, "/"); i >= 0 {
+		dir, name = s[:i], s[i+1:]
+	}
+	if strings.TrimSpace(name) == "" {
+		return nil, fmt.Errorf("路径模板 %q 缺少文件名", s)
+	}
+
+	layout, err := ParseLayout(dir)
+	if err != nil {
+		return nil, err
+	}
+	naming, err := ParseNameTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+	return &PathTemplate{layout: layout, naming: naming}, nil
+}
+
+// SetCategoryTemplates 设置各分类专用的路径模板，未设置的分类使用 SetLayout 和 SetNameTemplate 的设置。
+// 分类名称不区分大小写，模板使用音频标签而文件没有标签时，同样使用通用的布局和文件名
+func (c *Classifier) SetCategoryTemplates(templates map[string]*PathTemplate) {
+	c.templates = make(map[string]*PathTemplate, len(templates))
+	for category, tmpl := range templates {
+		c.templates[CategoryKey(category)] = tmpl
+	}
+}
+
+// CategoryKey 返回分类路径模板使用的键，规则名称和模板中的分类名称都按它比较
+func CategoryKey(category string) string {
+	return strings.ToLower(strings.TrimSpace(category))
+}
+
+// usesTags 判断路径模板是否使用音频标签
+func (t *PathTemplate) usesTags() bool {
+	return t.layout.dir.usesTags() || t.naming.tmpl.usesTags()
+}
+
+func (t *PathTemplate) String() string {
+	return t.layout.String() + "/" + t.naming.String()
+}
+
+// pathTemplate 返回文件使用的目录布局和文件名模板，untagged 表示因文件没有音频标签而未使用分类模板
+func (c *Classifier) pathTemplate(src *sourceFile, category string) (layout *Layout, naming *NameTemplate, untagged bool, err error) {
+	t := c.templates[CategoryKey(category)]
+	if t == nil {
+		return c.layout, c.naming, false, nil
+	}
+	if t.usesTags() {
+		tagged, err := src.hasTags()
+		if err != nil {
+			return nil, nil, false, err
+		}
+		if !tagged {
+			return c.layout, c.naming, true, nil
+		}
+	}
+	return t.layout, t.naming, false, nil
+}
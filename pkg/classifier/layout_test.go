@@ -0,0 +1,195 @@
+package classifier
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moyu-x/classified-file/pkg/metadata"
+)
+
+func TestParseLayout(t *testing.T) {
+	date := time.Date(2024, 3, 9, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		layout string
+		want   string
+		part   bool
+	}{
+		{DefaultLayout, "image", true},
+		{"{category}/{year}/{month}", filepath.Join("image", "2024", "03"), false},
+		{"/{year}/{month}/{day}/", filepath.Join("2024", "03", "09"), false},
+		{"photos/{date:2006/01}/{category}/{part}", filepath.Join("photos", "2024", "03", "image"), true},
+		{"{date}", "2024-03-09", false},
+		{"{part}", ".", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.layout, func(t *testing.T) {
+			l, err := ParseLayout(tt.layout)
+			if err != nil {
+				t.Fatalf("ParseLayout() error = %v", err)
+			}
+			src := &sourceFile{meta: &metadata.Metadata{Created: date, CreatedSource: metadata.SourceEXIF}}
+			got, err := l.expand(src, "image")
+			if err != nil {
+				t.Fatalf("expand() error = %v", err)
+			}
+			if got != tt.want || l.part != tt.part {
+				t.Errorf("expand() = %q part=%v, want %q part=%v", got, l.part, tt.want, tt.part)
+			}
+		})
+	}
+}
+
+func TestParseLayout_Invalid(t *testing.T) {
+	for _, layout := range []string{"", "/", "{part}/{category}", "{category}/{unknown}", "{category", "category}", "../{category}"} {
+		if _, err := ParseLayout(layout); err == nil {
+			t.Errorf("ParseLayout(%q) should fail", layout)
+		}
+	}
+}
+
+// exifJPEG 构造带 EXIF 相机型号和 DateTimeOriginal 的最小 JPEG
+func exifJPEG(date, model string) []byte {
+	be := binary.BigEndian
+	dateData, modelData := date+"\x00", model+"\x00"
+	var tiff bytes.Buffer
+	tiff.WriteString("MM")
+	binary.Write(&tiff, be, uint16(42))
+	binary.Write(&tiff, be, uint32(8))
+	// IFD0：Model 和指向偏移 38 的 ExifIFD 指针
+	binary.Write(&tiff, be, uint16(2))
+	binary.Write(&tiff, be, []uint16{0x0110, 2})
+	binary.Write(&tiff, be, []uint32{uint32(len(modelData)), uint32(56 + len(dateData))})
+	binary.Write(&tiff, be, []uint16{0x8769, 4})
+	binary.Write(&tiff, be, []uint32{1, 38, 0})
+	// EXIF IFD：DateTimeOriginal 数据位于偏移 56，之后是 Model
+	binary.Write(&tiff, be, uint16(1))
+	binary.Write(&tiff, be, []uint16{0x9003, 2})
+	binary.Write(&tiff, be, []uint32{uint32(len(dateData)), 56, 0})
+	tiff.WriteString(dateData)
+	tiff.WriteString(modelData)
+
+	var buf bytes.Buffer
+	buf.Write([]byte{0xFF, 0xD8, 0xFF, 0xE1})
+	binary.Write(&buf, be, uint16(2+6+tiff.Len()))
+	buf.WriteString("Exif\x00\x00")
+	buf.Write(tiff.Bytes())
+	buf.Write([]byte{0xFF, 0xDA, 0x00, 0x02, 0xFF, 0xD9})
+	return buf.Bytes()
+}
+
+// creationMP4 构造 mvhd 中带创建时间的最小 MP4
+func creationMP4(created time.Time) []byte {
+	be := binary.BigEndian
+	var buf bytes.Buffer
+	buf.Write([]byte{0, 0, 0, 16})
+	buf.WriteString("ftypisom\x00\x00\x02\x00")
+	binary.Write(&buf, be, uint32(8+8+12))
+	buf.WriteString("moov")
+	binary.Write(&buf, be, uint32(8+12))
+	buf.WriteString("mvhd")
+	buf.Write([]byte{0, 0, 0, 0})
+	binary.Write(&buf, be, uint32(created.Unix()+2082844800))
+	buf.Write([]byte{0, 0, 0, 0})
+	return buf.Bytes()
+}
+
+func TestClassifier_Classify_DateLayout(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	destDir := filepath.Join(tempDir, "dest")
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatalf("创建源目录失败: %v", err)
+	}
+
+	mtime := time.Date(2020, 12, 31, 12, 0, 0, 0, time.Local)
+	files := map[string][]byte{
+		"photo.jpg": exifJPEG("2023:07:14 09:30:05", "Canon EOS R5"),
+		"clip.mp4":  creationMP4(time.Date(2022, 5, 6, 12, 0, 0, 0, time.UTC)),
+		"plain.png": pngData,
+	}
+	for name, data := range files {
+		path := filepath.Join(sourceDir, name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("创建测试文件失败: %v", err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatalf("设置修改时间失败: %v", err)
+		}
+	}
+
+	layout, err := ParseLayout("{category}/{year}/{month}")
+	if err != nil {
+		t.Fatalf("ParseLayout() error = %v", err)
+	}
+	cls := NewClassifier()
+	cls.SetLayout(layout)
+
+	stats, err := cls.Classify([]string{sourceDir}, destDir)
+	if err != nil {
+		t.Fatalf("Classify() error = %v", err)
+	}
+	if stats.Processed != 3 {
+		t.Fatalf("Expected 3 processed files, got %d", stats.Processed)
+	}
+
+	for _, path := range []string{
+		filepath.Join("image", "2023", "07", "photo.jpg"),
+		filepath.Join("video", "2022", "05", "clip.mp4"),
+		filepath.Join("image", "2020", "12", "plain.png"),
+	} {
+		if _, err := os.Stat(filepath.Join(destDir, path)); err != nil {
+			t.Errorf("Expected %s: %v", path, err)
+		}
+	}
+
+	for _, source := range []string{"exif", "container", "mtime"} {
+		if stats.DateSources[source] != 1 {
+			t.Errorf("Expected 1 file with date source %s, got %v", source, stats.DateSources)
+		}
+	}
+	if !strings.Contains(stats.String(), "日期来源: exif 1 container 1 mtime 1") {
+		t.Errorf("Stats should report date sources:\n%s", stats.String())
+	}
+}
+
+func TestClassifier_Classify_CategoryTemplateCase(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	destDir := filepath.Join(tempDir, "dest")
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatalf("创建源目录失败: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, "a.png"), []byte("\x89PNG\r\n\x1a\n"), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	rules, err := NewRules([]Rule{{Name: "Raw-Photos", Extensions: []string{"png"}}})
+	if err != nil {
+		t.Fatalf("NewRules() error = %v", err)
+	}
+	tmpl, err := ParsePathTemplate("{category}/custom/{orig}.{ext}")
+	if err != nil {
+		t.Fatalf("ParsePathTemplate() error = %v", err)
+	}
+
+	// 模板中的分类名称与规则名称大小写不同时仍然生效，目标目录保留规则名称的大小写
+	cls := NewClassifier()
+	cls.SetRules(rules)
+	cls.SetCategoryTemplates(map[string]*PathTemplate{"raw-photos": tmpl})
+
+	if _, err := cls.Classify([]string{sourceDir}, destDir); err != nil {
+		t.Fatalf("Classify() error = %v", err)
+	}
+
+	target := filepath.Join(destDir, "Raw-Photos", "custom", "a.png")
+	if _, err := os.Stat(target); err != nil {
+		t.Errorf("Expected category template to be applied, missing %s: %v", target, err)
+	}
+}
@@ -0,0 +1,315 @@
+package classifier
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/h2non/filetype/types"
+
+	"github.com/moyu-x/classified-file/pkg/hasher"
+	"github.com/moyu-x/classified-file/pkg/logger"
+	"github.com/moyu-x/classified-file/pkg/metadata"
+)
+
+// nameFields 是文件名模板中可以使用的字段
+var nameFields = map[string]bool{
+	"orig":     true,
+	"ext":      true,
+	"category": true,
+	"date":     true,
+	"time":     true,
+	"year":     true,
+	"month":    true,
+	"day":      true,
+	"hash":     true,
+	"hash8":    true,
+	"camera":   true,
+	"seq":      true,
+
+	"artist":      true,
+	"albumartist": true,
+	"album":       true,
+	"title":       true,
+	"track":       true,
+	"disc":        true,
+}
+
+// unknownCamera 是没有相机信息时 {camera} 的值
+const unknownCamera = "unknown"
+
+// NameTemplate 是目标文件名模板，如 {date:2006-01-02}_{time}_{hash8}.{ext}
+type NameTemplate struct {
+	tmpl *template
+}
+
+// ParseNameTemplate 解析文件名模板，可用字段：
+// {orig} 原文件名（不含扩展名），{ext} 扩展名，{category} 分类，
+// {date:格式}、{time:格式}、{year}、{month}、{day} 文件日期，
+// {hash} 内容哈希，{hash:N} 或 {hash8} 哈希前 N 位，{camera} 相机型号，
+// {artist}、{albumartist}、{album}、{title}、{track:N}、{disc:N} 音频标签，
+// {seq} 或 {seq:N} 从 1 开始、不与已有文件冲突的序号（N 为补零位数）
+func ParseNameTemplate(s string) (*NameTemplate, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, fmt.Errorf("文件名模板不能为空")
+	}
+
+	tmpl, err := parseTemplate(s, nameFields)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range tmpl.parts {
+		if strings.ContainsAny(p.text, `/\`) {
+			return nil, fmt.Errorf("文件名模板不能包含目录分隔符: %s", s)
+		}
+		switch p.field {
+		case "hash", "seq", "track", "disc":
+			if err := validateWidth(p); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &NameTemplate{tmpl: tmpl}, nil
+}
+
+// SetNameTemplate 设置目标文件名模板，nil 表示保留原文件名
+func (c *Classifier) SetNameTemplate(t *NameTemplate) {
+	c.naming = t
+}
+
+func (t *NameTemplate) String() string {
+	return t.tmpl.String()
+}
+
+// expand 展开文件名，seq 为 {seq} 的值
+func (t *NameTemplate) expand(src *sourceFile, category string, seq int) (string, error) {
+	name, err := t.tmpl.expand(func(field, arg string) (string, error) {
+		switch field {
+		case "orig":
+			return src.origName(), nil
+		case "ext":
+			return src.extension(), nil
+		case "category":
+			return sanitizeName(category), nil
+		case "seq":
+			if arg == "" {
+				return strconv.Itoa(seq), nil
+			}
+			width, _ := strconv.Atoi(arg)
+			return fmt.Sprintf("%0*d", width, seq), nil
+		case "hash", "hash8":
+			hash, err := src.contentHash()
+			if err != nil {
+				return "", err
+			}
+			n := len(hash)
+			if field == "hash8" {
+				n = 8
+			} else if arg != "" {
+				n, _ = strconv.Atoi(arg)
+			}
+			return hash[:min(n, len(hash))], nil
+		case "camera":
+			meta, err := src.metadata()
+			if err != nil {
+				return "", err
+			}
+			if camera := sanitizeName(meta.Camera()); camera != "" {
+				return camera, nil
+			}
+			return unknownCamera, nil
+		}
+		if isTagField(field) {
+			return src.tagValue(field, arg)
+		}
+
+		date, err := src.date()
+		if err != nil {
+			return "", err
+		}
+		switch field {
+		case "date":
+			if arg == "" {
+				arg = "2006-01-02"
+			}
+			return sanitizeName(date.Format(arg)), nil
+		case "time":
+			if arg == "" {
+				arg = "150405"
+			}
+			return sanitizeName(date.Format(arg)), nil
+		case "year":
+			return date.Format("2006"), nil
+		case "month":
+			return date.Format("01"), nil
+		case "day":
+			return date.Format("02"), nil
+		}
+		return "", fmt.Errorf("未知字段")
+	})
+	if err != nil {
+		return "", err
+	}
+
+	name = strings.TrimRight(strings.TrimSpace(name), ".")
+	if name == "" {
+		return "", fmt.Errorf("文件名模板 %s 展开后为空: %s", t, src.path)
+	}
+	return name, nil
+}
+
+// targetPath 返回文件在 dir 中的目标路径：没有模板时使用原文件名，
+// 模板包含 {seq} 时取第一个不冲突的序号，否则冲突时添加 _N 后缀
+func (c *Classifier) targetPath(naming *NameTemplate, src *sourceFile, category, dir string) (string, error) {
+	if naming == nil {
+		return c.handleDuplicate(filepath.Join(dir, src.baseName()))
+	}
+	if !naming.tmpl.has("seq") {
+		name, err := naming.expand(src, category, 0)
+		if err != nil {
+			return "", err
+		}
+		return c.handleDuplicate(filepath.Join(dir, name))
+	}
+
+	for seq := 1; ; seq++ {
+		name, err := naming.expand(src, category, seq)
+		if err != nil {
+			return "", err
+		}
+		targetPath := filepath.Join(dir, name)
+		if _, err := os.Lstat(targetPath); os.IsNotExist(err) {
+			return targetPath, nil
+		}
+	}
+}
+
+// sanitizeName 将不能用于文件名的字符替换为 _
+func sanitizeName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, s)
+	return strings.TrimSpace(s)
+}
+
+// sourceFile 是正在分类的源文件，按需读取并缓存元数据、日期和哈希
+type sourceFile struct {
+	open     openFunc
+	path     string
+	fileType types.Type
+	mismatch *ExtensionMismatch // 启用修正扩展名且扩展名与内容不符时不为 nil
+
+	meta       *metadata.Metadata
+	modTime    time.Time
+	dateSource string // 使用过文件日期时记录日期来源
+	hash       string
+}
+
+// metadata 读取文件元数据和修改时间，读取元数据失败时返回空的 Metadata
+func (f *sourceFile) metadata() (*metadata.Metadata, error) {
+	if f.meta != nil {
+		return f.meta, nil
+	}
+
+	file, err := f.open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	f.modTime = info.ModTime()
+	f.meta = &metadata.Metadata{}
+
+	if r, ok := file.(io.ReaderAt); ok && f.fileType != types.Unknown {
+		meta, err := metadata.Read(r, info.Size())
+		if err != nil {
+			logger.Get().Debug().Err(err).Msgf("读取元数据失败: %s", f.path)
+		} else {
+			f.meta = meta
+		}
+	}
+
+	return f.meta, nil
+}
+
+// date 返回文件日期：图片优先使用 EXIF 拍摄时间，视频使用容器创建时间，否则使用修改时间
+func (f *sourceFile) date() (time.Time, error) {
+	meta, err := f.metadata()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	switch {
+	case meta.CreatedSource == metadata.SourceContainer:
+		// 容器中记录的是 UTC 时间，按本地日期归档
+		f.dateSource = string(meta.CreatedSource)
+		return meta.Created.Local(), nil
+	case !meta.Created.IsZero():
+		f.dateSource = string(meta.CreatedSource)
+		return meta.Created, nil
+	}
+
+	f.dateSource = DateSourceMtime
+	return f.modTime, nil
+}
+
+// contentHash 返回文件内容的十六进制哈希
+func (f *sourceFile) contentHash() (string, error) {
+	if f.hash != "" {
+		return f.hash, nil
+	}
+
+	file, err := f.open()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash, err := hasher.HashReader(file)
+	if err != nil {
+		return "", err
+	}
+	f.hash = fmt.Sprintf("%016x", hash)
+	return f.hash, nil
+}
+
+// baseName 返回不使用文件名模板时的目标文件名，需要修正扩展名时使用识别出的扩展名
+func (f *sourceFile) baseName() string {
+	if f.mismatch != nil {
+		return f.mismatch.FixedName()
+	}
+	return filepath.Base(f.path)
+}
+
+// origName 返回不含扩展名的文件名
+func (f *sourceFile) origName() string {
+	if f.mismatch != nil {
+		return strings.TrimSuffix(f.mismatch.FixedName(), "."+f.mismatch.Detected)
+	}
+	base := filepath.Base(f.path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// extension 返回小写的原扩展名，没有扩展名或需要修正扩展名时使用识别出的扩展名
+func (f *sourceFile) extension() string {
+	if f.mismatch != nil {
+		return f.mismatch.Detected
+	}
+	if ext := strings.TrimPrefix(filepath.Ext(f.path), "."); ext != "" {
+		return strings.ToLower(sanitizeName(ext))
+	}
+	return f.fileType.Extension
+}
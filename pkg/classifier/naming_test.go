@@ -0,0 +1,136 @@
+package classifier
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/moyu-x/classified-file/pkg/hasher"
+)
+
+func TestParseNameTemplate_Invalid(t *testing.T) {
+	for _, tmpl := range []string{"", "  ", "{orig}/{ext}", `{orig}\x`, "{unknown}", "{seq:0}", "{hash:x}", "{orig"} {
+		if _, err := ParseNameTemplate(tmpl); err == nil {
+			t.Errorf("ParseNameTemplate(%q) should fail", tmpl)
+		}
+	}
+}
+
+func TestSanitizeName(t *testing.T) {
+	if got := sanitizeName(` a/b:c*d?"e<f>g|h\i `); got != "a_b_c_d__e_f_g_h_i" {
+		t.Errorf("sanitizeName() = %q", got)
+	}
+}
+
+// hashHex 返回内容的十六进制哈希
+func hashHex(t *testing.T, data []byte) string {
+	t.Helper()
+	hash, err := hasher.HashReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("HashReader() error = %v", err)
+	}
+	return fmt.Sprintf("%016x", hash)
+}
+
+// classifyWithTemplate 按文件名模板分类 files，返回目标目录中的文件名
+func classifyWithTemplate(t *testing.T, tmpl string, files map[string][]byte) (*ClassifierStats, []string) {
+	t.Helper()
+
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	destDir := filepath.Join(tempDir, "dest")
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatalf("创建源目录失败: %v", err)
+	}
+
+	mtime := time.Date(2021, 2, 3, 4, 5, 6, 0, time.Local)
+	for name, data := range files {
+		path := filepath.Join(sourceDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("创建子目录失败: %v", err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("创建测试文件失败: %v", err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatalf("设置修改时间失败: %v", err)
+		}
+	}
+
+	naming, err := ParseNameTemplate(tmpl)
+	if err != nil {
+		t.Fatalf("ParseNameTemplate() error = %v", err)
+	}
+	layout, err := ParseLayout("{category}")
+	if err != nil {
+		t.Fatalf("ParseLayout() error = %v", err)
+	}
+	cls := NewClassifier()
+	cls.SetLayout(layout)
+	cls.SetNameTemplate(naming)
+
+	stats, err := cls.Classify([]string{sourceDir}, destDir)
+	if err != nil {
+		t.Fatalf("Classify() error = %v", err)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(destDir, "image"))
+	if err != nil {
+		t.Fatalf("读取目标目录失败: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return stats, names
+}
+
+func TestClassifier_Classify_NameTemplate(t *testing.T) {
+	photo := exifJPEG("2023:07:14 09:30:05", "Canon EOS R5")
+
+	stats, names := classifyWithTemplate(t, "{date:2006-01-02}_{time}_{hash8}_{camera}.{ext}", map[string][]byte{
+		"IMG_0001.JPG": photo,
+		"b.png":        pngData,
+	})
+
+	want := []string{
+		"2021-02-03_040506_" + hashHex(t, pngData)[:8] + "_unknown.png",
+		"2023-07-14_093005_" + hashHex(t, photo)[:8] + "_Canon EOS R5.jpg",
+	}
+	if len(names) != 2 || names[0] != want[0] || names[1] != want[1] {
+		t.Errorf("Expected %v, got %v", want, names)
+	}
+	if stats.DateSources["exif"] != 1 || stats.DateSources["mtime"] != 1 {
+		t.Errorf("Expected exif and mtime date sources, got %v", stats.DateSources)
+	}
+}
+
+func TestClassifier_Classify_NameTemplateSeq(t *testing.T) {
+	_, names := classifyWithTemplate(t, "{date}_{seq:3}.{ext}", map[string][]byte{
+		"a/x.png": pngData,
+		"b/x.png": pngData,
+		"c/y.png": pngData,
+	})
+
+	want := []string{"2021-02-03_001.png", "2021-02-03_002.png", "2021-02-03_003.png"}
+	if len(names) != 3 || names[0] != want[0] || names[1] != want[1] || names[2] != want[2] {
+		t.Errorf("Expected %v, got %v", want, names)
+	}
+}
+
+func TestClassifier_Classify_NameTemplateCollision(t *testing.T) {
+	_, names := classifyWithTemplate(t, "{category}-{orig}.{ext}", map[string][]byte{
+		"a/x.PNG": pngData,
+		"b/x.png": pngData,
+	})
+
+	want := []string{"image-x.png", "image-x_1.png"}
+	if len(names) != 2 || names[0] != want[0] || names[1] != want[1] {
+		t.Errorf("Expected %v, got %v", want, names)
+	}
+}
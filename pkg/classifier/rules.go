@@ -0,0 +1,153 @@
+package classifier
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/h2non/filetype/types"
+)
+
+// OtherCategory 是没有规则匹配时使用的分类
+const OtherCategory = "other"
+
+// Rule 分类规则，MIME、扩展名和文件名任一条件匹配即归入 Name 目录
+type Rule struct {
+	// Name 是输出目录名
+	Name string
+	// MIME 是按内容识别出的 MIME 类型，支持通配符，如 image/*、application/epub+zip
+	MIME []string
+	// Extensions 是按内容识别出的扩展名（不含点），如 cr2、ttf
+	Extensions []string
+	// Globs 是文件名模式，不区分大小写，如 *.mobi、IMG_*.dng；内容无法识别的文件只按文件名匹配
+	Globs []string
+	// Priority 越大越先匹配，相同时按定义顺序
+	Priority int
+}
+
+// DefaultRules 是内置的分类规则
+var DefaultRules = []Rule{
+	{Name: "image", MIME: []string{"image/*"}},
+	{Name: "video", MIME: []string{"video/*"}},
+	{Name: "audio", MIME: []string{"audio/*"}},
+	{Name: "document", Extensions: []string{"pdf", "doc", "docx", "xls", "xlsx", "ppt", "pptx", "txt", "rtf", "odt", "ods", "odp"}},
+	{Name: "archive", Extensions: []string{"zip", "tar", "gz", "bz2", "rar", "7z", "xz"}},
+	{Name: "code", Extensions: []string{"go", "py", "js", "ts", "java", "c", "h", "cpp", "hpp", "rs", "sh", "rb", "php", "sql", "css"}},
+	{Name: "text", MIME: []string{"text/*", "application/json", "application/xml", "application/yaml", "application/toml"}},
+}
+
+// defaultRules 是编译后的内置规则，内置规则不会校验失败
+var defaultRules, _ = NewRules(DefaultRules)
+
+// Rules 是按优先级排序的分类规则
+type Rules struct {
+	rules []Rule
+}
+
+// NewRules 校验并按优先级排序规则，匹配条件统一转为小写
+func NewRules(rules []Rule) (*Rules, error) {
+	sorted := make([]Rule, 0, len(rules))
+	for i, rule := range rules {
+		rule.Name = strings.TrimSpace(rule.Name)
+		if rule.Name == "" {
+			return nil, fmt.Errorf("第 %d 条分类规则缺少名称", i+1)
+		}
+		if err := ValidateCategory(rule.Name); err != nil {
+			return nil, err
+		}
+		if len(rule.MIME)+len(rule.Extensions)+len(rule.Globs) == 0 {
+			return nil, fmt.Errorf("分类规则 %s 没有匹配条件", rule.Name)
+		}
+
+		rule.MIME = lowerAll(rule.MIME)
+		rule.Extensions = lowerAll(rule.Extensions)
+		for j, ext := range rule.Extensions {
+			rule.Extensions[j] = strings.TrimPrefix(ext, ".")
+		}
+		rule.Globs = lowerAll(rule.Globs)
+		for _, pattern := range append(append([]string{}, rule.MIME...), rule.Globs...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("分类规则 %s 中的模式无效 %q: %w", rule.Name, pattern, err)
+			}
+		}
+
+		sorted = append(sorted, rule)
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority > sorted[j].Priority
+	})
+	return &Rules{rules: sorted}, nil
+}
+
+// ValidateCategory 检查分类名称是否为目标目录下的相对目录
+func ValidateCategory(name string) error {
+	if filepath.IsAbs(name) || strings.Contains(name, "..") {
+		return fmt.Errorf("分类名称必须是相对目录: %s", name)
+	}
+	return nil
+}
+
+// SetRules 设置分类规则，nil 表示使用内置规则
+func (c *Classifier) SetRules(rules *Rules) {
+	if rules == nil {
+		rules = defaultRules
+	}
+	c.rules = rules
+}
+
+// WithDefaults 将自定义规则放在内置规则之前，优先级相同时自定义规则先匹配
+func WithDefaults(custom []Rule) []Rule {
+	return append(append([]Rule{}, custom...), DefaultRules...)
+}
+
+// Match 返回第一条匹配的规则名称，fileType 为 types.Unknown 时只按文件名匹配
+func (r *Rules) Match(fileType types.Type, fileName string) (string, bool) {
+	name := strings.ToLower(filepath.Base(fileName))
+	mime := strings.ToLower(fileType.MIME.Value)
+	ext := strings.ToLower(fileType.Extension)
+	known := fileType != types.Unknown
+
+	for _, rule := range r.rules {
+		if known && (matchAny(rule.MIME, mime) || contains(rule.Extensions, ext)) {
+			return rule.Name, true
+		}
+		if matchAny(rule.Globs, name) {
+			return rule.Name, true
+		}
+	}
+	return "", false
+}
+
+func matchAny(patterns []string, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func lowerAll(values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
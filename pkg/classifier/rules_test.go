@@ -0,0 +1,115 @@
+package classifier
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/h2non/filetype"
+	"github.com/h2non/filetype/types"
+)
+
+func TestRules_Match(t *testing.T) {
+	rules, err := NewRules(WithDefaults([]Rule{
+		{Name: "raw-photos", Extensions: []string{".CR2", "nef"}, Priority: 10},
+		{Name: "ebooks", MIME: []string{"application/epub+zip"}, Globs: []string{"*.MOBI"}},
+		{Name: "fonts", MIME: []string{"font/*", "application/font-*"}},
+		{Name: "screenshots", Globs: []string{"screenshot*.png"}},
+	}))
+	if err != nil {
+		t.Fatalf("NewRules() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		fileType types.Type
+		fileName string
+		want     string
+		matched  bool
+	}{
+		{"mime wildcard", filetype.GetType("jpg"), "a.jpg", "image", true},
+		{"extension", filetype.GetType("pdf"), "a.pdf", "document", true},
+		{"priority over builtin", filetype.GetType("cr2"), "a.cr2", "raw-photos", true},
+		{"exact mime", filetype.GetType("epub"), "book.epub", "ebooks", true},
+		{"custom mime wildcard", filetype.GetType("ttf"), "a.ttf", "fonts", true},
+		{"glob before builtin at same priority", filetype.GetType("png"), "Screenshot 1.PNG", "screenshots", true},
+		{"glob for unknown type", types.Unknown, "dir/book.mobi", "ebooks", true},
+		{"unknown type ignores mime", types.Unknown, "a.bin", "", false},
+		{"no rule", filetype.GetType("exe"), "setup.exe", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, matched := rules.Match(tt.fileType, tt.fileName)
+			if got != tt.want || matched != tt.matched {
+				t.Errorf("Match() = %q, %v; want %q, %v", got, matched, tt.want, tt.matched)
+			}
+		})
+	}
+}
+
+func TestNewRules_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{"missing name", Rule{Globs: []string{"*.txt"}}},
+		{"absolute name", Rule{Name: "/tmp/x", Globs: []string{"*.txt"}}},
+		{"parent name", Rule{Name: "../x", Globs: []string{"*.txt"}}},
+		{"no conditions", Rule{Name: "x"}},
+		{"bad pattern", Rule{Name: "x", Globs: []string{"[a"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRules([]Rule{tt.rule}); err == nil {
+				t.Errorf("NewRules() should fail for %+v", tt.rule)
+			}
+		})
+	}
+}
+
+func TestClassifier_Classify_CustomRules(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	destDir := filepath.Join(tempDir, "dest")
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatalf("创建源目录失败: %v", err)
+	}
+
+	files := map[string][]byte{
+		"a.png":     pngData,
+		"book.mobi": []byte("BOOKMOBI plain data"),
+		"notes.bin": []byte("\x00no magic here"),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(sourceDir, name), data, 0644); err != nil {
+			t.Fatalf("创建测试文件失败: %v", err)
+		}
+	}
+
+	rules, err := NewRules(WithDefaults([]Rule{{Name: "books/kindle", Globs: []string{"*.mobi"}}}))
+	if err != nil {
+		t.Fatalf("NewRules() error = %v", err)
+	}
+	cls := NewClassifier()
+	cls.SetRules(rules)
+	cls.SetUnknownCategory("")
+
+	stats, err := cls.Classify([]string{sourceDir}, destDir)
+	if err != nil {
+		t.Fatalf("Classify() error = %v", err)
+	}
+	if stats.Processed != 2 || stats.UnknownType != 1 {
+		t.Errorf("Expected 2 processed and 1 unknown, got %+v", stats)
+	}
+
+	for _, path := range []string{
+		filepath.Join(destDir, "image", "part_0000", "a.png"),
+		filepath.Join(destDir, "books", "kindle", "part_0000", "book.mobi"),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected %s: %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package classifier
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/moyu-x/classified-file/pkg/metadata"
+)
+
+// 没有对应标签时使用的名称
+const (
+	unknownArtist = "Unknown Artist"
+	unknownAlbum  = "Unknown Album"
+)
+
+// maxTagLength 限制标签用作目录名或文件名时的字符数
+const maxTagLength = 100
+
+// tagFields 是目录布局和文件名模板中可以使用的音频标签字段
+var tagFields = []string{"artist", "albumartist", "album", "title", "track", "disc"}
+
+func isTagField(field string) bool {
+	for _, f := range tagFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// usesTags 判断模板是否使用音频标签
+func (t *template) usesTags() bool {
+	for _, p := range t.parts {
+		if isTagField(p.field) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateWidth 检查 {hash:N}、{seq:N}、{track:N} 等字段的位数参数
+func validateWidth(p templatePart) error {
+	if p.arg == "" {
+		return nil
+	}
+	if n, err := strconv.Atoi(p.arg); err != nil || n <= 0 {
+		return fmt.Errorf("模板中 {%s:%s} 的位数无效", p.field, p.arg)
+	}
+	return nil
+}
+
+// hasTags 判断文件是否带有音频标签
+func (f *sourceFile) hasTags() (bool, error) {
+	meta, err := f.metadata()
+	if err != nil {
+		return false, err
+	}
+	return meta.Audio != nil, nil
+}
+
+// tagValue 返回音频标签字段的值：艺术家和专辑艺术家互为后备，缺少标题时使用原文件名，
+// 缺少序号时为 0，{track:N} 和 {disc:N} 按 N 位补零
+func (f *sourceFile) tagValue(field, arg string) (string, error) {
+	meta, err := f.metadata()
+	if err != nil {
+		return "", err
+	}
+	tags := meta.Audio
+	if tags == nil {
+		tags = &metadata.AudioTags{}
+	}
+
+	switch field {
+	case "artist":
+		return tagName(firstNonEmpty(tags.Artist, tags.AlbumArtist), unknownArtist), nil
+	case "albumartist":
+		return tagName(firstNonEmpty(tags.AlbumArtist, tags.Artist), unknownArtist), nil
+	case "album":
+		return tagName(tags.Album, unknownAlbum), nil
+	case "title":
+		return tagName(tags.Title, f.origName()), nil
+	case "track", "disc":
+		n := tags.Track
+		if field == "disc" {
+			n = tags.Disc
+		}
+		width, _ := strconv.Atoi(arg)
+		return fmt.Sprintf("%0*d", width, n), nil
+	}
+	return "", fmt.Errorf("未知字段")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// tagName 将标签转换为可用作目录名或文件名的文本，去掉首尾的点避免生成隐藏文件或 ..，
+// 结果为空时使用 fallback
+func tagName(s, fallback string) string {
+	s = strings.Trim(sanitizeName(s), ". ")
+	if runes := []rune(s); len(runes) > maxTagLength {
+		s = strings.TrimRight(string(runes[:maxTagLength]), ". ")
+	}
+	if s == "" {
+		return fallback
+	}
+	return s
+}
@@ -0,0 +1,120 @@
+package classifier
+
+import (
+	"encoding/binary"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// taggedFLAC 构造带 Vorbis 注释的 FLAC，没有注释时为无标签文件
+func taggedFLAC(comments ...string) []byte {
+	le := binary.LittleEndian
+	comment := le.AppendUint32(nil, 0)
+	comment = le.AppendUint32(comment, uint32(len(comments)))
+	for _, c := range comments {
+		comment = le.AppendUint32(comment, uint32(len(c)))
+		comment = append(comment, c...)
+	}
+
+	data := []byte("fLaC\x00\x00\x00\x22")
+	data = append(data, make([]byte, 34)...)
+	data = append(data, 0x84, byte(len(comment)>>16), byte(len(comment)>>8), byte(len(comment)))
+	return append(data, comment...)
+}
+
+func TestParsePathTemplate(t *testing.T) {
+	tmpl, err := ParsePathTemplate("audio/{artist}/{album}/{track:02} - {title}.{ext}")
+	if err != nil {
+		t.Fatalf("ParsePathTemplate() error = %v", err)
+	}
+	if tmpl.String() != "audio/{artist}/{album}/{track:02} - {title}.{ext}" || !tmpl.usesTags() {
+		t.Errorf("ParsePathTemplate() = %s, usesTags = %v", tmpl, tmpl.usesTags())
+	}
+
+	if tmpl, err := ParsePathTemplate("{date}_{orig}.{ext}"); err != nil || tmpl.usesTags() {
+		t.Errorf("ParsePathTemplate() = %v, %v; want file name in {category}", tmpl, err)
+	}
+
+	for _, s := range []string{"", "audio/", "{part}/audio/{title}", "{artist}/{track:x}.{ext}", "../{title}", "audio/{camera}/{title}"} {
+		if _, err := ParsePathTemplate(s); err == nil {
+			t.Errorf("ParsePathTemplate(%q) should fail", s)
+		}
+	}
+}
+
+func TestTagName(t *testing.T) {
+	tests := []struct {
+		value, want string
+	}{
+		{"AC/DC", "AC_DC"},
+		{"..", "fallback"},
+		{" .hidden. ", "hidden"},
+		{strings.Repeat("é", 120), strings.Repeat("é", maxTagLength)},
+		{"", "fallback"},
+	}
+	for _, tt := range tests {
+		if got := tagName(tt.value, "fallback"); got != tt.want {
+			t.Errorf("tagName(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestClassifier_Classify_AudioTemplate(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	destDir := filepath.Join(tempDir, "dest")
+	if err := os.MkdirAll(sourceDir, 0755); err != nil {
+		t.Fatalf("创建源目录失败: %v", err)
+	}
+
+	files := map[string][]byte{
+		"01.flac":   taggedFLAC("ARTIST=AC/DC", "ALBUM=Back in Black", "TITLE=Hells Bells", "TRACKNUMBER=1/10"),
+		"02.flac":   taggedFLAC("ALBUMARTIST=Various", "TITLE=Intro", "TRACKNUMBER=2"),
+		"raw.flac":  taggedFLAC(),
+		"photo.png": pngData,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(sourceDir, name), data, 0644); err != nil {
+			t.Fatalf("创建测试文件失败: %v", err)
+		}
+	}
+
+	tmpl, err := ParsePathTemplate("audio/{artist}/{album}/{track:02} - {title}.{ext}")
+	if err != nil {
+		t.Fatalf("ParsePathTemplate() error = %v", err)
+	}
+	cls := NewClassifier()
+	cls.SetCategoryTemplates(map[string]*PathTemplate{"audio": tmpl})
+
+	stats, err := cls.Classify([]string{sourceDir}, destDir)
+	if err != nil {
+		t.Fatalf("Classify() error = %v", err)
+	}
+	if stats.Processed != 4 || stats.Untagged != 1 {
+		t.Errorf("Expected 4 processed and 1 untagged, got %d and %d", stats.Processed, stats.Untagged)
+	}
+
+	var got []string
+	filepath.WalkDir(destDir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			rel, _ := filepath.Rel(destDir, path)
+			got = append(got, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	sort.Strings(got)
+
+	want := []string{
+		"audio/AC_DC/Back in Black/01 - Hells Bells.flac",
+		"audio/Various/Unknown Album/02 - Intro.flac",
+		"audio/part_0000/raw.flac",
+		"image/part_0000/photo.png",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected files:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
@@ -0,0 +1,82 @@
+package classifier
+
+import (
+	"fmt"
+	"strings"
+)
+
+// template 是由文本和 {name} 或 {name:arg} 字段组成的模板
+type template struct {
+	source string
+	parts  []templatePart
+}
+
+// templatePart 是模板中的一段文本或一个字段
+type templatePart struct {
+	text  string
+	field string
+	arg   string
+}
+
+// parseTemplate 解析模板，fields 是允许使用的字段名
+func parseTemplate(s string, fields map[string]bool) (*template, error) {
+	t := &template{source: s}
+
+	for rest := s; rest != ""; {
+		start := strings.IndexAny(rest, "{}")
+		if start < 0 {
+			t.parts = append(t.parts, templatePart{text: rest})
+			break
+		}
+		if rest[start] == '}' {
+			return nil, fmt.Errorf("模板 %q 中有多余的 }", s)
+		}
+		if start > 0 {
+			t.parts = append(t.parts, templatePart{text: rest[:start]})
+		}
+
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("模板 %q 中的 { 没有闭合", s)
+		}
+		name, arg, _ := strings.Cut(rest[start+1:start+end], ":")
+		if !fields[name] {
+			return nil, fmt.Errorf("模板 %q 中有未知字段 {%s}", s, name)
+		}
+		t.parts = append(t.parts, templatePart{field: name, arg: arg})
+		rest = rest[start+end+1:]
+	}
+
+	return t, nil
+}
+
+// has 判断模板是否使用了字段
+func (t *template) has(field string) bool {
+	for _, p := range t.parts {
+		if p.field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// expand 用 value 返回的字段值展开模板
+func (t *template) expand(value func(field, arg string) (string, error)) (string, error) {
+	var buf strings.Builder
+	for _, p := range t.parts {
+		if p.field == "" {
+			buf.WriteString(p.text)
+			continue
+		}
+		v, err := value(p.field, p.arg)
+		if err != nil {
+			return "", fmt.Errorf("展开字段 {%s}: %w", p.field, err)
+		}
+		buf.WriteString(v)
+	}
+	return buf.String(), nil
+}
+
+func (t *template) String() string {
+	return t.source
+}
@@ -0,0 +1,143 @@
+package classifier
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/moyu-x/classified-file/internal"
+	"github.com/moyu-x/classified-file/pkg/hasher"
+	"github.com/moyu-x/classified-file/pkg/logger"
+)
+
+// SetMode 设置文件放入目标目录的方式：copy（默认）、move、hardlink 或 symlink
+func (c *Classifier) SetMode(mode internal.ClassifyMode) {
+	c.mode = mode
+}
+
+// transfer 按模式将源文件放到 targetPath，非本地文件（fs.FS 中的文件）总是复制
+func (c *Classifier) transfer(open openFunc, srcPath string, local bool, targetPath string, stats *ClassifierStats) error {
+	mode := c.mode
+	if !local && mode != internal.ClassifyCopy {
+		logger.Get().Debug().Msgf("非本地文件只能复制: %s", srcPath)
+		mode = internal.ClassifyCopy
+	}
+
+	switch mode {
+	case internal.ClassifyMove:
+		return c.moveFile(srcPath, targetPath, stats)
+	case internal.ClassifyHardlink:
+		if err := os.Link(srcPath, targetPath); err != nil {
+			return fmt.Errorf("创建硬链接: %w", err)
+		}
+		stats.Linked++
+	case internal.ClassifySymlink:
+		absPath, err := filepath.Abs(srcPath)
+		if err != nil {
+			return fmt.Errorf("解析源文件路径: %w", err)
+		}
+		if err := os.Symlink(absPath, targetPath); err != nil {
+			return fmt.Errorf("创建符号链接: %w", err)
+		}
+		stats.Linked++
+	default:
+		written, err := c.copyFrom(open, targetPath)
+		if err != nil {
+			return fmt.Errorf("复制文件: %w", err)
+		}
+		stats.BytesCopied += written
+	}
+
+	return nil
+}
+
+// moveFile 移动文件，同一文件系统内直接重命名，
+// 跨文件系统时复制到临时文件、校验哈希一致后再放到目标位置并删除源文件
+func (c *Classifier) moveFile(srcPath, targetPath string, stats *ClassifierStats) error {
+	info, err := os.Stat(srcPath)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(srcPath, targetPath)
+	if err == nil {
+		stats.Moved++
+		stats.BytesMoved += info.Size()
+		return nil
+	}
+	if !errors.Is(err, syscall.EXDEV) {
+		return fmt.Errorf("移动文件: %w", err)
+	}
+
+	logger.Get().Debug().Msgf("跨文件系统移动，复制后删除: %s -> %s", srcPath, targetPath)
+
+	written, err := c.copyVerified(srcPath, targetPath, info.ModTime())
+	if err != nil {
+		return fmt.Errorf("跨文件系统移动: %w", err)
+	}
+
+	if err := os.Remove(srcPath); err != nil {
+		// 目标文件已完整写入，保留两份比丢失数据更安全
+		logger.Get().Warn().Err(err).Msgf("删除源文件失败，目标文件已保留: %s", srcPath)
+		stats.BytesCopied += written
+		return nil
+	}
+
+	stats.Moved++
+	stats.BytesCopied += written
+	return nil
+}
+
+// copyVerified 将源文件复制到目标目录中新建的临时文件，校验哈希一致后再重命名为 targetPath，
+// 失败时删除临时文件。临时文件名由 os.CreateTemp 生成，不会覆盖目标目录中已有的文件
+func (c *Classifier) copyVerified(srcPath, targetPath string, modTime time.Time) (int64, error) {
+	sourceFile, err := os.Open(srcPath)
+	if err != nil {
+		return 0, err
+	}
+	defer sourceFile.Close()
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(targetPath), "."+filepath.Base(targetPath)+".*.partial")
+	if err != nil {
+		return 0, err
+	}
+	tmpPath := tmpFile.Name()
+
+	written, err := copyInto(tmpFile, sourceFile)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = verifyCopy(srcPath, tmpPath)
+	}
+	if err == nil {
+		err = os.Chtimes(tmpPath, modTime, modTime)
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, targetPath)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return written, err
+	}
+	return written, nil
+}
+
+// verifyCopy 比较源文件和副本的哈希，确保删除源文件前副本完整
+func verifyCopy(srcPath, dstPath string) error {
+	srcHash, err := hasher.CalculateHash(srcPath)
+	if err != nil {
+		return err
+	}
+	dstHash, err := hasher.CalculateHash(dstPath)
+	if err != nil {
+		return err
+	}
+	if srcHash != dstHash {
+		return fmt.Errorf("校验失败，副本与源文件内容不一致: %s", dstPath)
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package classifier
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/moyu-x/classified-file/internal"
+)
+
+var pngData = []byte("\x89PNG\r\n\x1a\n")
+
+func TestClassifier_Classify_Modes(t *testing.T) {
+	tests := []struct {
+		mode       internal.ClassifyMode
+		keepSource bool
+		check      func(t *testing.T, target string, info os.FileInfo)
+	}{
+		{internal.ClassifyCopy, true, nil},
+		{internal.ClassifyMove, false, nil},
+		{internal.ClassifyHardlink, true, func(t *testing.T, target string, info os.FileInfo) {
+			targetInfo, err := os.Stat(target)
+			if err != nil {
+				t.Fatalf("Stat target: %v", err)
+			}
+			if !os.SameFile(info, targetInfo) {
+				t.Errorf("Target should be a hard link to the source")
+			}
+		}},
+		{internal.ClassifySymlink, true, func(t *testing.T, target string, info os.FileInfo) {
+			linkInfo, err := os.Lstat(target)
+			if err != nil {
+				t.Fatalf("Lstat target: %v", err)
+			}
+			if linkInfo.Mode()&os.ModeSymlink == 0 {
+				t.Errorf("Target should be a symlink")
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			tempDir := t.TempDir()
+			sourceDir := filepath.Join(tempDir, "source")
+			destDir := filepath.Join(tempDir, "dest")
+			if err := os.MkdirAll(sourceDir, 0755); err != nil {
+				t.Fatalf("创建源目录失败: %v", err)
+			}
+
+			source := filepath.Join(sourceDir, "a.png")
+			if err := os.WriteFile(source, pngData, 0644); err != nil {
+				t.Fatalf("创建测试文件失败: %v", err)
+			}
+			info, err := os.Stat(source)
+			if err != nil {
+				t.Fatalf("Stat source: %v", err)
+			}
+
+			cls := NewClassifier()
+			cls.SetMode(tt.mode)
+
+			stats, err := cls.Classify([]string{sourceDir}, destDir)
+			if err != nil {
+				t.Fatalf("Classify() error = %v", err)
+			}
+			if stats.Processed != 1 {
+				t.Fatalf("Expected 1 processed file, got %d", stats.Processed)
+			}
+
+			target := filepath.Join(destDir, "image", "part_0000", "a.png")
+			data, err := os.ReadFile(target)
+			if err != nil || string(data) != string(pngData) {
+				t.Fatalf("Target content mismatch: %v", err)
+			}
+
+			_, err = os.Stat(source)
+			if tt.keepSource && err != nil {
+				t.Errorf("Source should be kept in %s mode: %v", tt.mode, err)
+			}
+			if !tt.keepSource && !os.IsNotExist(err) {
+				t.Errorf("Source should be removed in %s mode", tt.mode)
+			}
+
+			switch tt.mode {
+			case internal.ClassifyCopy:
+				if stats.BytesCopied != int64(len(pngData)) || stats.BytesMoved != 0 {
+					t.Errorf("Expected %d bytes copied, got copied=%d moved=%d", len(pngData), stats.BytesCopied, stats.BytesMoved)
+				}
+			case internal.ClassifyMove:
+				if stats.Moved != 1 || stats.BytesMoved != int64(len(pngData)) || stats.BytesCopied != 0 {
+					t.Errorf("Expected rename move, got moved=%d bytesMoved=%d bytesCopied=%d", stats.Moved, stats.BytesMoved, stats.BytesCopied)
+				}
+			default:
+				if stats.Linked != 1 || stats.BytesCopied != 0 {
+					t.Errorf("Expected 1 linked file without copying, got linked=%d copied=%d", stats.Linked, stats.BytesCopied)
+				}
+			}
+
+			if tt.check != nil {
+				tt.check(t, target, info)
+			}
+		})
+	}
+}
+
+func TestClassifier_ClassifyFS_AlwaysCopies(t *testing.T) {
+	destDir := filepath.Join(t.TempDir(), "dest")
+	fsys := fstest.MapFS{"a.png": {Data: pngData}}
+
+	cls := NewClassifier()
+	cls.SetMode(internal.ClassifyMove)
+
+	stats, err := cls.ClassifyFS(fsys, destDir)
+	if err != nil {
+		t.Fatalf("ClassifyFS() error = %v", err)
+	}
+	if stats.Processed != 1 || stats.Moved != 0 || stats.BytesCopied != int64(len(pngData)) {
+		t.Errorf("Expected fs.FS files to be copied, got %+v", stats)
+	}
+}
+
+func TestVerifyCopy(t *testing.T) {
+	tempDir := t.TempDir()
+	src := filepath.Join(tempDir, "src")
+	same := filepath.Join(tempDir, "same")
+	different := filepath.Join(tempDir, "different")
+	for path, content := range map[string]string{src: "data", same: "data", different: "dat"} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("创建测试文件失败: %v", err)
+		}
+	}
+
+	if err := verifyCopy(src, same); err != nil {
+		t.Errorf("verifyCopy() identical files error = %v", err)
+	}
+	if err := verifyCopy(src, different); err == nil {
+		t.Errorf("verifyCopy() should fail for different content")
+	}
+}
+
+func TestClassifier_CopyVerified_KeepsExistingPartial(t *testing.T) {
+	tempDir := t.TempDir()
+	src := filepath.Join(tempDir, "src.png")
+	destDir := filepath.Join(tempDir, "dest")
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		t.Fatalf("创建目标目录失败: %v", err)
+	}
+	if err := os.WriteFile(src, pngData, 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	// 目标目录中已有同名的 .partial 文件，不能被临时文件覆盖
+	target := filepath.Join(destDir, "a.png")
+	partial := target + ".partial"
+	if err := os.WriteFile(partial, []byte("keep"), 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	written, err := NewClassifier().copyVerified(src, target, modTime)
+	if err != nil {
+		t.Fatalf("copyVerified() error = %v", err)
+	}
+	if written != int64(len(pngData)) {
+		t.Errorf("Expected %d bytes written, got %d", len(pngData), written)
+	}
+
+	if data, err := os.ReadFile(partial); err != nil || string(data) != "keep" {
+		t.Errorf("Existing .partial file should be untouched, got %q, %v", data, err)
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		t.Fatalf("Stat target: %v", err)
+	}
+	if !info.ModTime().Equal(modTime) {
+		t.Errorf("Expected target mtime %v, got %v", modTime, info.ModTime())
+	}
+
+	entries, err := os.ReadDir(destDir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Temporary file should be renamed to the target, got %d entries", len(entries))
+	}
+}
+
+func TestClassifier_Classify_DestInsideSource(t *testing.T) {
+	sourceDir := t.TempDir()
+	destDir := filepath.Join(sourceDir, "sorted")
+	for _, name := range []string{"a.png", "b.png"} {
+		if err := os.WriteFile(filepath.Join(sourceDir, name), pngData, 0644); err != nil {
+			t.Fatalf("创建测试文件失败: %v", err)
+		}
+	}
+
+	cls := NewClassifier()
+	cls.SetMode(internal.ClassifyMove)
+
+	// 目标目录在源目录中，已经移动到目标目录的文件不能再次被处理
+	stats, err := cls.Classify([]string{sourceDir}, destDir)
+	if err != nil {
+		t.Fatalf("Classify() error = %v", err)
+	}
+	if stats.TotalProcessed != 2 || stats.Moved != 2 {
+		t.Errorf("Expected 2 files processed and moved once, got %d processed, %d moved", stats.TotalProcessed, stats.Moved)
+	}
+	for _, name := range []string{"a.png", "b.png"} {
+		target := filepath.Join(destDir, "image", "part_0000", name)
+		if _, err := os.Stat(target); err != nil {
+			t.Errorf("Expected %s: %v", target, err)
+		}
+	}
+
+	if _, err := NewClassifier().Classify([]string{sourceDir}, sourceDir); err == nil {
+		t.Errorf("Classify() should reject a destination equal to the source")
+	}
+}
@@ -8,10 +8,38 @@ import (
 
 type Config struct {
 	Database struct {
-		Path string
+		Path    string
+		Backend string
 	}
 	Scanner struct {
-		FollowSymlinks bool
+		FollowSymlinks bool `mapstructure:"follow_symlinks"`
+		IncludeHidden  bool `mapstructure:"include_hidden"`
+		GitIgnore      bool `mapstructure:"gitignore"`
+		Workers        int
+		OneFileSystem  bool `mapstructure:"one_file_system"`
+		Ordered        bool
+		StableFor      string `mapstructure:"stable_for"`
+		CheckOpen      bool   `mapstructure:"check_open"`
+	}
+	Filter struct {
+		Include      []string
+		Exclude      []string
+		IncludeRegex []string `mapstructure:"include_regex"`
+		ExcludeRegex []string `mapstructure:"exclude_regex"`
+		ExcludeDirs  []string `mapstructure:"exclude_dirs"`
+		MinSize      string   `mapstructure:"min_size"`
+		MaxSize      string   `mapstructure:"max_size"`
+		MinAge       string   `mapstructure:"min_age"`
+		MaxAge       string   `mapstructure:"max_age"`
+	}
+	Classify struct {
+		Categories    []CategoryRule
+		Layout        string
+		Rename        string
+		Templates     map[string]string
+		FixExtensions bool `mapstructure:"fix_extensions"`
+		Unknown       string
+		SkipUnknown   bool `mapstructure:"skip_unknown"`
 	}
 	Logging struct {
 		Level string
@@ -19,6 +47,15 @@ type Config struct {
 	}
 }
 
+// CategoryRule 是配置文件中的自定义分类规则
+type CategoryRule struct {
+	Name       string
+	MIME       []string
+	Extensions []string
+	Globs      []string
+	Priority   int
+}
+
 var cfg Config
 
 func Load() (*Config, error) {
@@ -30,7 +67,18 @@ func Load() (*Config, error) {
 	viper.AddConfigPath("/etc/classified-file")
 
 	viper.SetDefault("database.path", internal.DefaultDatabasePath)
+	viper.SetDefault("database.backend", "sqlite")
 	viper.SetDefault("scanner.follow_symlinks", false)
+	viper.SetDefault("scanner.include_hidden", true)
+	viper.SetDefault("scanner.gitignore", false)
+	viper.SetDefault("scanner.workers", 1)
+	viper.SetDefault("scanner.one_file_system", false)
+	viper.SetDefault("scanner.ordered", true)
+	viper.SetDefault("scanner.stable_for", "")
+	viper.SetDefault("scanner.check_open", false)
+	viper.SetDefault("classify.layout", "{category}/{part}")
+	viper.SetDefault("classify.unknown", "unknown")
+	viper.SetDefault("classify.skip_unknown", false)
 	viper.SetDefault("logging.level", "info")
 
 	if err := viper.ReadInConfig(); err != nil {
@@ -0,0 +1,220 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/moyu-x/classified-file/internal"
+	"github.com/moyu-x/classified-file/pkg/logger"
+)
+
+var boltBucket = []byte("file_hashes")
+
+// boltBatchSize 是缓冲的插入记录数，达到后在一个事务中写入
+const boltBatchSize = 1000
+
+// BoltStore 基于 bbolt 的嵌入式键值存储，适合大规模目录（写入吞吐高于 SQLite）。
+// 插入的记录先缓冲在内存中，每 boltBatchSize 条或 Flush、Close 时在一个事务中写入，
+// 避免每条记录单独提交和同步磁盘
+type BoltStore struct {
+	db *bolt.DB
+
+	mu      sync.Mutex
+	pending map[string]*internal.FileRecord
+	order   []string // 缓冲记录的插入顺序
+	nextID  uint64   // 最后分配的记录 ID，写入时保存为数据桶的序列号
+}
+
+func NewBoltStore(dbPath string) (*BoltStore, error) {
+	expandedPath, err := ExpandPath(dbPath)
+	if err != nil {
+		logger.Get().Error().Err(err).Msg("扩展数据库路径失败")
+		return nil, err
+	}
+
+	logger.Get().Info().Msgf("初始化键值数据库，路径: %s", expandedPath)
+
+	if err := os.MkdirAll(filepath.Dir(expandedPath), 0755); err != nil {
+		logger.Get().Error().Err(err).Msgf("创建数据库目录失败: %s", filepath.Dir(expandedPath))
+		return nil, err
+	}
+
+	db, err := bolt.Open(expandedPath, 0644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		logger.Get().Error().Err(err).Msg("打开键值数据库失败")
+		return nil, err
+	}
+
+	var nextID uint64
+	if err := db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(boltBucket)
+		if err != nil {
+			return err
+		}
+		nextID = bucket.Sequence()
+		return nil
+	}); err != nil {
+		db.Close()
+		logger.Get().Error().Err(err).Msg("创建数据桶失败")
+		return nil, err
+	}
+
+	logger.Get().Info().Msg("键值数据库初始化完成")
+	return &BoltStore{db: db, pending: make(map[string]*internal.FileRecord), nextID: nextID}, nil
+}
+
+func (b *BoltStore) Exists(hash string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.pending[hash]; ok {
+		return true, nil
+	}
+	exists := false
+	err := b.db.View(func(tx *bolt.Tx) error {
+		exists = tx.Bucket(boltBucket).Get([]byte(hash)) != nil
+		return nil
+	})
+	return exists, err
+}
+
+func (b *BoltStore) Insert(record *internal.FileRecord) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	exists := false
+	if _, ok := b.pending[record.Hash]; ok {
+		exists = true
+	} else if err := b.db.View(func(tx *bolt.Tx) error {
+		exists = tx.Bucket(boltBucket).Get([]byte(record.Hash)) != nil
+		return nil
+	}); err != nil {
+		return err
+	}
+	if exists {
+		err := fmt.Errorf("哈希已存在: %s", record.Hash)
+		logger.Get().Error().Err(err).Msgf("插入记录失败: %s", record.FilePath)
+		return err
+	}
+
+	b.nextID++
+	stored := *record
+	stored.ID = int64(b.nextID)
+	b.pending[record.Hash] = &stored
+	b.order = append(b.order, record.Hash)
+	logger.Get().Debug().Msgf("插入记录成功: %s (大小: %d bytes)", record.FilePath, record.FileSize)
+
+	if len(b.order) >= boltBatchSize {
+		return b.flush()
+	}
+	return nil
+}
+
+// Flush 将缓冲的记录写入数据库
+func (b *BoltStore) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.flush()
+}
+
+// flush 在一个事务中写入缓冲的记录，调用方需持有 mu
+func (b *BoltStore) flush() error {
+	if len(b.order) == 0 {
+		return nil
+	}
+
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		for _, hash := range b.order {
+			data, err := json.Marshal(b.pending[hash])
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(hash), data); err != nil {
+				return err
+			}
+		}
+		return bucket.SetSequence(b.nextID)
+	})
+	if err != nil {
+		logger.Get().Error().Err(err).Msgf("写入 %d 条记录失败", len(b.order))
+		return err
+	}
+
+	logger.Get().Debug().Msgf("已写入 %d 条记录", len(b.order))
+	b.pending = make(map[string]*internal.FileRecord)
+	b.order = b.order[:0]
+	return nil
+}
+
+func (b *BoltStore) Lookup(hash string) (*internal.FileRecord, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if pending, ok := b.pending[hash]; ok {
+		record := *pending
+		return &record, nil
+	}
+	var record *internal.FileRecord
+	err := b.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(boltBucket).Get([]byte(hash))
+		if data == nil {
+			return ErrRecordNotFound
+		}
+		record = &internal.FileRecord{}
+		return json.Unmarshal(data, record)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (b *BoltStore) Iterate(fn func(record *internal.FileRecord) error) error {
+	if err := b.Flush(); err != nil {
+		return err
+	}
+	return b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).ForEach(func(_, data []byte) error {
+			record := &internal.FileRecord{}
+			if err := json.Unmarshal(data, record); err != nil {
+				return err
+			}
+			return fn(record)
+		})
+	})
+}
+
+func (b *BoltStore) Delete(hash string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.pending[hash]; ok {
+		delete(b.pending, hash)
+		for i, h := range b.order {
+			if h == hash {
+				b.order = append(b.order[:i], b.order[i+1:]...)
+				break
+			}
+		}
+		return nil
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Delete([]byte(hash))
+	})
+}
+
+func (b *BoltStore) Close() error {
+	logger.Get().Info().Msg("关闭键值数据库")
+	err := b.Flush()
+	if closeErr := b.db.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -14,17 +14,84 @@ import (
 )
 
 type FileRecord struct {
-	ID        int64     `gorm:"primaryKey"`
-	Hash      string    `gorm:"uniqueIndex;not null"`
-	FilePath  string    `gorm:"not null"`
-	FileSize  int64     `gorm:"not null"`
-	CreatedAt time.Time `gorm:"not null"`
+	ID        int64           `gorm:"primaryKey"`
+	Hash      string          `gorm:"uniqueIndex;not null"`
+	FilePath  string          `gorm:"not null"`
+	FileSize  int64           `gorm:"not null"`
+	CreatedAt time.Time       `gorm:"not null"`
+	Metadata  MetadataColumns `gorm:"embedded;embeddedPrefix:meta_"`
+}
+
+// MetadataColumns 是与哈希一起保存的文件元数据列，HasMetadata 为 false 时其余列无意义
+type MetadataColumns struct {
+	HasMetadata bool
+	TakenAt     int64
+	TakenSource string
+	Make        string
+	Model       string
+	Orientation int
+	Width       int
+	Height      int
+	HasGPS      bool
+	Latitude    float64
+	Longitude   float64
+	Altitude    float64
+}
+
+func newMetadataColumns(meta *internal.FileMetadata) MetadataColumns {
+	if meta == nil {
+		return MetadataColumns{}
+	}
+	return MetadataColumns{
+		HasMetadata: true,
+		TakenAt:     meta.TakenAt,
+		TakenSource: meta.TakenSource,
+		Make:        meta.Make,
+		Model:       meta.Model,
+		Orientation: meta.Orientation,
+		Width:       meta.Width,
+		Height:      meta.Height,
+		HasGPS:      meta.HasGPS,
+		Latitude:    meta.Latitude,
+		Longitude:   meta.Longitude,
+		Altitude:    meta.Altitude,
+	}
+}
+
+func (c *MetadataColumns) toInternal() *internal.FileMetadata {
+	if !c.HasMetadata {
+		return nil
+	}
+	return &internal.FileMetadata{
+		TakenAt:     c.TakenAt,
+		TakenSource: c.TakenSource,
+		Make:        c.Make,
+		Model:       c.Model,
+		Orientation: c.Orientation,
+		Width:       c.Width,
+		Height:      c.Height,
+		HasGPS:      c.HasGPS,
+		Latitude:    c.Latitude,
+		Longitude:   c.Longitude,
+		Altitude:    c.Altitude,
+	}
 }
 
 func (FileRecord) TableName() string {
 	return "file_hashes"
 }
 
+func (r *FileRecord) toInternal() *internal.FileRecord {
+	return &internal.FileRecord{
+		ID:        r.ID,
+		Hash:      r.Hash,
+		FilePath:  r.FilePath,
+		FileSize:  r.FileSize,
+		CreatedAt: r.CreatedAt.Unix(),
+		Metadata:  r.Metadata.toInternal(),
+	}
+}
+
 type Database struct {
 	db    *gorm.DB
 	cache map[string]bool
@@ -32,7 +99,7 @@ type Database struct {
 }
 
 func NewDatabase(dbPath string) (*Database, error) {
-	expandedPath, err := expandPath(dbPath)
+	expandedPath, err := ExpandPath(dbPath)
 	if err != nil {
 		logger.Get().Error().Err(err).Msg("扩展数据库路径失败")
 		return nil, err
@@ -74,7 +141,8 @@ func NewDatabase(dbPath string) (*Database, error) {
 	}, nil
 }
 
-func expandPath(path string) (string, error) {
+// ExpandPath 展开路径开头的 ~ 为用户主目录
+func ExpandPath(path string) (string, error) {
 	if len(path) >= 2 && path[0] == '~' && (path[1] == '/' || path[1] == '\\') {
 		home, err := os.UserHomeDir()
 		if err != nil {
@@ -121,6 +189,7 @@ func (d *Database) Insert(record *internal.FileRecord) error {
 		FilePath:  record.FilePath,
 		FileSize:  record.FileSize,
 		CreatedAt: time.Unix(record.CreatedAt, 0),
+		Metadata:  newMetadataColumns(record.Metadata),
 	}
 
 	if err := d.db.Create(gormRecord).Error; err != nil {
@@ -136,6 +205,57 @@ func (d *Database) Insert(record *internal.FileRecord) error {
 	return nil
 }
 
+func (d *Database) Lookup(hash string) (*internal.FileRecord, error) {
+	var records []FileRecord
+	if err := d.db.Where("hash = ?", hash).Limit(1).Find(&records).Error; err != nil {
+		logger.Get().Error().Err(err).Msgf("查询记录失败: %s", hash)
+		return nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return records[0].toInternal(), nil
+}
+
+func (d *Database) Iterate(fn func(record *internal.FileRecord) error) error {
+	var batch []FileRecord
+	var fnErr error
+
+	result := d.db.FindInBatches(&batch, internal.DefaultBufferSize, func(tx *gorm.DB, _ int) error {
+		for i := range batch {
+			if fnErr = fn(batch[i].toInternal()); fnErr != nil {
+				return fnErr
+			}
+		}
+		return nil
+	})
+
+	if fnErr != nil {
+		return fnErr
+	}
+	if result.Error != nil {
+		logger.Get().Error().Err(result.Error).Msg("遍历记录失败")
+		return result.Error
+	}
+	return nil
+}
+
+func (d *Database) Delete(hash string) error {
+	if err := d.db.Where("hash = ?", hash).Delete(&FileRecord{}).Error; err != nil {
+		logger.Get().Error().Err(err).Msgf("删除记录失败: %s", hash)
+		return err
+	}
+
+	d.mu.Lock()
+	d.cache[hash] = false
+	d.mu.Unlock()
+
+	logger.Get().Debug().Msgf("删除记录成功: %s", hash)
+	return nil
+}
+
 func (d *Database) Close() error {
 	logger.Get().Info().Msg("关闭数据库连接")
 	sqlDB, err := d.db.DB()
@@ -7,6 +7,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+
 	"github.com/moyu-x/classified-file/internal"
 )
 
@@ -272,3 +275,41 @@ func TestDatabase_Persistence(t *testing.T) {
 		t.Error("Expected hash to persist across database reopen")
 	}
 }
+
+func TestDatabase_MigrateLegacySchema(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	// 没有元数据列的旧版表结构
+	legacy, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("gorm.Open() error = %v", err)
+	}
+	if err := legacy.Exec(`CREATE TABLE file_hashes (
+		id integer PRIMARY KEY AUTOINCREMENT,
+		hash text NOT NULL UNIQUE,
+		file_path text NOT NULL,
+		file_size integer NOT NULL,
+		created_at datetime NOT NULL)`).Error; err != nil {
+		t.Fatalf("创建旧版表失败: %v", err)
+	}
+	if err := legacy.Exec(`INSERT INTO file_hashes (hash, file_path, file_size, created_at) VALUES (?, ?, ?, ?)`,
+		"legacy_hash", "/test/file.txt", 1024, time.Now()).Error; err != nil {
+		t.Fatalf("插入旧版记录失败: %v", err)
+	}
+	sqlDB, _ := legacy.DB()
+	sqlDB.Close()
+
+	db, err := NewDatabase(dbPath)
+	if err != nil {
+		t.Fatalf("NewDatabase() error = %v", err)
+	}
+	defer db.Close()
+
+	got, err := db.Lookup("legacy_hash")
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if got.FilePath != "/test/file.txt" || got.Metadata != nil {
+		t.Errorf("Lookup() = %+v, want legacy record without metadata", got)
+	}
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/moyu-x/classified-file/internal"
+)
+
+// MemoryStore 内存存储，进程退出后数据丢失，适用于一次性扫描和测试
+type MemoryStore struct {
+	records map[string]internal.FileRecord
+	nextID  int64
+	mu      sync.RWMutex
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records: make(map[string]internal.FileRecord),
+	}
+}
+
+func (m *MemoryStore) Exists(hash string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	_, ok := m.records[hash]
+	return ok, nil
+}
+
+func (m *MemoryStore) Insert(record *internal.FileRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.records[record.Hash]; ok {
+		return fmt.Errorf("哈希已存在: %s", record.Hash)
+	}
+
+	m.nextID++
+	stored := *record
+	stored.ID = m.nextID
+	m.records[record.Hash] = stored
+	return nil
+}
+
+func (m *MemoryStore) Lookup(hash string) (*internal.FileRecord, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	record, ok := m.records[hash]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	return &record, nil
+}
+
+func (m *MemoryStore) Iterate(fn func(record *internal.FileRecord) error) error {
+	m.mu.RLock()
+	records := make([]internal.FileRecord, 0, len(m.records))
+	for _, record := range m.records {
+		records = append(records, record)
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].ID < records[j].ID
+	})
+
+	for i := range records {
+		if err := fn(&records[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) Delete(hash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.records, hash)
+	return nil
+}
+
+func (m *MemoryStore) Close() error {
+	return nil
+}
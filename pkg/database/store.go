@@ -0,0 +1,61 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/moyu-x/classified-file/internal"
+)
+
+// 存储后端类型
+const (
+	BackendSQLite = "sqlite"
+	BackendMemory = "memory"
+	BackendBolt   = "bolt"
+)
+
+// ErrRecordNotFound 查询的哈希不存在
+var ErrRecordNotFound = errors.New("记录不存在")
+
+// Store 哈希记录存储接口
+type Store interface {
+	// Exists 检查哈希是否已存在
+	Exists(hash string) (bool, error)
+	// Insert 插入记录，哈希已存在时返回错误
+	Insert(record *internal.FileRecord) error
+	// Lookup 按哈希查询记录，不存在时返回 ErrRecordNotFound
+	Lookup(hash string) (*internal.FileRecord, error)
+	// Iterate 遍历所有记录，回调返回错误时停止遍历
+	Iterate(fn func(record *internal.FileRecord) error) error
+	// Delete 按哈希删除记录，不存在时不报错
+	Delete(hash string) error
+	// Close 关闭存储
+	Close() error
+}
+
+// Flusher 由缓冲写入的存储实现，Flush 将缓冲的记录写入磁盘，
+// 用于中断退出前保存已插入但尚未提交的记录
+type Flusher interface {
+	Flush() error
+}
+
+var (
+	_ Store   = (*Database)(nil)
+	_ Store   = (*MemoryStore)(nil)
+	_ Store   = (*BoltStore)(nil)
+	_ Flusher = (*BoltStore)(nil)
+)
+
+// NewStore 根据后端类型创建存储，backend 为空时使用 SQLite
+func NewStore(backend, path string) (Store, error) {
+	switch backend {
+	case "", BackendSQLite:
+		return NewDatabase(path)
+	case BackendMemory:
+		return NewMemoryStore(), nil
+	case BackendBolt:
+		return NewBoltStore(path)
+	default:
+		return nil, fmt.Errorf("不支持的存储后端: %s", backend)
+	}
+}
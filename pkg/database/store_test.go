@@ -0,0 +1,290 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/moyu-x/classified-file/internal"
+)
+
+func newTestStores(t *testing.T) map[string]Store {
+	tempDir := t.TempDir()
+
+	stores := make(map[string]Store)
+	for _, backend := range []string{BackendSQLite, BackendMemory, BackendBolt} {
+		store, err := NewStore(backend, filepath.Join(tempDir, backend+".db"))
+		if err != nil {
+			t.Fatalf("NewStore(%s) error = %v", backend, err)
+		}
+		t.Cleanup(func() { store.Close() })
+		stores[backend] = store
+	}
+	return stores
+}
+
+func TestNewStore_UnknownBackend(t *testing.T) {
+	if _, err := NewStore("redis", filepath.Join(t.TempDir(), "test.db")); err == nil {
+		t.Error("Expected error for unknown backend")
+	}
+}
+
+func TestStore_InsertLookup(t *testing.T) {
+	for backend, store := range newTestStores(t) {
+		t.Run(backend, func(t *testing.T) {
+			record := &internal.FileRecord{
+				Hash:      "lookup_hash",
+				FilePath:  "/test/file.txt",
+				FileSize:  1024,
+				CreatedAt: time.Now().Unix(),
+			}
+
+			if err := store.Insert(record); err != nil {
+				t.Fatalf("Insert() error = %v", err)
+			}
+
+			if err := store.Insert(record); err == nil {
+				t.Error("Expected error when inserting duplicate hash")
+			}
+
+			got, err := store.Lookup("lookup_hash")
+			if err != nil {
+				t.Fatalf("Lookup() error = %v", err)
+			}
+			if got.FilePath != record.FilePath || got.FileSize != record.FileSize {
+				t.Errorf("Lookup() = %+v, want path %s size %d", got, record.FilePath, record.FileSize)
+			}
+			if got.ID == 0 {
+				t.Error("Expected record ID to be assigned")
+			}
+
+			if _, err := store.Lookup("missing_hash"); !errors.Is(err, ErrRecordNotFound) {
+				t.Errorf("Lookup() missing error = %v, want ErrRecordNotFound", err)
+			}
+		})
+	}
+}
+
+func TestStore_Metadata(t *testing.T) {
+	for backend, store := range newTestStores(t) {
+		t.Run(backend, func(t *testing.T) {
+			meta := &internal.FileMetadata{
+				TakenAt:     time.Date(2023, 7, 14, 9, 30, 5, 0, time.UTC).Unix(),
+				TakenSource: "exif",
+				Make:        "Apple",
+				Model:       "iPhone 12",
+				Orientation: 6,
+				Width:       4032,
+				Height:      3024,
+				HasGPS:      true,
+				Latitude:    -33.86,
+				Longitude:   151.2,
+				Altitude:    -10.5,
+			}
+			records := []*internal.FileRecord{
+				{Hash: "photo_hash", FilePath: "/test/photo.heic", FileSize: 1, Metadata: meta},
+				{Hash: "plain_hash", FilePath: "/test/file.txt", FileSize: 1},
+			}
+			for _, record := range records {
+				if err := store.Insert(record); err != nil {
+					t.Fatalf("Insert() error = %v", err)
+				}
+			}
+
+			got, err := store.Lookup("photo_hash")
+			if err != nil {
+				t.Fatalf("Lookup() error = %v", err)
+			}
+			if got.Metadata == nil || *got.Metadata != *meta {
+				t.Errorf("Lookup() metadata = %+v, want %+v", got.Metadata, meta)
+			}
+
+			got, err = store.Lookup("plain_hash")
+			if err != nil {
+				t.Fatalf("Lookup() error = %v", err)
+			}
+			if got.Metadata != nil {
+				t.Errorf("Expected no metadata, got %+v", got.Metadata)
+			}
+		})
+	}
+}
+
+func TestStore_Delete(t *testing.T) {
+	for backend, store := range newTestStores(t) {
+		t.Run(backend, func(t *testing.T) {
+			record := &internal.FileRecord{
+				Hash:      "delete_hash",
+				FilePath:  "/test/file.txt",
+				FileSize:  1024,
+				CreatedAt: time.Now().Unix(),
+			}
+
+			if err := store.Insert(record); err != nil {
+				t.Fatalf("Insert() error = %v", err)
+			}
+
+			if exists, _ := store.Exists("delete_hash"); !exists {
+				t.Fatal("Expected hash to exist after insert")
+			}
+
+			if err := store.Delete("delete_hash"); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+
+			exists, err := store.Exists("delete_hash")
+			if err != nil {
+				t.Fatalf("Exists() error = %v", err)
+			}
+			if exists {
+				t.Error("Expected hash to not exist after delete")
+			}
+
+			if err := store.Delete("delete_hash"); err != nil {
+				t.Errorf("Delete() of missing hash error = %v", err)
+			}
+		})
+	}
+}
+
+func TestStore_Iterate(t *testing.T) {
+	for backend, store := range newTestStores(t) {
+		t.Run(backend, func(t *testing.T) {
+			const numRecords = 25
+
+			for i := 0; i < numRecords; i++ {
+				record := &internal.FileRecord{
+					Hash:      fmt.Sprintf("hash%02d", i),
+					FilePath:  fmt.Sprintf("/test/file%d.txt", i),
+					FileSize:  int64(i),
+					CreatedAt: time.Now().Unix(),
+				}
+				if err := store.Insert(record); err != nil {
+					t.Fatalf("Insert() error = %v", err)
+				}
+			}
+
+			seen := make(map[string]bool)
+			err := store.Iterate(func(record *internal.FileRecord) error {
+				seen[record.Hash] = true
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Iterate() error = %v", err)
+			}
+			if len(seen) != numRecords {
+				t.Errorf("Expected %d records, got %d", numRecords, len(seen))
+			}
+
+			stopErr := errors.New("stop")
+			count := 0
+			err = store.Iterate(func(record *internal.FileRecord) error {
+				count++
+				return stopErr
+			})
+			if !errors.Is(err, stopErr) {
+				t.Errorf("Iterate() error = %v, want callback error", err)
+			}
+			if count != 1 {
+				t.Errorf("Expected iteration to stop after 1 record, got %d", count)
+			}
+		})
+	}
+}
+
+func TestBoltStore_Persistence(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.bolt")
+
+	store1, err := NewBoltStore(dbPath)
+	if err != nil {
+		t.Fatalf("First NewBoltStore() error = %v", err)
+	}
+
+	record := &internal.FileRecord{
+		Hash:      "persistent_hash",
+		FilePath:  "/test/file.txt",
+		FileSize:  1024,
+		CreatedAt: time.Now().Unix(),
+	}
+	if err := store1.Insert(record); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+	if err := store1.Close(); err != nil {
+		t.Fatalf("First Close() error = %v", err)
+	}
+
+	store2, err := NewBoltStore(dbPath)
+	if err != nil {
+		t.Fatalf("Second NewBoltStore() error = %v", err)
+	}
+	defer store2.Close()
+
+	exists, err := store2.Exists("persistent_hash")
+	if err != nil {
+		t.Fatalf("Exists() error = %v", err)
+	}
+	if !exists {
+		t.Error("Expected hash to persist across reopen")
+	}
+}
+
+func TestBoltStore_BatchedInsert(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.bolt")
+	store, err := NewBoltStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewBoltStore() error = %v", err)
+	}
+
+	for i := 0; i <= boltBatchSize; i++ {
+		record := &internal.FileRecord{Hash: fmt.Sprintf("hash_%d", i), FilePath: fmt.Sprintf("/test/%d", i)}
+		if err := store.Insert(record); err != nil {
+			t.Fatalf("Insert() error = %v", err)
+		}
+	}
+
+	committed := 0
+	store.db.View(func(tx *bolt.Tx) error {
+		committed = tx.Bucket(boltBucket).Stats().KeyN
+		return nil
+	})
+	if committed != boltBatchSize {
+		t.Errorf("Expected %d committed records before Close, got %d", boltBatchSize, committed)
+	}
+
+	last := fmt.Sprintf("hash_%d", boltBatchSize)
+	if exists, err := store.Exists(last); err != nil || !exists {
+		t.Errorf("Exists() = %v, %v; want buffered record to exist", exists, err)
+	}
+	if err := store.Insert(&internal.FileRecord{Hash: last}); err == nil {
+		t.Error("Expected duplicate buffered hash to be rejected")
+	}
+	record, err := store.Lookup(last)
+	if err != nil || record.ID != boltBatchSize+1 {
+		t.Errorf("Lookup() = %+v, %v; want ID %d", record, err, boltBatchSize+1)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	store, err = NewBoltStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewBoltStore() error = %v", err)
+	}
+	defer store.Close()
+
+	if err := store.Insert(&internal.FileRecord{Hash: "after_reopen"}); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+	record, err = store.Lookup("after_reopen")
+	if err != nil || record.ID != boltBatchSize+2 {
+		t.Errorf("Lookup() = %+v, %v; want ID %d after reopen", record, err, boltBatchSize+2)
+	}
+	count := 0
+	if err := store.Iterate(func(*internal.FileRecord) error { count++; return nil }); err != nil || count != boltBatchSize+2 {
+		t.Errorf("Iterate() visited %d records, error = %v; want %d", count, err, boltBatchSize+2)
+	}
+}
@@ -0,0 +1,184 @@
+package deduplicator
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/moyu-x/classified-file/internal"
+	"github.com/moyu-x/classified-file/pkg/archive"
+	"github.com/moyu-x/classified-file/pkg/database"
+	"github.com/moyu-x/classified-file/pkg/hasher"
+	"github.com/moyu-x/classified-file/pkg/logger"
+	"github.com/moyu-x/classified-file/pkg/scanner"
+)
+
+// SetScanArchives 设置是否索引 zip、tar、tar.gz、tar.bz2 压缩包中的成员
+func (d *Deduplicator) SetScanArchives(enabled bool) {
+	d.scanArchives = enabled
+}
+
+// indexArchives 在处理文件前索引扫描目录中所有压缩包的成员，
+// 使已经保存在压缩包中的散落文件在随后的处理中被识别为重复文件
+func (d *Deduplicator) indexArchives(dirs []string) {
+	walker := d.walker.Clone()
+	for _, dir := range dirs {
+		walker.WalkDir(dir, func(path string, entry fs.DirEntry) error {
+			if archive.IsArchive(path) {
+				d.indexArchive(path)
+			}
+			return nil
+		})
+	}
+}
+
+// indexArchiveList 索引文件列表中的压缩包成员
+func (d *Deduplicator) indexArchiveList(paths []string) {
+	d.walker.Clone().WalkList(paths, func(path string, info os.FileInfo) error {
+		if archive.IsArchive(path) {
+			d.indexArchive(path)
+		}
+		return nil
+	})
+}
+
+func (d *Deduplicator) indexArchive(archivePath string) {
+	if !d.claimArchive(archivePath) {
+		return
+	}
+
+	logger.Get().Info().Msgf("索引压缩包: %s", archivePath)
+	d.stats.Archives++
+
+	err := archive.Walk(archivePath, func(member archive.Member, r io.Reader) error {
+		hash, err := hasher.HashReader(r)
+		if err != nil {
+			return fmt.Errorf("读取成员 %s 失败: %w", member.Name, err)
+		}
+		d.indexMember(archive.MemberPath(archivePath, member.Name), member.Size, fmt.Sprintf("%016x", hash))
+		return nil
+	})
+	if err != nil {
+		logger.Get().Error().Err(err).Msgf("索引压缩包失败: %s", archivePath)
+		d.walker.Errors.Add(archivePath, scanner.OpArchive, err)
+	}
+}
+
+// ProcessFS 记录 fsys 中的文件并报告其中的重复文件，用于压缩包、内存文件系统等非本地目录树
+// fsys 中的文件只读取不修改，记录路径为 label!/name 形式的虚拟路径，与压缩包成员一样只会被报告；
+// 已记录的散落文件与之相同时改为以 fsys 中的文件为准，散落文件随后按重复文件处理
+func (d *Deduplicator) ProcessFS(fsys fs.FS, label string) (*internal.ProcessStats, error) {
+	d.stats = internal.ProcessStats{
+		StartTime: time.Now(),
+	}
+	d.linkSets = make(map[scanner.FileID][]string)
+	d.removedLinks = make(map[scanner.FileID]uint64)
+
+	err := d.walker.WalkFS(fsys, func(name string, entry fs.DirEntry) error {
+		memberPath := archive.MemberPath(label, name)
+
+		info, err := entry.Info()
+		if err != nil {
+			logger.Get().Error().Err(err).Msgf("获取文件信息失败: %s", memberPath)
+			d.walker.Errors.Add(memberPath, scanner.OpStat, err)
+			return nil
+		}
+
+		hash, err := hasher.CalculateHashFS(fsys, name)
+		if err != nil {
+			logger.Get().Error().Err(err).Msgf("处理文件失败: %s", memberPath)
+			d.walker.Errors.Add(memberPath, scanner.OpRead, err)
+			return nil
+		}
+
+		d.indexMember(memberPath, info.Size(), fmt.Sprintf("%016x", hash))
+		d.stats.TotalProcessed++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return d.finish(), nil
+}
+
+// claimArchive 在索引成员前先按压缩包本身去重。与已记录文件相同的压缩包随后会按重复文件处理，
+// 不再索引其成员；否则先记录压缩包本身，使成员记录始终指向被保留的那个压缩包
+func (d *Deduplicator) claimArchive(archivePath string) bool {
+	hash, err := hasher.CalculateHash(archivePath)
+	if err != nil {
+		logger.Get().Error().Err(err).Msgf("处理文件失败: %s", archivePath)
+		d.walker.Errors.Add(archivePath, scanner.OpRead, err)
+		return false
+	}
+	hashStr := fmt.Sprintf("%016x", hash)
+
+	record, err := d.db.Lookup(hashStr)
+	if errors.Is(err, database.ErrRecordNotFound) {
+		info, err := os.Stat(archivePath)
+		if err != nil {
+			logger.Get().Error().Err(err).Msgf("获取文件信息失败: %s", archivePath)
+			return false
+		}
+		d.insertRecord(archivePath, info.Size(), hashStr)
+		return true
+	}
+	if err != nil {
+		logger.Get().Error().Err(err).Msgf("查询记录失败: %s", archivePath)
+		return false
+	}
+
+	if !isSamePath(record.FilePath, archivePath) {
+		logger.Get().Info().Msgf("跳过重复的压缩包: %s (与 %s 相同)", archivePath, record.FilePath)
+		return false
+	}
+	return true
+}
+
+// indexMember 记录压缩包成员的哈希，压缩包成员只会被报告，不会被删除或移动
+func (d *Deduplicator) indexMember(memberPath string, size int64, hashStr string) {
+	d.stats.ArchiveMembers++
+
+	record, err := d.db.Lookup(hashStr)
+	if errors.Is(err, database.ErrRecordNotFound) {
+		d.insertRecord(memberPath, size, hashStr)
+		logger.Get().Debug().Msgf("新增压缩包成员记录: %s", memberPath)
+		return
+	}
+	if err != nil {
+		logger.Get().Error().Err(err).Msgf("查询记录失败: %s", memberPath)
+		return
+	}
+
+	switch {
+	case record.FilePath == memberPath:
+		logger.Get().Debug().Msgf("压缩包成员已记录: %s", memberPath)
+	case archive.IsMemberPath(record.FilePath):
+		d.stats.ArchiveDups++
+		logger.Get().Info().Msgf("压缩包成员重复: %s (与 %s 相同，压缩包成员不会被修改)", memberPath, record.FilePath)
+	default:
+		// 已记录的散落文件同样保存在压缩包中，改为以压缩包成员为准，散落文件随后按重复文件处理
+		d.stats.ArchiveDups++
+		if err := d.db.Delete(hashStr); err != nil {
+			logger.Get().Error().Err(err).Msgf("更新记录失败: %s", record.FilePath)
+			return
+		}
+		d.insertRecord(memberPath, size, hashStr)
+		logger.Get().Info().Msgf("文件已保存在压缩包中: %s (%s)", record.FilePath, memberPath)
+	}
+}
+
+func (d *Deduplicator) insertRecord(path string, size int64, hashStr string) {
+	record := &internal.FileRecord{
+		Hash:      hashStr,
+		FilePath:  path,
+		FileSize:  size,
+		CreatedAt: time.Now().Unix(),
+	}
+	if err := d.db.Insert(record); err != nil {
+		logger.Get().Error().Err(err).Msgf("插入记录失败: %s", path)
+	}
+}
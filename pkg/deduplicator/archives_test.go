@@ -0,0 +1,224 @@
+package deduplicator
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/moyu-x/classified-file/internal"
+	"github.com/moyu-x/classified-file/pkg/archive"
+	"github.com/moyu-x/classified-file/pkg/database"
+	"github.com/moyu-x/classified-file/pkg/hasher"
+)
+
+func writeZip(t *testing.T, archivePath string, members map[string]string) {
+	t.Helper()
+
+	file, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	defer file.Close()
+
+	writer := zip.NewWriter(file)
+	for name, content := range members {
+		w, err := writer.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to add member: %v", err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("Failed to write member: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close archive: %v", err)
+	}
+}
+
+func TestDeduplicator_Process_Archives(t *testing.T) {
+	tempDir := t.TempDir()
+	data := filepath.Join(tempDir, "data")
+	if err := os.MkdirAll(data, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	// 散落文件 a.jpg 在压缩包被扫描之前就已记录
+	loose := filepath.Join(data, "a.jpg")
+	if err := os.WriteFile(loose, []byte("photo"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	unique := filepath.Join(data, "unique.txt")
+	if err := os.WriteFile(unique, []byte("unique"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	store := database.NewMemoryStore()
+	if _, err := NewDeduplicator(store, internal.ModeDelete, "", false).Process([]string{data}, false, false); err != nil {
+		t.Fatalf("First Process() error = %v", err)
+	}
+
+	backup := filepath.Join(data, "backup.zip")
+	writeZip(t, backup, map[string]string{
+		"photos/a.jpg": "photo",
+		"photos/b.jpg": "other",
+	})
+	second := filepath.Join(data, "second.zip")
+	writeZip(t, second, map[string]string{"b-copy.jpg": "other"})
+
+	d := NewDeduplicator(store, internal.ModeDelete, "", false)
+	d.SetScanArchives(true)
+
+	stats, err := d.Process([]string{data}, false, false)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if stats.Archives != 2 || stats.ArchiveMembers != 3 {
+		t.Errorf("Expected 2 archives with 3 members, got %d archives, %d members", stats.Archives, stats.ArchiveMembers)
+	}
+
+	if stats.ArchiveDups != 2 {
+		t.Errorf("Expected 2 archive duplicates, got %d", stats.ArchiveDups)
+	}
+
+	if _, err := os.Stat(loose); !os.IsNotExist(err) {
+		t.Errorf("Expected loose file preserved in archive to be deleted")
+	}
+
+	for _, file := range []string{unique, backup, second} {
+		if _, err := os.Stat(file); err != nil {
+			t.Errorf("Expected %s to be kept: %v", file, err)
+		}
+	}
+
+	hash, err := hasher.CalculateHash(unique)
+	if err != nil {
+		t.Fatalf("CalculateHash() error = %v", err)
+	}
+	if record, err := store.Lookup(fmt.Sprintf("%016x", hash)); err != nil || record.FilePath != unique {
+		t.Errorf("Expected unique file record to be unchanged, got %+v, %v", record, err)
+	}
+
+	record, err := store.Lookup(fmt.Sprintf("%016x", hashOf(t, "photo")))
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if record.FilePath != archive.MemberPath(backup, "photos/a.jpg") {
+		t.Errorf("Expected record to point at archive member, got %s", record.FilePath)
+	}
+}
+
+func hashOf(t *testing.T, content string) uint64 {
+	t.Helper()
+
+	hash, err := hasher.HashReader(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("HashReader() error = %v", err)
+	}
+	return hash
+}
+
+func TestDeduplicator_Process_DuplicateArchives(t *testing.T) {
+	tempDir := t.TempDir()
+	data := filepath.Join(tempDir, "data")
+	if err := os.MkdirAll(data, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	// 未开启压缩包扫描时已记录的压缩包
+	recorded := filepath.Join(data, "recorded.zip")
+	writeZip(t, recorded, map[string]string{"a.jpg": "photo"})
+
+	store := database.NewMemoryStore()
+	if _, err := NewDeduplicator(store, internal.ModeDelete, "", false).Process([]string{data}, false, false); err != nil {
+		t.Fatalf("First Process() error = %v", err)
+	}
+
+	content, err := os.ReadFile(recorded)
+	if err != nil {
+		t.Fatalf("Failed to read archive: %v", err)
+	}
+	copyPath := filepath.Join(data, "copy.zip")
+	if err := os.WriteFile(copyPath, content, 0644); err != nil {
+		t.Fatalf("Failed to create archive copy: %v", err)
+	}
+
+	d := NewDeduplicator(store, internal.ModeDelete, "", false)
+	d.SetScanArchives(true)
+
+	stats, err := d.Process([]string{data}, false, false)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if stats.Archives != 1 || stats.ArchiveMembers != 1 {
+		t.Errorf("Expected 1 archive with 1 member, got %d archives, %d members", stats.Archives, stats.ArchiveMembers)
+	}
+
+	if _, err := os.Stat(copyPath); !os.IsNotExist(err) {
+		t.Errorf("Expected duplicate archive to be deleted")
+	}
+	if _, err := os.Stat(recorded); err != nil {
+		t.Errorf("Expected recorded archive to be kept: %v", err)
+	}
+
+	record, err := store.Lookup(fmt.Sprintf("%016x", hashOf(t, "photo")))
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if record.FilePath != archive.MemberPath(recorded, "a.jpg") {
+		t.Errorf("Expected member record to point at kept archive, got %s", record.FilePath)
+	}
+}
+
+func TestDeduplicator_ProcessFS(t *testing.T) {
+	data := t.TempDir()
+	loose := filepath.Join(data, "a.jpg")
+	if err := os.WriteFile(loose, []byte("photo"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	store := database.NewMemoryStore()
+	if _, err := NewDeduplicator(store, internal.ModeDelete, "", false).Process([]string{data}, false, false); err != nil {
+		t.Fatalf("First Process() error = %v", err)
+	}
+
+	fsys := fstest.MapFS{
+		"photos/a.jpg":      {Data: []byte("photo")},
+		"photos/b.jpg":      {Data: []byte("other")},
+		"copies/b-copy.jpg": {Data: []byte("other")},
+	}
+
+	stats, err := NewDeduplicator(store, internal.ModeDelete, "", false).ProcessFS(fsys, "memory")
+	if err != nil {
+		t.Fatalf("ProcessFS() error = %v", err)
+	}
+
+	if stats.TotalProcessed != 3 || stats.ArchiveMembers != 3 || stats.ArchiveDups != 2 {
+		t.Errorf("Expected 3 processed, 3 members and 2 duplicates, got %d, %d, %d",
+			stats.TotalProcessed, stats.ArchiveMembers, stats.ArchiveDups)
+	}
+	if _, err := os.Stat(loose); err != nil {
+		t.Errorf("ProcessFS() must not touch local files: %v", err)
+	}
+
+	record, err := store.Lookup(fmt.Sprintf("%016x", hashOf(t, "photo")))
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if record.FilePath != archive.MemberPath("memory", "photos/a.jpg") {
+		t.Errorf("Expected record to point at the fs.FS file, got %s", record.FilePath)
+	}
+
+	// 散落文件随后按重复文件处理
+	if _, err := NewDeduplicator(store, internal.ModeDelete, "", false).Process([]string{data}, false, false); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if _, err := os.Stat(loose); !os.IsNotExist(err) {
+		t.Errorf("Expected loose duplicate to be deleted, got %v", err)
+	}
+}
@@ -1,50 +1,93 @@
 package deduplicator
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/moyu-x/classified-file/pkg/database"
 	"github.com/moyu-x/classified-file/pkg/hasher"
+	"github.com/moyu-x/classified-file/pkg/lock"
 	"github.com/moyu-x/classified-file/internal"
 	"github.com/moyu-x/classified-file/pkg/logger"
 	"github.com/moyu-x/classified-file/pkg/progress"
 	"github.com/moyu-x/classified-file/pkg/scanner"
+	"github.com/moyu-x/classified-file/pkg/stability"
 )
 
 type Deduplicator struct {
-	db           *database.Database
+	db           database.Store
+	walker       *scanner.FileWalker
 	mode         internal.OperationMode
 	targetDir    string
 	stats        internal.ProcessStats
 	progressChan chan internal.ProgressUpdate
-	totalFiles   int
+	totalFiles   atomic.Int64
 	verbose      bool
 
+	countMode   internal.CountMode
+	discovering atomic.Bool
+
+	scanArchives bool
+	dirMode      internal.DirMode
+	stability    *stability.Checker
+	deferred     stability.Deferred // 最近被修改、推迟到本轮处理结束后复查的文件
+
+	watchCtx    context.Context
+	watchSettle time.Duration
+	watching    atomic.Bool
+
 	trackers   map[string]*progress.Tracker
 	resumeMode bool
 	resetMode  bool
+
+	locks    map[string]*lock.FileLock
+	lockWait bool
+	// stateMu 保护 trackers 和 locks 的写入以及 unmarked，中断处理在信号 goroutine 中访问它们
+	stateMu sync.Mutex
+
+	// unmarked 是数据库记录尚未写入、暂不写入进度文件的已处理文件，由 stateMu 保护
+	unmarked []progressMark
+
+	linkSets     map[scanner.FileID][]string
+	removedLinks map[scanner.FileID]uint64 // 删除模式下每个 inode 已删除的链接数
+}
+
+// progressBatchSize 是数据库缓冲写入时进度标记的批量大小，每批先写入数据库记录再更新进度
+const progressBatchSize = 1000
+
+// progressMark 是等待写入进度文件的已处理文件
+type progressMark struct {
+	tracker *progress.Tracker
+	path    string
 }
 
 var globalDedup *Deduplicator
 
-func NewDeduplicator(db *database.Database, mode internal.OperationMode, targetDir string, verbose bool) *Deduplicator {
+func NewDeduplicator(db database.Store, mode internal.OperationMode, targetDir string, verbose bool) *Deduplicator {
 	logger.Get().Info().Msgf("创建去重处理器，模式: %s", mode)
 	if targetDir != "" {
 		logger.Get().Info().Msgf("目标目录: %s", targetDir)
 	}
 	dedup := &Deduplicator{
 		db:           db,
+		walker:       scanner.NewFileWalker(),
 		mode:         mode,
 		targetDir:    targetDir,
 		progressChan: make(chan internal.ProgressUpdate, 100),
 		verbose:      verbose,
 		trackers:     make(map[string]*progress.Tracker),
+		countMode:    internal.CountUpfront,
+		locks:        make(map[string]*lock.FileLock),
 	}
 	globalDedup = dedup
 	return dedup
@@ -55,15 +98,41 @@ func SetupSignalHandler() {
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
 	go func() {
-		sig := <-sigChan
-		logger.Get().Warn().Msgf("收到信号 %v，正在优雅关闭...", sig)
-		if globalDedup != nil {
-			globalDedup.HandleInterrupt()
+		for sig := range sigChan {
+			// 监视模式由监视上下文负责结束，Process 返回前会正常保存状态
+			if globalDedup != nil && globalDedup.watching.Load() {
+				logger.Get().Warn().Msgf("收到信号 %v，停止监视...", sig)
+				continue
+			}
+			logger.Get().Warn().Msgf("收到信号 %v，正在优雅关闭...", sig)
+			if globalDedup != nil {
+				globalDedup.HandleInterrupt()
+			}
+			os.Exit(0)
 		}
-		os.Exit(0)
 	}()
 }
 
+// SetWalker 设置遍历目录使用的 FileWalker
+func (d *Deduplicator) SetWalker(walker *scanner.FileWalker) {
+	d.walker = walker
+}
+
+// SetStability 设置处理文件前的写入检查，为 nil 时不检查
+func (d *Deduplicator) SetStability(checker *stability.Checker) {
+	d.stability = checker
+}
+
+// SetCountMode 设置文件计数模式，未知模式按 CountUpfront 处理
+func (d *Deduplicator) SetCountMode(mode internal.CountMode) {
+	d.countMode = mode
+}
+
+// SetLockWait 设置进度状态被其他进程锁定时是否等待释放
+func (d *Deduplicator) SetLockWait(wait bool) {
+	d.lockWait = wait
+}
+
 func (d *Deduplicator) Process(dirs []string, resume, reset bool) (*internal.ProcessStats, error) {
 	d.resumeMode = resume
 	d.resetMode = reset
@@ -73,11 +142,22 @@ func (d *Deduplicator) Process(dirs []string, resume, reset bool) (*internal.Pro
 	d.stats = internal.ProcessStats{
 		StartTime: time.Now(),
 	}
+	d.linkSets = make(map[scanner.FileID][]string)
+	d.removedLinks = make(map[scanner.FileID]uint64)
+
+	defer d.releaseLocks()
+
+	dirs = canonicalizeRoots(dirs)
 
 	for _, dir := range dirs {
 		rootDir := getRootDir(dir)
 		progressRoot := getProgressRoot(dir)
 
+		if err := d.acquireLock(progressRoot); err != nil {
+			d.releaseTrackers()
+			return nil, err
+		}
+
 		if reset {
 			if progress.Exists(progressRoot) {
 				logger.Get().Info().Msgf("删除进度文件: %s", progressRoot)
@@ -91,10 +171,13 @@ func (d *Deduplicator) Process(dirs []string, resume, reset bool) (*internal.Pro
 		tracker, err := progress.NewTracker(progressRoot)
 		if err != nil {
 			logger.Get().Error().Err(err).Msgf("创建进度跟踪器失败: %s", progressRoot)
+			d.releaseTrackers()
 			return nil, err
 		}
 
+		d.stateMu.Lock()
 		d.trackers[rootDir] = tracker
+		d.stateMu.Unlock()
 
 		processedCount := tracker.GetProcessedCount()
 		if processedCount > 0 {
@@ -102,111 +185,439 @@ func (d *Deduplicator) Process(dirs []string, resume, reset bool) (*internal.Pro
 		}
 	}
 
-	walker := scanner.NewFileWalker()
-	totalFiles, err := walker.CountFiles(dirs)
-	if err != nil {
-		return nil, fmt.Errorf("统计文件数量失败: %w", err)
+	switch d.dirMode {
+	case internal.DirsReport:
+		// 只报告时不再逐文件处理，避免重复目录中的每个文件都被单独报告
+		d.analyzeDirs(d.walker, dirs)
+		d.closeTrackers()
+		return d.finish(), nil
+	case internal.DirsApply:
+		d.analyzeDirs(d.walker.Clone(), dirs)
 	}
-	d.totalFiles = totalFiles
 
-	logger.Get().Info().Msgf("文件统计完成，共找到 %d 个文件", totalFiles)
+	if d.scanArchives {
+		d.indexArchives(dirs)
+	}
 
-	d.processFiles(walker, dirs)
+	switch d.countMode {
+	case internal.CountStream:
+		d.streamFiles(dirs)
+	case internal.CountNone:
+		d.totalFiles.Store(-1)
+		d.processFiles(dirs)
+	default:
+		// 计数使用单独的 FileWalker，特殊文件等跳过统计只在处理时计入一次
+		totalFiles, err := d.walker.Clone().CountFiles(dirs)
+		if err != nil {
+			d.releaseTrackers()
+			return nil, fmt.Errorf("统计文件数量失败: %w", err)
+		}
+		d.totalFiles.Store(int64(totalFiles))
+		d.processFiles(dirs)
+	}
 
-	for rootDir, tracker := range d.trackers {
+	if d.watchCtx != nil {
+		if err := d.watchFiles(dirs); err != nil {
+			d.releaseTrackers()
+			return nil, err
+		}
+	}
+
+	d.closeTrackers()
+	return d.finish(), nil
+}
+
+// closeTrackers 在扫描完成后关闭并删除各根目录的进度文件
+func (d *Deduplicator) closeTrackers() {
+	for rootDir, tracker := range d.takeTrackers() {
 		if err := tracker.Close(); err != nil {
 			logger.Get().Error().Err(err).Msgf("关闭进度跟踪器失败: %s", rootDir)
 		}
 	}
+}
+
+// releaseTrackers 在扫描出错时关闭进度文件，保留已处理的记录以便恢复
+func (d *Deduplicator) releaseTrackers() {
+	for rootDir, tracker := range d.takeTrackers() {
+		if err := tracker.Release(); err != nil {
+			logger.Get().Error().Err(err).Msgf("关闭进度跟踪器失败: %s", rootDir)
+		}
+	}
+}
+
+// flushTrackers 将各根目录的进度写入磁盘，可在中断处理中与 Process 并发调用
+func (d *Deduplicator) flushTrackers() {
+	d.stateMu.Lock()
+	defer d.stateMu.Unlock()
+
+	d.commitProgress()
+	for rootDir, tracker := range d.trackers {
+		if err := tracker.Flush(); err != nil {
+			logger.Get().Error().Err(err).Msgf("刷新进度文件失败: %s", rootDir)
+		} else {
+			logger.Get().Info().Msgf("进度文件已保存: %s (已处理 %d 个文件)",
+				rootDir, tracker.GetProcessedCount())
+		}
+	}
+}
+
+// takeTrackers 写入尚未保存的进度后取出并清空已打开的进度跟踪器
+func (d *Deduplicator) takeTrackers() map[string]*progress.Tracker {
+	d.stateMu.Lock()
+	defer d.stateMu.Unlock()
+
+	d.commitProgress()
+	trackers := d.trackers
+	d.trackers = make(map[string]*progress.Tracker)
+	return trackers
+}
+
+// ProcessFiles 处理给定的文件列表而不遍历目录，用于 --files-from
+// 文件列表没有扫描根目录，因此不记录进度，也不支持恢复和重置模式
+func (d *Deduplicator) ProcessFiles(files []string) (*internal.ProcessStats, error) {
+	d.stats = internal.ProcessStats{
+		StartTime: time.Now(),
+	}
+	d.linkSets = make(map[scanner.FileID][]string)
+	d.removedLinks = make(map[scanner.FileID]uint64)
+
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		paths = append(paths, getRootDir(file))
+	}
+	d.totalFiles.Store(int64(len(paths)))
+	logger.Get().Info().Msgf("从文件列表读取到 %d 个路径", len(paths))
+
+	if d.scanArchives {
+		d.indexArchiveList(paths)
+	}
+
+	err := d.walker.WalkList(paths, func(path string, info os.FileInfo) error {
+		d.processFile(path, info, nil)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	d.recheckDeferred()
+
+	return d.finish(), nil
+}
+
+// finish 汇总遍历结果并输出处理统计
+func (d *Deduplicator) finish() *internal.ProcessStats {
+	d.collectHardLinkSets()
+	d.stats.DanglingLinks = len(d.walker.DanglingLinks())
+	d.stats.ScanErrors = d.walker.Errors.Len()
+	d.stats.Skipped = d.walker.Skipped()
 
 	d.stats.EndTime = time.Now()
 	duration := d.stats.EndTime.Sub(d.stats.StartTime)
 	logger.Get().Info().Msgf("文件处理完成，总耗时: %v", duration)
-	logger.Get().Info().Msgf("统计: TotalProcessed=%d, Added=%d, Deleted=%d, Moved=%d",
-		d.stats.TotalProcessed, d.stats.Added, d.stats.Deleted, d.stats.Moved)
-	return &d.stats, nil
+	logger.Get().Info().Msgf("统计: TotalProcessed=%d, Added=%d, Deleted=%d, Moved=%d, HardLinked=%d",
+		d.stats.TotalProcessed, d.stats.Added, d.stats.Deleted, d.stats.Moved, d.stats.HardLinked)
+	return &d.stats
 }
 
-func (d *Deduplicator) processFiles(walker *scanner.FileWalker, dirs []string) {
+func (d *Deduplicator) acquireLock(progressRoot string) error {
+	d.stateMu.Lock()
+	_, ok := d.locks[progressRoot]
+	d.stateMu.Unlock()
+	if ok {
+		return nil
+	}
+
+	// 等待锁时不持有 stateMu，以免阻塞中断处理
+	fileLock, err := lock.Acquire(filepath.Join(progressRoot, progress.LockFileName), d.lockWait)
+	if err != nil {
+		logger.Get().Error().Err(err).Msgf("获取进度锁失败: %s", progressRoot)
+		return fmt.Errorf("获取进度锁失败: %w", err)
+	}
+
+	d.stateMu.Lock()
+	d.locks[progressRoot] = fileLock
+	d.stateMu.Unlock()
+	return nil
+}
+
+func (d *Deduplicator) releaseLocks() {
+	d.stateMu.Lock()
+	locks := d.locks
+	d.locks = make(map[string]*lock.FileLock)
+	d.stateMu.Unlock()
+
+	for progressRoot, fileLock := range locks {
+		if err := fileLock.Release(); err != nil {
+			logger.Get().Error().Err(err).Msgf("释放进度锁失败: %s", progressRoot)
+		}
+	}
+}
+
+func (d *Deduplicator) processFiles(dirs []string) {
 	for _, dir := range dirs {
-		rootDir := getRootDir(dir)
-		tracker := d.trackers[rootDir]
-
-		walker.Walk(dir, func(path string, info os.FileInfo) error {
-			if tracker.IsProcessed(path) {
-				if d.resumeMode {
-					d.stats.TotalProcessed++
-					if d.verbose {
-						logger.Get().Debug().Msgf("[%d/%d] 跳过已处理文件: %s",
-							d.stats.TotalProcessed, d.totalFiles, path)
-					}
-					return nil
-				}
-			}
+		tracker := d.trackers[getRootDir(dir)]
+		d.walker.Walk(dir, func(path string, info os.FileInfo) error {
+			d.processFile(path, info, tracker)
+			return nil
+		})
+	}
+	d.recheckDeferred()
+}
 
-			hash, err := hasher.CalculateHash(path)
-			if err != nil {
-				logger.Get().Error().Err(err).Msgf("处理文件失败: %s", path)
-				return nil
-			}
+type discoveredFile struct {
+	path    string
+	info    os.FileInfo
+	tracker *progress.Tracker
+}
 
-			hashStr := fmt.Sprintf("%016x", hash)
-			logger.Get().Debug().Msgf("File hash: %s = %s", path, hashStr)
+// streamFiles 在后台遍历目录并通过通道交给处理循环，总数随发现实时增加，
+// 避免在网络挂载等慢速文件系统上为统计总数额外遍历一次
+func (d *Deduplicator) streamFiles(dirs []string) {
+	files := make(chan discoveredFile, internal.DefaultBufferSize)
+	d.discovering.Store(true)
 
-			exists, err := d.db.Exists(hashStr)
-			if err != nil {
-				logger.Get().Error().Err(err).Msgf("查询数据库失败: %s", path)
+	go func() {
+		defer close(files)
+		defer d.discovering.Store(false)
+
+		for _, dir := range dirs {
+			tracker := d.trackers[getRootDir(dir)]
+			d.walker.Walk(dir, func(path string, info os.FileInfo) error {
+				d.totalFiles.Add(1)
+				files <- discoveredFile{path: path, info: info, tracker: tracker}
 				return nil
+			})
+		}
+		logger.Get().Info().Msgf("文件发现完成，共找到 %d 个文件", d.totalFiles.Load())
+	}()
+
+	for file := range files {
+		d.processFile(file.path, file.info, file.tracker)
+	}
+	d.recheckDeferred()
+}
+
+// processFile 处理单个文件，tracker 为 nil 时不记录进度
+func (d *Deduplicator) processFile(path string, info os.FileInfo, tracker *progress.Tracker) {
+	if tracker != nil && tracker.IsProcessed(path) {
+		if d.resumeMode {
+			if d.verbose {
+				logger.Get().Debug().Msgf("%s 跳过已处理文件: %s", d.position(), path)
 			}
+			d.stats.TotalProcessed++
+			return
+		}
+	}
+
+	// 仍在写入的文件不标记为已处理，下次扫描时会重新处理
+	if err := d.stability.Check(path, info); err != nil {
+		if errors.Is(err, stability.ErrRecent) {
+			d.deferred.Add(path, info)
+			return
+		}
+		d.skipBusy(path, err)
+		return
+	}
+
+	d.processStable(path, info, tracker)
+}
+
+// recheckDeferred 在本轮处理结束后统一复查最近被修改的文件，稳定的文件按正常流程处理
+func (d *Deduplicator) recheckDeferred() {
+	d.stability.Recheck(&d.deferred, func(path string, info os.FileInfo, err error) {
+		if err != nil {
+			d.skipBusy(path, err)
+			return
+		}
+		d.processStable(path, info, d.trackerFor(path))
+	})
+}
+
+func (d *Deduplicator) skipBusy(path string, err error) {
+	d.stats.Busy++
+	logger.Get().Warn().Err(err).Msgf("%s 跳过正在写入的文件: %s", d.position(), path)
+}
+
+// processStable 处理已确认写入完成的文件
+func (d *Deduplicator) processStable(path string, info os.FileInfo, tracker *progress.Tracker) {
+	d.trackHardLink(path, info)
+
+	hash, err := hasher.CalculateHash(path)
+	if err != nil {
+		logger.Get().Error().Err(err).Msgf("处理文件失败: %s", path)
+		d.walker.Errors.Add(path, scanner.OpRead, err)
+		return
+	}
 
-			if exists {
-				logger.Get().Debug().Msgf("File is duplicate (hash exists): %s", path)
-				d.handleDuplicate(path, info, hashStr)
+	hashStr := fmt.Sprintf("%016x", hash)
+	logger.Get().Debug().Msgf("File hash: %s = %s", path, hashStr)
+
+	exists, err := d.db.Exists(hashStr)
+	if err != nil {
+		logger.Get().Error().Err(err).Msgf("查询数据库失败: %s", path)
+		return
+	}
+
+	var record *internal.FileRecord
+	if exists {
+		record, err = d.db.Lookup(hashStr)
+		if err != nil {
+			logger.Get().Error().Err(err).Msgf("查询记录失败: %s", path)
+			return
+		}
+	}
+
+	if record != nil && isSamePath(record.FilePath, path) {
+		d.stats.AlreadyIndexed++
+		logger.Get().Info().Msgf("%s 已记录: %s (%s)",
+			d.position(), path, internal.FormatBytes(info.Size()))
+	} else if record != nil && isSameInode(record.FilePath, info) {
+		d.stats.HardLinked++
+		logger.Get().Info().Msgf("%s 跳过硬链接: %s (与 %s 为同一文件)",
+			d.position(), path, record.FilePath)
+	} else if exists {
+		logger.Get().Debug().Msgf("File is duplicate (hash exists): %s", path)
+		d.handleDuplicate(path, info, hashStr)
+	} else {
+		logger.Get().Debug().Msgf("File is new (hash not in DB): %s", path)
+		record := &internal.FileRecord{
+			Hash:      hashStr,
+			FilePath:  path,
+			FileSize:  info.Size(),
+			CreatedAt: time.Now().Unix(),
+			Metadata:  readMetadata(path),
+		}
+		if err := d.db.Insert(record); err == nil {
+			d.stats.Added++
+			if d.verbose {
+				logger.Get().Info().Msgf("%s 新增记录: %s (%s, 哈希: %s)",
+					d.position(), path, internal.FormatBytes(info.Size()), hashStr)
 			} else {
-				logger.Get().Debug().Msgf("File is new (hash not in DB): %s", path)
-				record := &internal.FileRecord{
-					Hash:      hashStr,
-					FilePath:  path,
-					FileSize:  info.Size(),
-					CreatedAt: time.Now().Unix(),
-				}
-				if err := d.db.Insert(record); err == nil {
-					d.stats.Added++
-					if d.verbose {
-						logger.Get().Info().Msgf("[%d/%d] 新增记录: %s (%s, 哈希: %s)",
-							d.stats.TotalProcessed+1, d.totalFiles, path, formatBytes(info.Size()), hashStr)
-					} else {
-						logger.Get().Info().Msgf("[%d/%d] 新增记录: %s (%s)",
-							d.stats.TotalProcessed+1, d.totalFiles, path, formatBytes(info.Size()))
-					}
-				}
+				logger.Get().Info().Msgf("%s 新增记录: %s (%s)",
+					d.position(), path, internal.FormatBytes(info.Size()))
 			}
+		}
+	}
 
-			if err := tracker.MarkProcessed(path); err != nil {
-				logger.Get().Error().Err(err).Msgf("标记文件已处理失败: %s", path)
-			}
+	if tracker != nil {
+		d.markProcessed(tracker, path)
+	}
 
-			d.stats.TotalProcessed++
-			return nil
-		})
+	d.stats.TotalProcessed++
+}
+
+// markProcessed 标记文件已处理。数据库缓冲写入时先记下标记，等记录写入数据库后再写入进度文件，
+// 避免进程崩溃后恢复时跳过哈希尚未写入数据库的文件
+func (d *Deduplicator) markProcessed(tracker *progress.Tracker, path string) {
+	if _, ok := d.db.(database.Flusher); !ok {
+		markTracker(tracker, path)
+		return
+	}
+
+	d.stateMu.Lock()
+	defer d.stateMu.Unlock()
+
+	d.unmarked = append(d.unmarked, progressMark{tracker: tracker, path: path})
+	if len(d.unmarked) >= progressBatchSize {
+		d.commitProgress()
+	}
+}
+
+// commitProgress 将数据库中缓冲的记录写入后，再把对应文件标记为已处理，调用方需持有 stateMu
+func (d *Deduplicator) commitProgress() {
+	if len(d.unmarked) == 0 {
+		return
+	}
+	if flusher, ok := d.db.(database.Flusher); ok {
+		if err := flusher.Flush(); err != nil {
+			logger.Get().Error().Err(err).Msg("保存数据库记录失败，暂不更新进度")
+			return
+		}
+	}
+
+	for _, mark := range d.unmarked {
+		markTracker(mark.tracker, mark.path)
+	}
+	d.unmarked = nil
+}
+
+func markTracker(tracker *progress.Tracker, path string) {
+	if err := tracker.MarkProcessed(path); err != nil {
+		logger.Get().Error().Err(err).Msgf("标记文件已处理失败: %s", path)
+	}
+}
+
+// position 返回日志中的处理进度前缀，流式模式下发现尚未结束时总数后带 "+"，
+// 不统计总数时显示 "?"
+func (d *Deduplicator) position() string {
+	return fmt.Sprintf("[%d/%s]", d.stats.TotalProcessed+1, d.totalString())
+}
+
+func (d *Deduplicator) totalString() string {
+	total := d.totalFiles.Load()
+	if total < 0 {
+		return "?"
+	}
+	if d.discovering.Load() {
+		return fmt.Sprintf("%d+", total)
+	}
+	return fmt.Sprintf("%d", total)
+}
+
+// trackHardLink 记录有多个硬链接的文件，用于在结束时报告扫描范围内已互为硬链接的文件组
+// 链接数在遍历到文件时才读取，同组中较早的链接可能已在本次运行中被删除，
+// 因此 inode 第一次出现时链接数大于 1 即记录，之后同一 inode 的文件不再检查链接数
+func (d *Deduplicator) trackHardLink(path string, info os.FileInfo) {
+	id, ok := scanner.GetFileID(info)
+	if !ok {
+		return
+	}
+	if _, seen := d.linkSets[id]; !seen && scanner.LinkCount(info) <= 1 {
+		return
+	}
+	d.linkSets[id] = append(d.linkSets[id], path)
+}
+
+func (d *Deduplicator) collectHardLinkSets() {
+	for _, paths := range d.linkSets {
+		if len(paths) > 1 {
+			d.stats.HardLinkSets = append(d.stats.HardLinkSets, paths)
+		}
+	}
+
+	sort.Slice(d.stats.HardLinkSets, func(i, j int) bool {
+		return d.stats.HardLinkSets[i][0] < d.stats.HardLinkSets[j][0]
+	})
+
+	for i, paths := range d.stats.HardLinkSets {
+		logger.Get().Info().Msgf("硬链接组 [%d]: %s", i+1, strings.Join(paths, ", "))
 	}
 }
 
 func (d *Deduplicator) handleDuplicate(path string, info os.FileInfo, hashStr string) {
 	switch d.mode {
 	case internal.ModeDelete:
+		// 删除符号链接不会释放目标文件的空间
+		symlink := false
+		if current, err := os.Lstat(path); err == nil {
+			symlink = current.Mode()&os.ModeSymlink != 0
+		}
+
 		if err := os.Remove(path); err == nil {
 			d.stats.Deleted++
-			d.stats.FreedSpace += info.Size()
+			if !symlink && d.isLastLink(info) {
+				d.stats.FreedSpace += info.Size()
+			}
 			if d.verbose {
-				logger.Get().Info().Msgf("[%d/%d] 发现重复: %s (%s, 已删除, 哈希: %s)",
-					d.stats.TotalProcessed+1, d.totalFiles, path, formatBytes(info.Size()), hashStr)
+				logger.Get().Info().Msgf("%s 发现重复: %s (%s, 已删除, 哈希: %s)",
+					d.position(), path, internal.FormatBytes(info.Size()), hashStr)
 			} else {
-				logger.Get().Info().Msgf("[%d/%d] 发现重复: %s (%s, 已删除)",
-					d.stats.TotalProcessed+1, d.totalFiles, path, formatBytes(info.Size()))
+				logger.Get().Info().Msgf("%s 发现重复: %s (%s, 已删除)",
+					d.position(), path, internal.FormatBytes(info.Size()))
 			}
 		} else {
 			logger.Get().Error().Err(err).Msgf("删除文件失败: %s", path)
+			d.walker.Errors.Add(path, scanner.OpRemove, err)
 		}
 	case internal.ModeMove:
 		if err := d.moveFile(path, hashStr); err == nil {
@@ -214,27 +625,43 @@ func (d *Deduplicator) handleDuplicate(path string, info os.FileInfo, hashStr st
 			dstPath := d.buildDstPath(path, hashStr)
 			if strings.Contains(filepath.Base(dstPath), "_") && !strings.HasPrefix(filepath.Base(dstPath), hashStr[:8]+"_"+hashStr[8:]) {
 				if d.verbose {
-					logger.Get().Info().Msgf("[%d/%d] 发现重复: %s (%s, 已移动到 %s [重命名], 哈希: %s)",
-						d.stats.TotalProcessed+1, d.totalFiles, path, formatBytes(info.Size()), dstPath, hashStr)
+					logger.Get().Info().Msgf("%s 发现重复: %s (%s, 已移动到 %s [重命名], 哈希: %s)",
+						d.position(), path, internal.FormatBytes(info.Size()), dstPath, hashStr)
 				} else {
-					logger.Get().Info().Msgf("[%d/%d] 发现重复: %s (%s, 已移动到 %s [重命名])",
-						d.stats.TotalProcessed+1, d.totalFiles, path, formatBytes(info.Size()), dstPath)
+					logger.Get().Info().Msgf("%s 发现重复: %s (%s, 已移动到 %s [重命名])",
+						d.position(), path, internal.FormatBytes(info.Size()), dstPath)
 				}
 			} else {
 				if d.verbose {
-					logger.Get().Info().Msgf("[%d/%d] 发现重复: %s (%s, 已移动到 %s, 哈希: %s)",
-						d.stats.TotalProcessed+1, d.totalFiles, path, formatBytes(info.Size()), dstPath, hashStr)
+					logger.Get().Info().Msgf("%s 发现重复: %s (%s, 已移动到 %s, 哈希: %s)",
+						d.position(), path, internal.FormatBytes(info.Size()), dstPath, hashStr)
 				} else {
-					logger.Get().Info().Msgf("[%d/%d] 发现重复: %s (%s, 已移动到 %s)",
-						d.stats.TotalProcessed+1, d.totalFiles, path, formatBytes(info.Size()), dstPath)
+					logger.Get().Info().Msgf("%s 发现重复: %s (%s, 已移动到 %s)",
+						d.position(), path, internal.FormatBytes(info.Size()), dstPath)
 				}
 			}
 		} else {
 			logger.Get().Error().Err(err).Msgf("移动文件失败: %s", path)
+			d.walker.Errors.Add(path, scanner.OpMove, err)
 		}
 	}
 }
 
+// isLastLink 记录删除了文件的一个硬链接，返回是否已删除该 inode 的全部链接。
+// 只有删除最后一个链接时才真正释放空间，扫描范围外还有链接时不计入释放空间
+func (d *Deduplicator) isLastLink(info os.FileInfo) bool {
+	links := scanner.LinkCount(info)
+	if links <= 1 {
+		return true
+	}
+	id, ok := scanner.GetFileID(info)
+	if !ok {
+		return false
+	}
+	d.removedLinks[id]++
+	return d.removedLinks[id] >= links
+}
+
 func (d *Deduplicator) moveFile(srcPath, hash string) error {
 	if d.targetDir == "" {
 		return fmt.Errorf("target directory not specified")
@@ -297,19 +724,6 @@ func (d *Deduplicator) buildDstPath(srcPath, hash string) string {
 	return dstPath
 }
 
-func formatBytes(bytes int64) string {
-	const unit = 1024
-	if bytes < unit {
-		return fmt.Sprintf("%d B", bytes)
-	}
-	div, exp := int64(unit), 0
-	for n := bytes / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
-	}
-	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
-}
-
 func (d *Deduplicator) Progress() <-chan internal.ProgressUpdate {
 	return d.progressChan
 }
@@ -317,16 +731,17 @@ func (d *Deduplicator) Progress() <-chan internal.ProgressUpdate {
 func (d *Deduplicator) HandleInterrupt() {
 	logger.Get().Warn().Msg("收到中断信号，正在保存状态...")
 
-	for rootDir, tracker := range d.trackers {
-		if err := tracker.Flush(); err != nil {
-			logger.Get().Error().Err(err).Msgf("刷新进度文件失败: %s", rootDir)
-		} else {
-			logger.Get().Info().Msgf("进度文件已保存: %s (已处理 %d 个文件)",
-				rootDir, tracker.GetProcessedCount())
+	d.flushTrackers()
+
+	if flusher, ok := d.db.(database.Flusher); ok {
+		if err := flusher.Flush(); err != nil {
+			logger.Get().Error().Err(err).Msg("保存数据库记录失败")
 		}
 	}
 
-	logger.Get().Warn().Msgf("中断处理完成，已处理: %d/%d 个文件", d.stats.TotalProcessed, d.totalFiles)
+	d.releaseLocks()
+
+	logger.Get().Warn().Msgf("中断处理完成，已处理: %d/%s 个文件", d.stats.TotalProcessed, d.totalString())
 }
 
 func getRootDir(dir string) string {
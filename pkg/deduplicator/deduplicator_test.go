@@ -1,14 +1,19 @@
 package deduplicator
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 
 	"github.com/moyu-x/classified-file/pkg/database"
 	"github.com/moyu-x/classified-file/pkg/hasher"
+	"github.com/moyu-x/classified-file/pkg/lock"
+	"github.com/moyu-x/classified-file/pkg/progress"
+	"github.com/moyu-x/classified-file/pkg/stability"
 	"github.com/moyu-x/classified-file/internal"
 )
 
@@ -827,3 +832,546 @@ func TestDeduplicator_Process_Statistics(t *testing.T) {
 		t.Error("Expected EndTime to be set")
 	}
 }
+
+func TestDeduplicator_Process_RootLocked(t *testing.T) {
+	tempDir := t.TempDir()
+	testFilesDir := filepath.Join(tempDir, "files")
+
+	if err := os.MkdirAll(testFilesDir, 0755); err != nil {
+		t.Fatalf("Failed to create test files directory: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(testFilesDir, "file.txt"), []byte("content"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	held, err := lock.Acquire(filepath.Join(tempDir, progress.LockFileName), false)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	d := NewDeduplicator(database.NewMemoryStore(), internal.ModeDelete, "", false)
+
+	_, err = d.Process([]string{testFilesDir}, false, false)
+	var lockedErr *lock.LockedError
+	if !errors.As(err, &lockedErr) {
+		t.Fatalf("Expected LockedError, got %v", err)
+	}
+
+	if err := held.Release(); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+
+	stats, err := d.Process([]string{testFilesDir}, false, false)
+	if err != nil {
+		t.Fatalf("Process() after release error = %v", err)
+	}
+
+	if stats.Added != 1 {
+		t.Errorf("Expected 1 file added, got %d", stats.Added)
+	}
+
+	if _, err := os.Stat(filepath.Join(tempDir, progress.LockFileName)); !os.IsNotExist(err) {
+		t.Error("Expected progress lock to be released after Process")
+	}
+}
+
+func TestDeduplicator_Process_SecondRootLocked(t *testing.T) {
+	tempDir := t.TempDir()
+	first := filepath.Join(tempDir, "first", "files")
+	second := filepath.Join(tempDir, "second", "files")
+	for _, dir := range []string{first, second} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+
+	// 第一个根目录有上次中断留下的进度
+	progressFile := filepath.Join(tempDir, "first", progress.ProgressFileName)
+	if err := os.WriteFile(progressFile, []byte("/previous/file\n"), 0644); err != nil {
+		t.Fatalf("Failed to create progress file: %v", err)
+	}
+
+	held, err := lock.Acquire(filepath.Join(tempDir, "second", progress.LockFileName), false)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	defer held.Release()
+
+	d := NewDeduplicator(database.NewMemoryStore(), internal.ModeDelete, "", false)
+	_, err = d.Process([]string{first, second}, true, false)
+	var lockedErr *lock.LockedError
+	if !errors.As(err, &lockedErr) {
+		t.Fatalf("Expected LockedError, got %v", err)
+	}
+
+	if len(d.trackers) != 0 {
+		t.Errorf("Expected trackers to be closed, got %d open", len(d.trackers))
+	}
+	data, err := os.ReadFile(progressFile)
+	if err != nil || string(data) != "/previous/file\n" {
+		t.Errorf("Expected progress of first root to be kept, got %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "first", progress.LockFileName)); !os.IsNotExist(err) {
+		t.Error("Expected lock of first root to be released")
+	}
+}
+
+func TestDeduplicator_InterruptDuringProcess(t *testing.T) {
+	tempDir := t.TempDir()
+	var dirs []string
+	for i := 0; i < 20; i++ {
+		dir := filepath.Join(tempDir, fmt.Sprintf("root%d", i), "files")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte(dir), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+		dirs = append(dirs, dir)
+	}
+
+	d := NewDeduplicator(database.NewMemoryStore(), internal.ModeDelete, "", false)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.Process(dirs, false, false)
+	}()
+
+	// 中断处理与 Process 并发访问进度跟踪器和锁，使用 -race 运行时不应报告数据竞争
+	for {
+		select {
+		case <-done:
+			return
+		default:
+			d.flushTrackers()
+			d.releaseLocks()
+		}
+	}
+}
+
+func TestDeduplicator_Process_HardLinks(t *testing.T) {
+	tempDir := t.TempDir()
+	testFilesDir := filepath.Join(tempDir, "files")
+
+	if err := os.MkdirAll(testFilesDir, 0755); err != nil {
+		t.Fatalf("Failed to create test files directory: %v", err)
+	}
+
+	content := []byte("linked content")
+	original := filepath.Join(testFilesDir, "a.txt")
+	if err := os.WriteFile(original, content, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	link := filepath.Join(testFilesDir, "b.txt")
+	if err := os.Link(original, link); err != nil {
+		t.Skipf("Skipping hard link test: %v", err)
+	}
+
+	copied := filepath.Join(testFilesDir, "c.txt")
+	if err := os.WriteFile(copied, content, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	d := NewDeduplicator(database.NewMemoryStore(), internal.ModeDelete, "", false)
+
+	stats, err := d.Process([]string{testFilesDir}, false, false)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if stats.Added != 1 {
+		t.Errorf("Expected 1 file added, got %d", stats.Added)
+	}
+
+	if stats.HardLinked != 1 {
+		t.Errorf("Expected 1 hard link skipped, got %d", stats.HardLinked)
+	}
+
+	if stats.Deleted != 1 {
+		t.Errorf("Expected 1 file deleted, got %d", stats.Deleted)
+	}
+
+	if stats.FreedSpace != int64(len(content)) {
+		t.Errorf("Expected freed space %d, got %d", len(content), stats.FreedSpace)
+	}
+
+	if len(stats.HardLinkSets) != 1 || len(stats.HardLinkSets[0]) != 2 {
+		t.Errorf("Expected 1 hard link set of 2 files, got %v", stats.HardLinkSets)
+	}
+
+	for _, file := range []string{original, link} {
+		if _, err := os.Stat(file); err != nil {
+			t.Errorf("Expected %s to be kept: %v", file, err)
+		}
+	}
+
+	if _, err := os.Stat(copied); !os.IsNotExist(err) {
+		t.Error("Expected copied duplicate to be deleted")
+	}
+}
+
+func TestDeduplicator_Process_HardLinkedDuplicates(t *testing.T) {
+	tempDir := t.TempDir()
+	testFilesDir := filepath.Join(tempDir, "files")
+
+	if err := os.MkdirAll(testFilesDir, 0755); err != nil {
+		t.Fatalf("Failed to create test files directory: %v", err)
+	}
+
+	content := []byte("duplicate content")
+	first := filepath.Join(testFilesDir, "a.txt")
+	if err := os.WriteFile(first, content, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	second := filepath.Join(testFilesDir, "b.txt")
+	if err := os.Link(first, second); err != nil {
+		t.Skipf("Skipping hard link test: %v", err)
+	}
+
+	hash, err := hasher.CalculateHash(first)
+	if err != nil {
+		t.Fatalf("CalculateHash() error = %v", err)
+	}
+
+	store := database.NewMemoryStore()
+	record := &internal.FileRecord{
+		Hash:      fmt.Sprintf("%016x", hash),
+		FilePath:  "/some/other/path/a.txt",
+		FileSize:  int64(len(content)),
+		CreatedAt: time.Now().Unix(),
+	}
+	if err := store.Insert(record); err != nil {
+		t.Fatalf("Failed to insert record: %v", err)
+	}
+
+	d := NewDeduplicator(store, internal.ModeDelete, "", false)
+
+	stats, err := d.Process([]string{testFilesDir}, false, false)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	// 两个链接都是数据库中其他文件的重复，删除第二个链接时才释放空间
+	if stats.Deleted != 2 {
+		t.Errorf("Expected 2 files deleted, got %d", stats.Deleted)
+	}
+
+	if stats.HardLinked != 0 {
+		t.Errorf("Expected no hard links skipped, got %d", stats.HardLinked)
+	}
+
+	if stats.FreedSpace != int64(len(content)) {
+		t.Errorf("Expected %d bytes freed, got %d", len(content), stats.FreedSpace)
+	}
+
+	// 处理 b.txt 时 a.txt 已被删除，链接数只剩 1，仍然属于同一个硬链接组
+	if len(stats.HardLinkSets) != 1 || !reflect.DeepEqual(stats.HardLinkSets[0], []string{first, second}) {
+		t.Errorf("Expected hard link set [%s %s], got %v", first, second, stats.HardLinkSets)
+	}
+}
+
+func TestDeduplicator_Process_DuplicateLinkedOutsideRoot(t *testing.T) {
+	tempDir := t.TempDir()
+	testFilesDir := filepath.Join(tempDir, "files")
+
+	if err := os.MkdirAll(testFilesDir, 0755); err != nil {
+		t.Fatalf("Failed to create test files directory: %v", err)
+	}
+
+	content := []byte("duplicate content")
+	first := filepath.Join(testFilesDir, "a.txt")
+	second := filepath.Join(testFilesDir, "b.txt")
+	for _, path := range []string{first, second} {
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+
+	// b.txt 在扫描范围外还有一个链接，删除它不会释放空间
+	outside := filepath.Join(tempDir, "outside.txt")
+	if err := os.Link(second, outside); err != nil {
+		t.Skipf("Skipping hard link test: %v", err)
+	}
+
+	d := NewDeduplicator(database.NewMemoryStore(), internal.ModeDelete, "", false)
+
+	stats, err := d.Process([]string{testFilesDir}, false, false)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if stats.Deleted != 1 || stats.HardLinked != 0 {
+		t.Errorf("Expected 1 deleted and no hard links skipped, got Deleted=%d HardLinked=%d", stats.Deleted, stats.HardLinked)
+	}
+
+	if stats.FreedSpace != 0 {
+		t.Errorf("Expected no freed space, got %d", stats.FreedSpace)
+	}
+
+	if _, err := os.Stat(second); !os.IsNotExist(err) {
+		t.Error("Expected duplicate with an outside link to be deleted")
+	}
+	if _, err := os.Stat(outside); err != nil {
+		t.Errorf("Expected outside link to be kept: %v", err)
+	}
+}
+
+func TestDeduplicator_Process_CountModes(t *testing.T) {
+	modes := []internal.CountMode{internal.CountUpfront, internal.CountStream, internal.CountNone}
+
+	for _, mode := range modes {
+		t.Run(string(mode), func(t *testing.T) {
+			testFilesDir := filepath.Join(t.TempDir(), "files")
+			if err := os.MkdirAll(filepath.Join(testFilesDir, "sub"), 0755); err != nil {
+				t.Fatalf("Failed to create test files directory: %v", err)
+			}
+
+			files := map[string]string{
+				"a.txt":     "same",
+				"b.txt":     "same",
+				"c.txt":     "unique",
+				"sub/d.txt": "same",
+				"sub/e.txt": "other",
+			}
+			for name, content := range files {
+				if err := os.WriteFile(filepath.Join(testFilesDir, name), []byte(content), 0644); err != nil {
+					t.Fatalf("Failed to create file: %v", err)
+				}
+			}
+
+			d := NewDeduplicator(database.NewMemoryStore(), internal.ModeDelete, "", false)
+			d.SetCountMode(mode)
+
+			stats, err := d.Process([]string{testFilesDir}, false, false)
+			if err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+
+			if stats.TotalProcessed != len(files) {
+				t.Errorf("Expected %d files processed, got %d", len(files), stats.TotalProcessed)
+			}
+
+			if stats.Added != 3 {
+				t.Errorf("Expected 3 files added, got %d", stats.Added)
+			}
+
+			if stats.Deleted != 2 {
+				t.Errorf("Expected 2 files deleted, got %d", stats.Deleted)
+			}
+
+			wantTotal := fmt.Sprintf("%d", len(files))
+			if mode == internal.CountNone {
+				wantTotal = "?"
+			}
+			if got := d.totalString(); got != wantTotal {
+				t.Errorf("totalString() = %q, want %q", got, wantTotal)
+			}
+		})
+	}
+}
+
+func TestDeduplicator_position(t *testing.T) {
+	d := NewDeduplicator(database.NewMemoryStore(), internal.ModeDelete, "", false)
+	d.stats.TotalProcessed = 4
+	d.totalFiles.Store(10)
+
+	if got := d.position(); got != "[5/10]" {
+		t.Errorf("position() = %q, want %q", got, "[5/10]")
+	}
+
+	d.discovering.Store(true)
+	if got := d.position(); got != "[5/10+]" {
+		t.Errorf("position() while discovering = %q, want %q", got, "[5/10+]")
+	}
+
+	d.discovering.Store(false)
+	d.totalFiles.Store(-1)
+	if got := d.position(); got != "[5/?]" {
+		t.Errorf("position() without count = %q, want %q", got, "[5/?]")
+	}
+}
+
+func TestDeduplicator_ProcessFiles(t *testing.T) {
+	tempDir := t.TempDir()
+
+	a := filepath.Join(tempDir, "a.txt")
+	b := filepath.Join(tempDir, "sub", "b.txt")
+	unlisted := filepath.Join(tempDir, "sub", "c.txt")
+	if err := os.MkdirAll(filepath.Dir(b), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	for _, file := range []string{a, b, unlisted} {
+		if err := os.WriteFile(file, []byte("same content"), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+
+	d := NewDeduplicator(database.NewMemoryStore(), internal.ModeDelete, "", false)
+
+	stats, err := d.ProcessFiles([]string{a, filepath.Join(tempDir, "sub"), b})
+	if err != nil {
+		t.Fatalf("ProcessFiles() error = %v", err)
+	}
+
+	if stats.TotalProcessed != 2 {
+		t.Errorf("Expected 2 files processed, got %d", stats.TotalProcessed)
+	}
+
+	if stats.Deleted != 1 {
+		t.Errorf("Expected 1 file deleted, got %d", stats.Deleted)
+	}
+
+	if _, err := os.Stat(b); !os.IsNotExist(err) {
+		t.Errorf("Expected listed duplicate %s to be deleted", b)
+	}
+
+	if _, err := os.Stat(unlisted); err != nil {
+		t.Errorf("Expected unlisted file to be kept: %v", err)
+	}
+
+	if progress.Exists(tempDir) || progress.Exists(filepath.Dir(tempDir)) {
+		t.Error("Expected no progress file when processing a file list")
+	}
+}
+
+func TestDeduplicator_Process_SkipsBusyFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	data := filepath.Join(tempDir, "data")
+	writeTree(t, data, map[string]string{
+		"a.bin":        "same",
+		"download.bin": "same",
+	})
+
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(data, "a.bin"), past, past); err != nil {
+		t.Fatalf("Failed to set times: %v", err)
+	}
+
+	// 模拟仍在下载的文件：检查等待期间继续写入
+	download := filepath.Join(data, "download.bin")
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			case <-time.After(20 * time.Millisecond):
+				os.WriteFile(download, []byte(fmt.Sprintf("same%d", i)), 0644)
+			}
+		}
+	}()
+
+	d := NewDeduplicator(database.NewMemoryStore(), internal.ModeDelete, "", false)
+	d.SetStability(&stability.Checker{Interval: 200 * time.Millisecond})
+
+	stats, err := d.Process([]string{data}, false, false)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if stats.Busy != 1 {
+		t.Errorf("Expected 1 busy file, got %d", stats.Busy)
+	}
+	if stats.Deleted != 0 {
+		t.Errorf("Busy file should not be deleted, got %d deleted", stats.Deleted)
+	}
+	if _, err := os.Stat(download); err != nil {
+		t.Errorf("Busy file should be kept: %v", err)
+	}
+}
+
+func TestDeduplicator_Process_DefersRecentFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	data := filepath.Join(tempDir, "data")
+	writeTree(t, data, map[string]string{
+		"old.bin":    "old",
+		"new1.bin":   "new1",
+		"new2.bin":   "new2",
+		"new3.bin":   "new3",
+		"copy/1.bin": "new1",
+	})
+
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(data, "old.bin"), past, past); err != nil {
+		t.Fatalf("Failed to set times: %v", err)
+	}
+
+	interval := 300 * time.Millisecond
+	d := NewDeduplicator(database.NewMemoryStore(), internal.ModeDelete, "", false)
+	d.SetStability(&stability.Checker{Interval: interval})
+
+	start := time.Now()
+	stats, err := d.Process([]string{data}, false, false)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	elapsed := time.Since(start)
+
+	if stats.Busy != 0 {
+		t.Errorf("Expected no busy files, got %d", stats.Busy)
+	}
+	if stats.Added != 4 || stats.Deleted != 1 || stats.TotalProcessed != 5 {
+		t.Errorf("Expected 4 added, 1 deleted, 5 processed, got %d added, %d deleted, %d processed",
+			stats.Added, stats.Deleted, stats.TotalProcessed)
+	}
+	// 最近被修改的文件在本轮结束后统一等待一次
+	if elapsed >= 2*interval {
+		t.Errorf("Expected a single wait for recent files, took %v", elapsed)
+	}
+}
+
+// flushingStore 模拟缓冲写入的数据库，记录 Flush 的调用次数
+type flushingStore struct {
+	*database.MemoryStore
+	flushes int
+}
+
+func (s *flushingStore) Flush() error {
+	s.flushes++
+	return nil
+}
+
+func TestDeduplicator_ProgressWaitsForFlush(t *testing.T) {
+	tempDir := t.TempDir()
+	testFilesDir := filepath.Join(tempDir, "files")
+	if err := os.MkdirAll(testFilesDir, 0755); err != nil {
+		t.Fatalf("Failed to create test files directory: %v", err)
+	}
+	file := filepath.Join(testFilesDir, "a.txt")
+	if err := os.WriteFile(file, []byte("content"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	tracker, err := progress.NewTracker(testFilesDir)
+	if err != nil {
+		t.Fatalf("NewTracker() error = %v", err)
+	}
+	defer tracker.Release()
+
+	store := &flushingStore{MemoryStore: database.NewMemoryStore()}
+	d := NewDeduplicator(store, internal.ModeDelete, "", false)
+	d.trackers[testFilesDir] = tracker
+
+	info, err := os.Stat(file)
+	if err != nil {
+		t.Fatalf("Failed to stat file: %v", err)
+	}
+	d.processFile(file, info, tracker)
+
+	// 记录仍在数据库缓冲中时不能标记为已处理
+	if tracker.IsProcessed(file) {
+		t.Errorf("File should not be marked processed before the store is flushed")
+	}
+
+	d.flushTrackers()
+	if store.flushes != 1 {
+		t.Errorf("Expected store to be flushed once, got %d", store.flushes)
+	}
+	if !tracker.IsProcessed(file) {
+		t.Errorf("File should be marked processed after the store is flushed")
+	}
+}
@@ -0,0 +1,519 @@
+package deduplicator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/moyu-x/classified-file/internal"
+	"github.com/moyu-x/classified-file/pkg/database"
+	"github.com/moyu-x/classified-file/pkg/hasher"
+	"github.com/moyu-x/classified-file/pkg/logger"
+	"github.com/moyu-x/classified-file/pkg/scanner"
+)
+
+// SetDirMode 设置目录级去重模式，为空时不比较目录
+func (d *Deduplicator) SetDirMode(mode internal.DirMode) {
+	d.dirMode = mode
+}
+
+type dirFile struct {
+	hash  uint64
+	size  int64
+	links uint64
+}
+
+// dirNode 是目录树中的一个目录，只包含扫描到文件的目录
+type dirNode struct {
+	path     string
+	parent   *dirNode
+	files    map[string]dirFile
+	children map[string]*dirNode
+	// incomplete 为 true 表示子树中有文件读取失败，该目录不参与比较
+	incomplete bool
+
+	// hash 按子项名称和内容计算，contentHash 只按内容计算
+	hash        uint64
+	contentHash uint64
+	fileCount   int
+	size        int64
+	// contents 是子树中每个文件哈希出现的次数
+	contents map[uint64]int
+}
+
+func newDirNode(path string, parent *dirNode) *dirNode {
+	return &dirNode{
+		path:     path,
+		parent:   parent,
+		files:    make(map[string]dirFile),
+		children: make(map[string]*dirNode),
+	}
+}
+
+// lookup 返回相对路径 rel 对应的子目录，不存在时创建
+func (n *dirNode) lookup(rel string) *dirNode {
+	node := n
+	if rel == "." {
+		return node
+	}
+	for _, name := range strings.Split(rel, string(filepath.Separator)) {
+		child, ok := node.children[name]
+		if !ok {
+			child = newDirNode(filepath.Join(node.path, name), node)
+			node.children[name] = child
+		}
+		node = child
+	}
+	return node
+}
+
+// compute 自底向上计算目录哈希：
+// hash 是排序后的 (类型, 名称, 子项哈希) 序列的哈希，contentHash 是排序后的子项内容哈希序列的哈希
+func (n *dirNode) compute() {
+	n.contents = make(map[uint64]int)
+
+	var named, unnamed []string
+	for name, file := range n.files {
+		named = append(named, fmt.Sprintf("f\x00%s\x00%016x", name, file.hash))
+		unnamed = append(unnamed, fmt.Sprintf("f%016x", file.hash))
+		n.contents[file.hash]++
+		n.fileCount++
+		n.size += file.size
+	}
+	for name, child := range n.children {
+		child.compute()
+		named = append(named, fmt.Sprintf("d\x00%s\x00%016x", name, child.hash))
+		unnamed = append(unnamed, fmt.Sprintf("d%016x", child.contentHash))
+		for hash, count := range child.contents {
+			n.contents[hash] += count
+		}
+		n.fileCount += child.fileCount
+		n.size += child.size
+		n.incomplete = n.incomplete || child.incomplete
+	}
+
+	sort.Strings(named)
+	sort.Strings(unnamed)
+	n.hash = xxhash.Sum64String(strings.Join(named, "\n"))
+	n.contentHash = xxhash.Sum64String(strings.Join(unnamed, "\n"))
+}
+
+// eligible 判断目录是否参与比较
+func (n *dirNode) eligible() bool {
+	return n != nil && n.fileCount > 0 && !n.incomplete
+}
+
+// contains 判断 n 的子树是否包含 other 子树中的全部文件内容（按出现次数）
+func (n *dirNode) contains(other *dirNode) bool {
+	if n.fileCount < other.fileCount {
+		return false
+	}
+	for hash, count := range other.contents {
+		if n.contents[hash] < count {
+			return false
+		}
+	}
+	return true
+}
+
+// isAncestorOf 判断 n 是否为 other 的祖先目录
+func (n *dirNode) isAncestorOf(other *dirNode) bool {
+	for p := other.parent; p != nil; p = p.parent {
+		if p == n {
+			return true
+		}
+	}
+	return false
+}
+
+func related(a, b *dirNode) bool {
+	return a == b || a.isAncestorOf(b) || b.isAncestorOf(a)
+}
+
+// freedSpace 返回删除子树后实际释放的空间，仍有其他硬链接的文件不计入
+func (n *dirNode) freedSpace() int64 {
+	var freed int64
+	for _, file := range n.files {
+		if file.links <= 1 {
+			freed += file.size
+		}
+	}
+	for _, child := range n.children {
+		freed += child.freedSpace()
+	}
+	return freed
+}
+
+func (n *dirNode) walk(fn func(node *dirNode)) {
+	fn(n)
+	for _, child := range n.children {
+		child.walk(fn)
+	}
+}
+
+// buildDirTree 遍历并哈希目录中的所有文件，返回计算好哈希的目录树
+func (d *Deduplicator) buildDirTree(walker *scanner.FileWalker, dir string) *dirNode {
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		return nil
+	}
+
+	root := newDirNode(dir, nil)
+	walker.Walk(dir, func(path string, info os.FileInfo) error {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			return nil
+		}
+
+		node := root.lookup(filepath.Dir(rel))
+		// 包含正在写入的文件的目录不能整体处理
+		if d.dirMode == internal.DirsApply {
+			if err := d.stability.Check(path, info); err != nil {
+				logger.Get().Warn().Err(err).Msgf("跳过正在写入的文件: %s", path)
+				node.incomplete = true
+				return nil
+			}
+		}
+
+		hash, err := hasher.CalculateHash(path)
+		if err != nil {
+			logger.Get().Error().Err(err).Msgf("处理文件失败: %s", path)
+			d.walker.Errors.Add(path, scanner.OpRead, err)
+			node.incomplete = true
+			return nil
+		}
+		node.files[filepath.Base(rel)] = dirFile{hash: hash, size: info.Size(), links: scanner.LinkCount(info)}
+		return nil
+	})
+
+	root.compute()
+	return root
+}
+
+// analyzeDirs 比较扫描目录中所有子目录的内容，报告重复目录和包含关系，
+// apply 模式下按操作模式处理完全相同的目录
+func (d *Deduplicator) analyzeDirs(walker *scanner.FileWalker, dirs []string) {
+	logger.Get().Info().Msg("比较目录内容...")
+
+	var nodes []*dirNode
+	for _, dir := range dirs {
+		root := d.buildDirTree(walker, dir)
+		if root == nil {
+			continue
+		}
+		root.walk(func(node *dirNode) {
+			if node.eligible() {
+				nodes = append(nodes, node)
+			}
+		})
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].path < nodes[j].path
+	})
+
+	exact := dirGroups(nodes, func(n *dirNode) uint64 { return n.hash })
+	for _, group := range exact {
+		d.stats.DuplicateDirs = append(d.stats.DuplicateDirs, newDirGroup(group, group[0].hash, false))
+	}
+	for _, group := range dirGroups(nodes, func(n *dirNode) uint64 { return n.contentHash }) {
+		if sameHash(group) {
+			continue
+		}
+		d.stats.DuplicateDirs = append(d.stats.DuplicateDirs, newDirGroup(group, group[0].contentHash, true))
+	}
+	sort.SliceStable(d.stats.DuplicateDirs, func(i, j int) bool {
+		a, b := d.stats.DuplicateDirs[i], d.stats.DuplicateDirs[j]
+		if a.Size != b.Size {
+			return a.Size > b.Size
+		}
+		return a.Paths[0] < b.Paths[0]
+	})
+	d.stats.SupersetDirs = findSupersets(nodes)
+
+	for i, group := range d.stats.DuplicateDirs {
+		suffix := ""
+		if group.NamesDiffer {
+			suffix = ", 文件名不同"
+		}
+		logger.Get().Info().Msgf("重复目录组 [%d]: %s (%d 个文件, %s%s)",
+			i+1, strings.Join(group.Paths, ", "), group.Files, internal.FormatBytes(group.Size), suffix)
+	}
+	for _, pair := range d.stats.SupersetDirs {
+		logger.Get().Info().Msgf("目录包含: %s 包含 %s 的全部 %d 个文件", pair.Superset, pair.Subset, pair.Files)
+	}
+
+	if d.dirMode == internal.DirsApply {
+		for _, group := range exact {
+			d.applyDirGroup(group)
+		}
+	}
+}
+
+// dirGroups 按 key 分组，返回成员多于一个的组；
+// 所有成员的父目录同样互相重复时只保留父目录所在的组，只报告最上层的重复目录
+func dirGroups(nodes []*dirNode, key func(n *dirNode) uint64) [][]*dirNode {
+	byKey := make(map[uint64][]*dirNode)
+	var keys []uint64
+	for _, node := range nodes {
+		k := key(node)
+		if _, ok := byKey[k]; !ok {
+			keys = append(keys, k)
+		}
+		byKey[k] = append(byKey[k], node)
+	}
+
+	var groups [][]*dirNode
+	for _, k := range keys {
+		group := byKey[k]
+		if len(group) < 2 || parentsDuplicate(group, key) {
+			continue
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+func parentsDuplicate(group []*dirNode, key func(n *dirNode) uint64) bool {
+	seen := make(map[*dirNode]bool)
+	for _, node := range group {
+		parent := node.parent
+		if !parent.eligible() || seen[parent] || key(parent) != key(group[0].parent) {
+			return false
+		}
+		seen[parent] = true
+	}
+	return true
+}
+
+func sameHash(group []*dirNode) bool {
+	for _, node := range group[1:] {
+		if node.hash != group[0].hash {
+			return false
+		}
+	}
+	return true
+}
+
+func newDirGroup(group []*dirNode, hash uint64, namesDiffer bool) internal.DirGroup {
+	paths := make([]string, 0, len(group))
+	for _, node := range group {
+		paths = append(paths, node.path)
+	}
+	return internal.DirGroup{
+		Hash:        fmt.Sprintf("%016x", hash),
+		Paths:       paths,
+		Files:       group[0].fileCount,
+		Size:        group[0].size,
+		NamesDiffer: namesDiffer,
+	}
+}
+
+// findSupersets 查找一个目录包含另一个目录全部文件内容的目录对，
+// 只报告最大的被包含目录和最小的包含目录，内容相同的目录已作为重复目录报告
+func findSupersets(nodes []*dirNode) []internal.DirSuperset {
+	index := make(map[uint64][]*dirNode)
+	for _, node := range nodes {
+		for hash := range node.contents {
+			index[hash] = append(index[hash], node)
+		}
+	}
+
+	var result []internal.DirSuperset
+	for _, subset := range nodes {
+		// 从出现次数最少的文件内容开始查找候选目录
+		var candidates []*dirNode
+		for hash := range subset.contents {
+			if candidates == nil || len(index[hash]) < len(candidates) {
+				candidates = index[hash]
+			}
+		}
+
+		for _, superset := range candidates {
+			if !isMinimalSuperset(superset, subset) {
+				continue
+			}
+			result = append(result, internal.DirSuperset{
+				Superset: superset.path,
+				Subset:   subset.path,
+				Files:    subset.fileCount,
+			})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Subset != result[j].Subset {
+			return result[i].Subset < result[j].Subset
+		}
+		return result[i].Superset < result[j].Superset
+	})
+	return result
+}
+
+func isMinimalSuperset(superset, subset *dirNode) bool {
+	if related(superset, subset) || superset.contentHash == subset.contentHash || !superset.contains(subset) {
+		return false
+	}
+	// 文件内容完全相同但目录结构不同时只报告一次
+	if superset.fileCount == subset.fileCount && superset.path > subset.path {
+		return false
+	}
+	// 父目录同样被包含时由父目录报告
+	if parent := subset.parent; parent.eligible() && !related(superset, parent) && superset.contains(parent) {
+		return false
+	}
+	// 有更小的子目录包含时由子目录报告
+	for _, child := range superset.children {
+		if child.eligible() && !related(child, subset) && child.contains(subset) {
+			return false
+		}
+	}
+	return true
+}
+
+// applyDirGroup 保留组中第一个仍然存在的目录，按操作模式删除或移动其余目录
+func (d *Deduplicator) applyDirGroup(group []*dirNode) {
+	var keep *dirNode
+	for _, node := range group {
+		if _, err := os.Lstat(node.path); err == nil {
+			keep = node
+			break
+		}
+	}
+	if keep == nil {
+		return
+	}
+
+	for _, node := range group {
+		if node == keep {
+			continue
+		}
+		if _, err := os.Lstat(node.path); err != nil {
+			continue
+		}
+		if err := verifyDirTree(node); err != nil {
+			logger.Get().Warn().Err(err).Msgf("目录包含未参与比较的内容，跳过: %s", node.path)
+			continue
+		}
+
+		switch d.mode {
+		case internal.ModeDelete:
+			if err := os.RemoveAll(node.path); err != nil {
+				logger.Get().Error().Err(err).Msgf("删除目录失败: %s", node.path)
+				d.walker.Errors.Add(node.path, scanner.OpRemove, err)
+				continue
+			}
+			d.stats.FreedSpace += node.freedSpace()
+			logger.Get().Info().Msgf("发现重复目录: %s (%d 个文件, %s, 与 %s 相同, 已删除)",
+				node.path, node.fileCount, internal.FormatBytes(node.size), keep.path)
+		case internal.ModeMove:
+			dstPath, err := d.moveDir(node.path)
+			if err != nil {
+				logger.Get().Error().Err(err).Msgf("移动目录失败: %s", node.path)
+				d.walker.Errors.Add(node.path, scanner.OpMove, err)
+				continue
+			}
+			logger.Get().Info().Msgf("发现重复目录: %s (%d 个文件, %s, 与 %s 相同, 已移动到 %s)",
+				node.path, node.fileCount, internal.FormatBytes(node.size), keep.path, dstPath)
+		default:
+			continue
+		}
+
+		d.stats.DirsRemoved++
+		d.forgetDir(node)
+	}
+}
+
+// verifyDirTree 确认目录中只有参与了比较的普通文件，避免删除或移动被过滤、隐藏或无法读取的内容
+func verifyDirTree(node *dirNode) error {
+	return filepath.WalkDir(node.path, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == node.path || entry.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(node.path, path)
+		if err != nil {
+			return err
+		}
+		dir := node.lookupExisting(filepath.Dir(rel))
+		if !entry.Type().IsRegular() || dir == nil {
+			return fmt.Errorf("未参与比较: %s", path)
+		}
+		if _, ok := dir.files[entry.Name()]; !ok {
+			return fmt.Errorf("未参与比较: %s", path)
+		}
+		return nil
+	})
+}
+
+// lookupExisting 返回相对路径 rel 对应的子目录，不存在时返回 nil
+func (n *dirNode) lookupExisting(rel string) *dirNode {
+	node := n
+	if rel == "." {
+		return node
+	}
+	for _, name := range strings.Split(rel, string(filepath.Separator)) {
+		node = node.children[name]
+		if node == nil {
+			return nil
+		}
+	}
+	return node
+}
+
+// forgetDir 删除指向已处理目录中文件的数据库记录，避免保留的目录在随后的处理中被当作重复文件
+func (d *Deduplicator) forgetDir(node *dirNode) {
+	node.walk(func(n *dirNode) {
+		for name, file := range n.files {
+			path := filepath.Join(n.path, name)
+			hashStr := fmt.Sprintf("%016x", file.hash)
+			record, err := d.db.Lookup(hashStr)
+			if errors.Is(err, database.ErrRecordNotFound) {
+				continue
+			}
+			if err != nil {
+				logger.Get().Error().Err(err).Msgf("查询记录失败: %s", path)
+				continue
+			}
+			if isSamePath(record.FilePath, path) {
+				if err := d.db.Delete(hashStr); err != nil {
+					logger.Get().Error().Err(err).Msgf("删除记录失败: %s", path)
+				}
+			}
+		}
+	})
+}
+
+// moveDir 将目录移动到目标目录，重名时添加序号
+func (d *Deduplicator) moveDir(srcPath string) (string, error) {
+	if d.targetDir == "" {
+		return "", fmt.Errorf("target directory not specified")
+	}
+	if err := os.MkdirAll(d.targetDir, 0755); err != nil {
+		return "", err
+	}
+
+	baseName := filepath.Base(srcPath)
+	dstPath := filepath.Join(d.targetDir, baseName)
+	for counter := 1; ; counter++ {
+		if _, err := os.Lstat(dstPath); os.IsNotExist(err) {
+			break
+		} else if err != nil {
+			return "", fmt.Errorf("检查目标目录失败: %w", err)
+		}
+		if counter >= 100 {
+			return "", fmt.Errorf("无法生成唯一目录名，已尝试 %d 次", counter)
+		}
+		dstPath = filepath.Join(d.targetDir, fmt.Sprintf("%s_%d", baseName, counter))
+	}
+
+	logger.Get().Debug().Msgf("移动目录: %s -> %s", srcPath, dstPath)
+	return dstPath, os.Rename(srcPath, dstPath)
+}
@@ -0,0 +1,201 @@
+package deduplicator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/moyu-x/classified-file/internal"
+	"github.com/moyu-x/classified-file/pkg/database"
+)
+
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+}
+
+func TestDeduplicator_Process_DirsReport(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTree(t, tempDir, map[string]string{
+		"album/disc1/01.mp3": "track1",
+		"album/disc1/02.mp3": "track2",
+		"album/cover.jpg":    "cover",
+		"copy/disc1/01.mp3":  "track1",
+		"copy/disc1/02.mp3":  "track2",
+		"copy/cover.jpg":     "cover",
+		// 内容相同但文件名不同
+		"renamed/a.mp3":    "track1",
+		"renamed/b.mp3":    "track2",
+		"renamed/c.jpg":    "cover",
+		"renamed/d/e.mp3":  "bonus",
+		"partial/01.mp3":   "track1",
+		"partial/x.txt":    "unique",
+		"merged/cover.jpg": "cover",
+	})
+
+	d := NewDeduplicator(database.NewMemoryStore(), internal.ModeDelete, "", false)
+	d.SetDirMode(internal.DirsReport)
+
+	stats, err := d.Process([]string{tempDir}, false, false)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if stats.TotalProcessed != 0 || stats.Deleted != 0 {
+		t.Errorf("Report mode should not process files, got processed=%d deleted=%d", stats.TotalProcessed, stats.Deleted)
+	}
+
+	album := filepath.Join(tempDir, "album")
+	copyDir := filepath.Join(tempDir, "copy")
+	if len(stats.DuplicateDirs) != 1 {
+		t.Fatalf("Expected 1 duplicate group (top-most only), got %+v", stats.DuplicateDirs)
+	}
+	group := stats.DuplicateDirs[0]
+	if len(group.Paths) != 2 || group.Paths[0] != album || group.Paths[1] != copyDir {
+		t.Errorf("Unexpected duplicate group: %v", group.Paths)
+	}
+	if group.Files != 3 || group.Size != int64(len("track1track2cover")) || group.NamesDiffer {
+		t.Errorf("Unexpected group details: %+v", group)
+	}
+
+	// renamed 比 album 多一个文件，album 和 copy 都被 renamed 包含
+	renamed := filepath.Join(tempDir, "renamed")
+	want := map[string]bool{
+		album + "|" + renamed:                          false,
+		copyDir + "|" + renamed:                        false,
+		filepath.Join(tempDir, "merged") + "|" + album: false,
+	}
+	for _, pair := range stats.SupersetDirs {
+		key := pair.Subset + "|" + pair.Superset
+		if _, ok := want[key]; ok {
+			want[key] = true
+		}
+		if pair.Subset == filepath.Join(tempDir, "partial") {
+			t.Errorf("partial has a unique file and should not be a subset: %+v", pair)
+		}
+		if pair.Subset == filepath.Join(album, "disc1") {
+			t.Errorf("Subset covered by its parent should not be reported: %+v", pair)
+		}
+	}
+	for key, found := range want {
+		if !found {
+			t.Errorf("Missing superset pair %s in %+v", key, stats.SupersetDirs)
+		}
+	}
+
+	if _, err := os.Stat(copyDir); err != nil {
+		t.Errorf("Report mode should not modify files: %v", err)
+	}
+}
+
+func TestDeduplicator_Process_DirsNamesDiffer(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTree(t, tempDir, map[string]string{
+		"a/1.jpg": "one",
+		"a/2.jpg": "two",
+		"b/x.jpg": "one",
+		"b/y.jpg": "two",
+	})
+
+	d := NewDeduplicator(database.NewMemoryStore(), internal.ModeDelete, "", false)
+	d.SetDirMode(internal.DirsReport)
+
+	stats, err := d.Process([]string{tempDir}, false, false)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if len(stats.DuplicateDirs) != 1 || !stats.DuplicateDirs[0].NamesDiffer {
+		t.Fatalf("Expected 1 name-insensitive duplicate group, got %+v", stats.DuplicateDirs)
+	}
+	if len(stats.SupersetDirs) != 0 {
+		t.Errorf("Duplicate directories should not be reported as supersets: %+v", stats.SupersetDirs)
+	}
+}
+
+func TestDeduplicator_Process_DirsApply(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTree(t, tempDir, map[string]string{
+		"album/01.mp3":   "track1",
+		"album/02.mp3":   "track2",
+		"copy1/01.mp3":   "track1",
+		"copy1/02.mp3":   "track2",
+		"copy2/01.mp3":   "track1",
+		"copy2/02.mp3":   "track2",
+		"copy2/.notes":   "hidden",
+		"loose/song.mp3": "track1",
+	})
+
+	store := database.NewMemoryStore()
+	d := NewDeduplicator(store, internal.ModeDelete, "", false)
+	d.SetDirMode(internal.DirsApply)
+
+	stats, err := d.Process([]string{tempDir}, false, false)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if stats.DirsRemoved != 1 {
+		t.Errorf("Expected 1 directory removed, got %d", stats.DirsRemoved)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "copy1")); !os.IsNotExist(err) {
+		t.Errorf("Duplicate directory copy1 should be removed")
+	}
+	// copy2 中有未参与比较的隐藏文件，不能整体删除
+	if _, err := os.Stat(filepath.Join(tempDir, "copy2", ".notes")); err != nil {
+		t.Errorf("Directory with unscanned content should be kept: %v", err)
+	}
+	for _, name := range []string{"album/01.mp3", "album/02.mp3"} {
+		if _, err := os.Stat(filepath.Join(tempDir, name)); err != nil {
+			t.Errorf("Kept directory file %s should exist: %v", name, err)
+		}
+	}
+
+	// 剩余的重复文件继续逐文件处理
+	if _, err := os.Stat(filepath.Join(tempDir, "loose", "song.mp3")); !os.IsNotExist(err) {
+		t.Errorf("Loose duplicate should be deleted by the per-file pass")
+	}
+	if stats.FreedSpace < int64(len("track1track2")) {
+		t.Errorf("Expected freed space to include removed directory, got %d", stats.FreedSpace)
+	}
+}
+
+func TestDeduplicator_Process_DirsApplyMove(t *testing.T) {
+	tempDir := t.TempDir()
+	data := filepath.Join(tempDir, "data")
+	target := filepath.Join(tempDir, "target")
+	writeTree(t, data, map[string]string{
+		"a/photo.jpg": "photo",
+		"b/photo.jpg": "photo",
+	})
+
+	d := NewDeduplicator(database.NewMemoryStore(), internal.ModeMove, target, false)
+	d.SetDirMode(internal.DirsApply)
+
+	stats, err := d.Process([]string{data}, false, false)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if stats.DirsRemoved != 1 {
+		t.Fatalf("Expected 1 directory moved, got %d", stats.DirsRemoved)
+	}
+	if _, err := os.Stat(filepath.Join(target, "b", "photo.jpg")); err != nil {
+		t.Errorf("Duplicate directory should be moved to target: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(data, "a", "photo.jpg")); err != nil {
+		t.Errorf("Kept directory should stay in place: %v", err)
+	}
+	if stats.Moved != 0 {
+		t.Errorf("Kept directory files should not be treated as duplicates, got %d moved", stats.Moved)
+	}
+}
@@ -0,0 +1,42 @@
+package deduplicator
+
+import (
+	"github.com/moyu-x/classified-file/internal"
+	"github.com/moyu-x/classified-file/pkg/logger"
+	"github.com/moyu-x/classified-file/pkg/metadata"
+)
+
+// readMetadata 读取图片和视频的元数据，用于与哈希一起保存；没有元数据或读取失败时返回 nil，
+// 音频标签不保存
+func readMetadata(path string) *internal.FileMetadata {
+	meta, err := metadata.ReadFile(path)
+	if err != nil {
+		logger.Get().Debug().Err(err).Msgf("读取元数据失败: %s", path)
+		return nil
+	}
+	if meta.IsZero() {
+		return nil
+	}
+
+	record := &internal.FileMetadata{
+		TakenSource: string(meta.CreatedSource),
+		Make:        meta.Make,
+		Model:       meta.Model,
+		Orientation: meta.Orientation,
+		Width:       meta.Width,
+		Height:      meta.Height,
+	}
+	if !meta.Created.IsZero() {
+		record.TakenAt = meta.Created.Unix()
+	}
+	if meta.GPS != nil {
+		record.HasGPS = true
+		record.Latitude = meta.GPS.Latitude
+		record.Longitude = meta.GPS.Longitude
+		record.Altitude = meta.GPS.Altitude
+	}
+	if *record == (internal.FileMetadata{}) {
+		return nil
+	}
+	return record
+}
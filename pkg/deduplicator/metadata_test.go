@@ -0,0 +1,56 @@
+package deduplicator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/moyu-x/classified-file/internal"
+	"github.com/moyu-x/classified-file/pkg/database"
+	"github.com/moyu-x/classified-file/pkg/hasher"
+)
+
+// sofJPEG 是只包含 320x240 帧起始段、没有 EXIF 的 JPEG
+var sofJPEG = []byte{
+	0xFF, 0xD8,
+	0xFF, 0xC0, 0x00, 0x08, 0x08, 0x00, 0xF0, 0x01, 0x40, 0x00,
+	0xFF, 0xDA, 0x00, 0x02, 0xFF, 0xD9,
+}
+
+func TestDeduplicator_Process_StoresMetadata(t *testing.T) {
+	tempDir := t.TempDir()
+	photo := filepath.Join(tempDir, "photo.jpg")
+	text := filepath.Join(tempDir, "notes.txt")
+	if err := os.WriteFile(photo, sofJPEG, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if err := os.WriteFile(text, []byte("notes"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	store := database.NewMemoryStore()
+	d := NewDeduplicator(store, internal.ModeDelete, "", false)
+	if _, err := d.ProcessFiles([]string{photo, text}); err != nil {
+		t.Fatalf("ProcessFiles() error = %v", err)
+	}
+
+	lookup := func(path string) *internal.FileRecord {
+		hash, err := hasher.CalculateHash(path)
+		if err != nil {
+			t.Fatalf("CalculateHash() error = %v", err)
+		}
+		record, err := store.Lookup(fmt.Sprintf("%016x", hash))
+		if err != nil {
+			t.Fatalf("Lookup(%s) error = %v", path, err)
+		}
+		return record
+	}
+
+	if meta := lookup(photo).Metadata; meta == nil || meta.Width != 320 || meta.Height != 240 {
+		t.Errorf("Expected 320x240 metadata for photo, got %+v", meta)
+	}
+	if meta := lookup(text).Metadata; meta != nil {
+		t.Errorf("Expected no metadata for text file, got %+v", meta)
+	}
+}
@@ -0,0 +1,95 @@
+package deduplicator
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/moyu-x/classified-file/pkg/logger"
+)
+
+// canonicalizeRoots 将扫描根目录转换为绝对路径并解析符号链接，
+// 去除重复的根目录以及被其他根目录包含的子目录，保持原有顺序
+func canonicalizeRoots(dirs []string) []string {
+	canonical := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		canonical = append(canonical, canonicalPath(dir))
+	}
+
+	byLength := make([]string, len(canonical))
+	copy(byLength, canonical)
+	sort.SliceStable(byLength, func(i, j int) bool {
+		return len(byLength[i]) < len(byLength[j])
+	})
+
+	kept := make(map[string]bool)
+	var keptOrder []string
+	for _, root := range byLength {
+		if kept[root] {
+			logger.Get().Warn().Msgf("忽略重复的扫描目录: %s", root)
+			continue
+		}
+
+		parent := ""
+		for _, k := range keptOrder {
+			if isWithin(k, root) {
+				parent = k
+				break
+			}
+		}
+		if parent != "" {
+			logger.Get().Warn().Msgf("忽略嵌套的扫描目录: %s (已包含在 %s 中)", root, parent)
+			continue
+		}
+
+		kept[root] = true
+		keptOrder = append(keptOrder, root)
+	}
+
+	result := make([]string, 0, len(keptOrder))
+	for _, root := range canonical {
+		if kept[root] {
+			result = append(result, root)
+			delete(kept, root)
+		}
+	}
+	return result
+}
+
+// canonicalPath 返回绝对路径并解析符号链接，路径不存在时仅返回绝对路径
+func canonicalPath(path string) string {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+
+	resolved, err := filepath.EvalSymlinks(absPath)
+	if err != nil {
+		return absPath
+	}
+	return resolved
+}
+
+// isWithin 判断 path 是否位于 root 之内（包括 root 本身）
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// isSamePath 判断数据库记录中的路径与当前文件路径是否相同
+func isSamePath(recordPath, path string) bool {
+	return recordPath == path || canonicalPath(recordPath) == canonicalPath(path)
+}
+
+// isSameInode 判断数据库记录中的路径与当前文件是否指向同一个 inode（硬链接）
+func isSameInode(recordPath string, info os.FileInfo) bool {
+	recordInfo, err := os.Lstat(recordPath)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(recordInfo, info)
+}
@@ -0,0 +1,121 @@
+package deduplicator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/moyu-x/classified-file/internal"
+	"github.com/moyu-x/classified-file/pkg/database"
+)
+
+func TestCanonicalizeRoots(t *testing.T) {
+	tempDir := canonicalPath(t.TempDir())
+
+	data := filepath.Join(tempDir, "data")
+	photos := filepath.Join(data, "photos")
+	other := filepath.Join(tempDir, "other")
+	dataPrefix := filepath.Join(tempDir, "data2")
+
+	for _, dir := range []string{photos, other, dataPrefix} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+
+	link := filepath.Join(tempDir, "link")
+	if err := os.Symlink(data, link); err != nil {
+		t.Skipf("Skipping symlink test: %v", err)
+	}
+
+	got := canonicalizeRoots([]string{photos, other, data, link, dataPrefix, data + "/"})
+	want := []string{other, data, dataPrefix}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("canonicalizeRoots() = %v, want %v", got, want)
+	}
+}
+
+func TestDeduplicator_Process_NestedRoots(t *testing.T) {
+	tempDir := t.TempDir()
+	data := filepath.Join(tempDir, "data")
+	photos := filepath.Join(data, "photos")
+
+	if err := os.MkdirAll(photos, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	files := []string{
+		filepath.Join(data, "a.txt"),
+		filepath.Join(photos, "b.jpg"),
+	}
+	for i, file := range files {
+		if err := os.WriteFile(file, []byte{byte(i), 'x'}, 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+
+	d := NewDeduplicator(database.NewMemoryStore(), internal.ModeDelete, "", false)
+
+	stats, err := d.Process([]string{data, photos}, false, false)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if stats.TotalProcessed != len(files) {
+		t.Errorf("Expected %d files processed, got %d", len(files), stats.TotalProcessed)
+	}
+
+	if stats.Deleted != 0 {
+		t.Errorf("Expected no files deleted, got %d", stats.Deleted)
+	}
+
+	for _, file := range files {
+		if _, err := os.Stat(file); err != nil {
+			t.Errorf("Expected %s to be kept: %v", file, err)
+		}
+	}
+}
+
+func TestDeduplicator_Process_RescanSameRoot(t *testing.T) {
+	tempDir := t.TempDir()
+	data := filepath.Join(tempDir, "data")
+
+	if err := os.MkdirAll(data, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	file := filepath.Join(data, "a.txt")
+	if err := os.WriteFile(file, []byte("content"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	store := database.NewMemoryStore()
+
+	if _, err := NewDeduplicator(store, internal.ModeDelete, "", false).Process([]string{data}, false, false); err != nil {
+		t.Fatalf("First Process() error = %v", err)
+	}
+
+	link := filepath.Join(tempDir, "link")
+	if err := os.Symlink(data, link); err != nil {
+		t.Skipf("Skipping symlink test: %v", err)
+	}
+
+	stats, err := NewDeduplicator(store, internal.ModeDelete, "", false).Process([]string{link}, false, false)
+	if err != nil {
+		t.Fatalf("Second Process() error = %v", err)
+	}
+
+	if stats.Deleted != 0 {
+		t.Errorf("Expected no files deleted on rescan, got %d", stats.Deleted)
+	}
+
+	if stats.AlreadyIndexed != 1 {
+		t.Errorf("Expected 1 already indexed file, got %d", stats.AlreadyIndexed)
+	}
+
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("Expected file to be kept: %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+//go:build !windows
+
+package deduplicator
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/moyu-x/classified-file/internal"
+	"github.com/moyu-x/classified-file/pkg/database"
+)
+
+func TestDeduplicator_Process_SpecialFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	testFilesDir := filepath.Join(tempDir, "files")
+	if err := os.MkdirAll(testFilesDir, 0755); err != nil {
+		t.Fatalf("Failed to create test files directory: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(testFilesDir, "file.txt"), []byte("content"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	fifo := filepath.Join(testFilesDir, "pipe")
+	if err := syscall.Mkfifo(fifo, 0644); err != nil {
+		t.Skipf("Skipping FIFO test: %v", err)
+	}
+	// 默认不跟随符号链接，指向命名管道的链接也不能交给哈希计算
+	if err := os.Symlink(fifo, filepath.Join(testFilesDir, "link")); err != nil {
+		t.Skipf("Skipping symlink test: %v", err)
+	}
+
+	d := NewDeduplicator(database.NewMemoryStore(), internal.ModeDelete, "", false)
+
+	type result struct {
+		stats *internal.ProcessStats
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		stats, err := d.Process([]string{testFilesDir}, false, false)
+		done <- result{stats, err}
+	}()
+
+	var res result
+	select {
+	case res = <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Process() blocked on a FIFO")
+	}
+	if res.err != nil {
+		t.Fatalf("Process() error = %v", res.err)
+	}
+
+	if res.stats.Added != 1 {
+		t.Errorf("Expected 1 file added, got %d", res.stats.Added)
+	}
+	// 统计文件数和处理使用不同的遍历，命名管道和指向它的链接各只计一次
+	if len(res.stats.Skipped) != 1 || res.stats.Skipped["fifo"] != 2 {
+		t.Errorf("Expected 2 skipped FIFOs, got %v", res.stats.Skipped)
+	}
+}
@@ -0,0 +1,45 @@
+package deduplicator
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/moyu-x/classified-file/pkg/progress"
+	"github.com/moyu-x/classified-file/pkg/watcher"
+)
+
+// SetWatch 设置监视模式：首次扫描完成后继续监视目录，处理新建或移入的文件，直到 ctx 结束
+// ctx 为 nil 时不监视，settle 为文件稳定等待时间
+func (d *Deduplicator) SetWatch(ctx context.Context, settle time.Duration) {
+	d.watchCtx = ctx
+	d.watchSettle = settle
+}
+
+// watchFiles 监视扫描目录，稳定后的新文件按正常流程去重并记录进度
+func (d *Deduplicator) watchFiles(dirs []string) error {
+	d.watching.Store(true)
+	defer d.watching.Store(false)
+
+	w := watcher.New(d.walker, d.watchSettle)
+	w.Exclude(d.targetDir)
+
+	return w.Run(d.watchCtx, dirs, func(path string, info os.FileInfo) {
+		if d.totalFiles.Load() >= 0 {
+			d.totalFiles.Add(1)
+		}
+		d.processFile(path, info, d.trackerFor(path))
+		d.recheckDeferred()
+	})
+}
+
+// trackerFor 返回路径所在扫描根目录的进度跟踪器
+func (d *Deduplicator) trackerFor(path string) *progress.Tracker {
+	var best string
+	for rootDir := range d.trackers {
+		if isWithin(rootDir, path) && len(rootDir) > len(best) {
+			best = rootDir
+		}
+	}
+	return d.trackers[best]
+}
@@ -0,0 +1,78 @@
+package deduplicator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/moyu-x/classified-file/internal"
+	"github.com/moyu-x/classified-file/pkg/database"
+)
+
+func TestDeduplicator_Process_Watch(t *testing.T) {
+	tempDir := t.TempDir()
+	data := filepath.Join(tempDir, "data")
+	writeTree(t, data, map[string]string{"original.jpg": "photo"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	d := NewDeduplicator(database.NewMemoryStore(), internal.ModeDelete, "", false)
+	d.SetWatch(ctx, 50*time.Millisecond)
+
+	type result struct {
+		stats *internal.ProcessStats
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		stats, err := d.Process([]string{data}, false, false)
+		done <- result{stats, err}
+	}()
+
+	// 等待首次扫描完成并进入监视
+	deadline := time.Now().Add(5 * time.Second)
+	for !d.watching.Load() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for watch mode")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	duplicate := filepath.Join(data, "download.jpg")
+	if err := os.WriteFile(duplicate, []byte("photo"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	unique := filepath.Join(data, "new.jpg")
+	if err := os.WriteFile(unique, []byte("new photo"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	for {
+		if _, err := os.Stat(duplicate); os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for duplicate to be deleted")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	cancel()
+	res := <-done
+	if res.err != nil {
+		t.Fatalf("Process() error = %v", res.err)
+	}
+	if res.stats.Deleted != 1 {
+		t.Errorf("Expected 1 deleted file, got %d", res.stats.Deleted)
+	}
+	if _, err := os.Stat(unique); err != nil {
+		t.Errorf("Unique file should be kept: %v", err)
+	}
+	if d.watching.Load() {
+		t.Errorf("Watching flag should be cleared after Process returns")
+	}
+}
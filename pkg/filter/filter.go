@@ -0,0 +1,268 @@
+package filter
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Options 文件过滤条件，空值表示不限制
+type Options struct {
+	Include      []string      // 包含的 glob 模式，设置后只处理匹配的文件
+	Exclude      []string      // 排除的 glob 模式，同时作用于文件和目录
+	IncludeRegex []string      // 包含的正则表达式，匹配完整路径
+	ExcludeRegex []string      // 排除的正则表达式，匹配完整路径
+	ExcludeDirs  []string      // 排除的目录名（支持 glob）
+	MinSize      int64         // 最小文件大小（字节）
+	MaxSize      int64         // 最大文件大小（字节）
+	MinAge       time.Duration // 最小文件年龄（按修改时间）
+	MaxAge       time.Duration // 最大文件年龄（按修改时间）
+}
+
+// Filter 编译后的过滤器，nil 表示不过滤
+//
+// glob 模式使用 / 分隔，** 匹配任意层级目录：
+// 不含 / 的模式匹配文件名（如 *.jpg），含 / 的模式匹配相对扫描根目录的路径（如 photos/**/*.raw），
+// 以 / 开头的模式同样相对扫描根目录
+type Filter struct {
+	include      []string
+	exclude      []string
+	includeRegex []*regexp.Regexp
+	excludeRegex []*regexp.Regexp
+	excludeDirs  []string
+	minSize      int64
+	maxSize      int64
+	minAge       time.Duration
+	maxAge       time.Duration
+}
+
+// New 编译过滤条件，没有任何条件时返回 nil
+func New(opts Options) (*Filter, error) {
+	if opts.IsEmpty() {
+		return nil, nil
+	}
+
+	if opts.MaxSize > 0 && opts.MinSize > opts.MaxSize {
+		return nil, fmt.Errorf("最小文件大小 %d 大于最大文件大小 %d", opts.MinSize, opts.MaxSize)
+	}
+	if opts.MaxAge > 0 && opts.MinAge > opts.MaxAge {
+		return nil, fmt.Errorf("最小文件年龄 %v 大于最大文件年龄 %v", opts.MinAge, opts.MaxAge)
+	}
+
+	f := &Filter{
+		minSize: opts.MinSize,
+		maxSize: opts.MaxSize,
+		minAge:  opts.MinAge,
+		maxAge:  opts.MaxAge,
+	}
+
+	var err error
+	if f.include, err = compileGlobs(opts.Include); err != nil {
+		return nil, err
+	}
+	if f.exclude, err = compileGlobs(opts.Exclude); err != nil {
+		return nil, err
+	}
+	if f.excludeDirs, err = compileGlobs(opts.ExcludeDirs); err != nil {
+		return nil, err
+	}
+	if f.includeRegex, err = compileRegexes(opts.IncludeRegex); err != nil {
+		return nil, err
+	}
+	if f.excludeRegex, err = compileRegexes(opts.ExcludeRegex); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// IsEmpty 判断是否没有设置任何过滤条件
+func (o Options) IsEmpty() bool {
+	return len(o.Include) == 0 && len(o.Exclude) == 0 &&
+		len(o.IncludeRegex) == 0 && len(o.ExcludeRegex) == 0 &&
+		len(o.ExcludeDirs) == 0 &&
+		o.MinSize == 0 && o.MaxSize == 0 && o.MinAge == 0 && o.MaxAge == 0
+}
+
+// AllowDir 判断是否进入目录，relPath 为相对扫描根目录的路径
+func (f *Filter) AllowDir(relPath string) bool {
+	if f == nil {
+		return true
+	}
+
+	relPath = filepath.ToSlash(relPath)
+	name := path.Base(relPath)
+
+	for _, pattern := range f.excludeDirs {
+		if matchGlob(pattern, relPath, name) {
+			return false
+		}
+	}
+	for _, pattern := range f.exclude {
+		if matchGlob(pattern, relPath, name) {
+			return false
+		}
+	}
+	return true
+}
+
+// NeedsInfo 判断 AllowFile 是否需要文件信息（大小或修改时间），不需要时 info 可以为 nil
+func (f *Filter) NeedsInfo() bool {
+	if f == nil {
+		return false
+	}
+	return f.minSize > 0 || f.maxSize > 0 || f.minAge > 0 || f.maxAge > 0
+}
+
+// AllowFile 判断是否处理文件，fullPath 为完整路径，relPath 为相对扫描根目录的路径
+func (f *Filter) AllowFile(fullPath, relPath string, info os.FileInfo) bool {
+	if f == nil {
+		return true
+	}
+
+	relPath = filepath.ToSlash(relPath)
+	name := path.Base(relPath)
+
+	if len(f.include) > 0 || len(f.includeRegex) > 0 {
+		included := false
+		for _, pattern := range f.include {
+			if matchGlob(pattern, relPath, name) {
+				included = true
+				break
+			}
+		}
+		for _, re := range f.includeRegex {
+			if included {
+				break
+			}
+			included = re.MatchString(fullPath)
+		}
+		if !included {
+			return false
+		}
+	}
+
+	for _, pattern := range f.exclude {
+		if matchGlob(pattern, relPath, name) {
+			return false
+		}
+	}
+	for _, re := range f.excludeRegex {
+		if re.MatchString(fullPath) {
+			return false
+		}
+	}
+
+	if !f.NeedsInfo() {
+		return true
+	}
+
+	size := info.Size()
+	if f.minSize > 0 && size < f.minSize {
+		return false
+	}
+	if f.maxSize > 0 && size > f.maxSize {
+		return false
+	}
+
+	// 每次按当前时间计算，监视模式下启动后新建的文件同样可以满足最小年龄
+	age := time.Since(info.ModTime())
+	if f.minAge > 0 && age < f.minAge {
+		return false
+	}
+	if f.maxAge > 0 && age > f.maxAge {
+		return false
+	}
+
+	return true
+}
+
+// MinAgeWait 返回文件还需要多久才能满足最小年龄，没有设置最小年龄或已经满足时返回 0
+// 监视模式用它将尚未满足最小年龄的文件留在队列中稍后重试，而不是直接丢弃
+func (f *Filter) MinAgeWait(info os.FileInfo) time.Duration {
+	if f == nil || f.minAge <= 0 {
+		return 0
+	}
+	if wait := f.minAge - time.Since(info.ModTime()); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+func compileGlobs(patterns []string) ([]string, error) {
+	compiled := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(filepath.ToSlash(pattern))
+		if pattern == "" {
+			continue
+		}
+		for _, segment := range strings.Split(strings.TrimPrefix(pattern, "/"), "/") {
+			if _, err := path.Match(segment, ""); err != nil {
+				return nil, fmt.Errorf("无效的 glob 模式 %q: %w", pattern, err)
+			}
+		}
+		compiled = append(compiled, pattern)
+	}
+	return compiled, nil
+}
+
+func compileRegexes(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		if pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("无效的正则表达式 %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// matchGlob 不含 / 的模式匹配名称，否则匹配相对路径
+func matchGlob(pattern, relPath, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, name)
+		return ok
+	}
+	return MatchPath(strings.TrimPrefix(pattern, "/"), relPath)
+}
+
+// MatchPath 按 / 分段匹配路径，** 匹配零个或多个目录层级
+func MatchPath(pattern, relPath string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(relPath, "/"))
+}
+
+func matchSegments(patterns, segments []string) bool {
+	for len(patterns) > 0 {
+		if patterns[0] == "**" {
+			for len(patterns) > 0 && patterns[0] == "**" {
+				patterns = patterns[1:]
+			}
+			if len(patterns) == 0 {
+				return true
+			}
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(patterns, segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(patterns[0], segments[0]); !ok {
+			return false
+		}
+		patterns, segments = patterns[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
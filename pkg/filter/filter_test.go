@@ -0,0 +1,253 @@
+package filter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type fakeInfo struct {
+	os.FileInfo
+	size    int64
+	modTime time.Time
+}
+
+func (f fakeInfo) Size() int64        { return f.size }
+func (f fakeInfo) ModTime() time.Time { return f.modTime }
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"photos/*.jpg", "photos/a.jpg", true},
+		{"photos/*.jpg", "photos/2024/a.jpg", false},
+		{"photos/**/*.jpg", "photos/a.jpg", true},
+		{"photos/**/*.jpg", "photos/2024/01/a.jpg", true},
+		{"**/cache/**", "a/b/cache/c/d.txt", true},
+		{"**/cache/**", "cache", true},
+		{"**/cache/**", "a/cachex/d.txt", false},
+		{"**", "anything/at/all", true},
+		{"a/**/b", "a/b", true},
+		{"a/**/b", "a/x/y/b", true},
+		{"a/**/b", "a/x/y/c", false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchPath(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("MatchPath(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestNew_Empty(t *testing.T) {
+	f, err := New(Options{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if f != nil {
+		t.Error("Expected nil filter for empty options")
+	}
+
+	if !f.AllowDir("any") || !f.AllowFile("/any", "any", fakeInfo{}) {
+		t.Error("Expected nil filter to allow everything")
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	invalid := []Options{
+		{Include: []string{"[abc"}},
+		{ExcludeRegex: []string{"(unclosed"}},
+		{MinSize: 100, MaxSize: 10},
+		{MinAge: time.Hour, MaxAge: time.Minute},
+	}
+
+	for _, opts := range invalid {
+		if _, err := New(opts); err == nil {
+			t.Errorf("Expected error for options %+v", opts)
+		}
+	}
+}
+
+func TestFilter_AllowFile(t *testing.T) {
+	f, err := New(Options{
+		Include:      []string{"*.jpg", "docs/**"},
+		Exclude:      []string{"**/thumbs/**", "*.tmp.jpg"},
+		ExcludeRegex: []string{`/private/`},
+		MinSize:      10,
+		MaxSize:      1000,
+		MaxAge:       30 * 24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	now := time.Now()
+	ok := fakeInfo{size: 100, modTime: now.Add(-time.Hour)}
+
+	tests := []struct {
+		name string
+		rel  string
+		info fakeInfo
+		want bool
+	}{
+		{"included by name", "a/b/photo.jpg", ok, true},
+		{"included by path", "docs/readme.txt", ok, true},
+		{"not included", "a/readme.txt", ok, false},
+		{"excluded glob", "a/thumbs/photo.jpg", ok, false},
+		{"excluded name", "a/photo.tmp.jpg", ok, false},
+		{"excluded regex", "private/photo.jpg", ok, false},
+		{"too small", "photo.jpg", fakeInfo{size: 5, modTime: ok.modTime}, false},
+		{"too large", "photo.jpg", fakeInfo{size: 5000, modTime: ok.modTime}, false},
+		{"too old", "photo.jpg", fakeInfo{size: 100, modTime: now.Add(-60 * 24 * time.Hour)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fullPath := filepath.Join("/root", filepath.FromSlash(tt.rel))
+			if got := f.AllowFile(fullPath, filepath.FromSlash(tt.rel), tt.info); got != tt.want {
+				t.Errorf("AllowFile(%s) = %v, want %v", tt.rel, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter_MinAge(t *testing.T) {
+	f, err := New(Options{MinAge: time.Hour})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	// 过滤器创建之后才修改的文件按检查时的时间计算年龄
+	later := fakeInfo{modTime: time.Now().Add(time.Minute)}
+	if f.AllowFile("new.txt", "new.txt", later) {
+		t.Errorf("File newer than min age should be filtered")
+	}
+	if wait := f.MinAgeWait(later); wait <= time.Hour {
+		t.Errorf("Expected to wait more than the min age, got %v", wait)
+	}
+
+	aged := fakeInfo{modTime: time.Now().Add(-2 * time.Hour)}
+	if !f.AllowFile("old.txt", "old.txt", aged) {
+		t.Errorf("File older than min age should be allowed")
+	}
+	if wait := f.MinAgeWait(aged); wait != 0 {
+		t.Errorf("Expected no wait for old file, got %v", wait)
+	}
+
+	var nilFilter *Filter
+	if wait := nilFilter.MinAgeWait(later); wait != 0 {
+		t.Errorf("nil filter should not wait, got %v", wait)
+	}
+}
+
+func TestFilter_NeedsInfo(t *testing.T) {
+	var nilFilter *Filter
+	if nilFilter.NeedsInfo() {
+		t.Error("Expected nil filter not to need file info")
+	}
+
+	globOnly, err := New(Options{Include: []string{"*.jpg"}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if globOnly.NeedsInfo() {
+		t.Error("Expected glob-only filter not to need file info")
+	}
+	if !globOnly.AllowFile("/a/b.jpg", "b.jpg", nil) {
+		t.Error("Expected glob-only filter to accept nil info")
+	}
+
+	sized, err := New(Options{MinSize: 10})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if !sized.NeedsInfo() {
+		t.Error("Expected size filter to need file info")
+	}
+}
+
+func TestFilter_AllowDir(t *testing.T) {
+	f, err := New(Options{
+		Exclude:     []string{"build/**"},
+		ExcludeDirs: []string{"node_modules", ".git"},
+		MinAge:      time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := map[string]bool{
+		"src":                     true,
+		"src/node_modules":        false,
+		".git":                    false,
+		"build":                   false,
+		"src/build":               true,
+		"src/node_modules_backup": true,
+	}
+
+	for dir, want := range tests {
+		if got := f.AllowDir(filepath.FromSlash(dir)); got != want {
+			t.Errorf("AllowDir(%s) = %v, want %v", dir, got, want)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"":      0,
+		"100":   100,
+		"100B":  100,
+		"10K":   10 << 10,
+		"10kb":  10 << 10,
+		"1.5MB": 3 << 19,
+		"2GiB":  2 << 30,
+		"1T":    1 << 40,
+	}
+
+	for input, want := range tests {
+		got, err := ParseSize(input)
+		if err != nil {
+			t.Errorf("ParseSize(%q) error = %v", input, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseSize(%q) = %d, want %d", input, got, want)
+		}
+	}
+
+	for _, input := range []string{"abc", "10X", "-1K"} {
+		if _, err := ParseSize(input); err == nil {
+			t.Errorf("Expected error for ParseSize(%q)", input)
+		}
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := map[string]time.Duration{
+		"":    0,
+		"30m": 30 * time.Minute,
+		"12h": 12 * time.Hour,
+		"7d":  7 * 24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+	}
+
+	for input, want := range tests {
+		got, err := ParseAge(input)
+		if err != nil {
+			t.Errorf("ParseAge(%q) error = %v", input, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseAge(%q) = %v, want %v", input, got, want)
+		}
+	}
+
+	for _, input := range []string{"abc", "xd", "-1h"} {
+		if _, err := ParseAge(input); err == nil {
+			t.Errorf("Expected error for ParseAge(%q)", input)
+		}
+	}
+}
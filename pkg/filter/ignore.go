@@ -0,0 +1,148 @@
+package filter
+
+import (
+	"bufio"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreRules 单个忽略文件（.classifiedignore / .gitignore）中的规则，语义与 gitignore 一致：
+// 支持 # 注释、! 取反、结尾 / 表示只匹配目录、包含 / 的模式相对忽略文件所在目录锚定、
+// 不含 / 的模式匹配任意层级的名称，以及 ** 通配
+type IgnoreRules struct {
+	base     string
+	patterns []ignorePattern
+}
+
+type ignorePattern struct {
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	contents bool // 以 /** 结尾，只匹配目录内的内容，不匹配目录本身
+}
+
+// ParseIgnore 解析忽略文件，base 为忽略文件所在目录相对扫描根目录的路径
+func ParseIgnore(r io.Reader, base string) (*IgnoreRules, error) {
+	base = filepath.ToSlash(base)
+	if base == "." {
+		base = ""
+	}
+
+	rules := &IgnoreRules{base: base}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if p, ok := parseIgnoreLine(scanner.Text()); ok {
+			rules.patterns = append(rules.patterns, p)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func parseIgnoreLine(line string) (ignorePattern, bool) {
+	line = strings.TrimSuffix(line, "\r")
+	line = trimTrailingSpaces(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignorePattern{}, false
+	}
+
+	var p ignorePattern
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return ignorePattern{}, false
+	}
+
+	// 开头或中间包含 / 的模式相对忽略文件所在目录锚定，否则匹配任意层级
+	if strings.Contains(line, "/") {
+		line = strings.TrimPrefix(line, "/")
+	} else {
+		line = "**/" + line
+	}
+
+	if rest, ok := strings.CutSuffix(line, "/**"); ok {
+		p.contents = true
+		line = rest
+	}
+
+	for _, segment := range strings.Split(line, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return ignorePattern{}, false
+		}
+	}
+
+	p.pattern = line
+	return p, true
+}
+
+// trimTrailingSpaces 去除行尾未转义的空格
+func trimTrailingSpaces(line string) string {
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	return line
+}
+
+// match 判断相对忽略文件所在目录的路径是否匹配
+func (p ignorePattern) match(relPath string, isDir bool) bool {
+	if p.contents {
+		// 匹配 pattern 下的任意内容：路径的某个真前缀匹配 pattern
+		segments := strings.Split(relPath, "/")
+		for i := 1; i < len(segments); i++ {
+			if MatchPath(p.pattern, strings.Join(segments[:i], "/")) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if p.dirOnly && !isDir {
+		return false
+	}
+	return MatchPath(p.pattern, relPath)
+}
+
+// relative 返回 relPath 相对规则所在目录的路径，不在该目录下时返回 false
+func (r *IgnoreRules) relative(relPath string) (string, bool) {
+	if r.base == "" {
+		return relPath, true
+	}
+	if rest, ok := strings.CutPrefix(relPath, r.base+"/"); ok {
+		return rest, true
+	}
+	return "", false
+}
+
+// Ignored 按从浅到深的顺序应用忽略规则，最后一条匹配的规则生效；relPath 为相对扫描根目录的路径
+func Ignored(rules []*IgnoreRules, relPath string, isDir bool) bool {
+	relPath = filepath.ToSlash(relPath)
+
+	ignored := false
+	for _, r := range rules {
+		rel, ok := r.relative(relPath)
+		if !ok {
+			continue
+		}
+		for _, p := range r.patterns {
+			if p.match(rel, isDir) {
+				ignored = !p.negate
+			}
+		}
+	}
+	return ignored
+}
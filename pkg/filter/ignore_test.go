@@ -0,0 +1,88 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+)
+
+func parseRules(t *testing.T, base, content string) *IgnoreRules {
+	t.Helper()
+	rules, err := ParseIgnore(strings.NewReader(content), base)
+	if err != nil {
+		t.Fatalf("ParseIgnore() error = %v", err)
+	}
+	return rules
+}
+
+func TestIgnored_Patterns(t *testing.T) {
+	rules := []*IgnoreRules{parseRules(t, ".", `
+# 注释
+*.log
+!keep.log
+/build
+dist/
+docs/*.tmp
+**/cache/**
+\#literal
+trailing   
+`)}
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"app.log", false, true},
+		{"sub/deep/app.log", false, true},
+		{"keep.log", false, false},
+		{"sub/keep.log", false, false},
+		{"build", true, true},
+		{"sub/build", true, false},
+		{"dist", true, true},
+		{"dist", false, false},
+		{"sub/dist", true, true},
+		{"docs/a.tmp", false, true},
+		{"docs/sub/a.tmp", false, false},
+		{"a/cache", true, false},
+		{"a/cache/x.bin", false, true},
+		{"#literal", false, true},
+		{"trailing", false, true},
+		{"main.go", false, false},
+	}
+
+	for _, tt := range tests {
+		if got := Ignored(rules, tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Ignored(%q, dir=%v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestIgnored_Inheritance(t *testing.T) {
+	rules := []*IgnoreRules{
+		parseRules(t, "", "*.tmp\n/top.txt\n"),
+		parseRules(t, "project", "!important.tmp\n/out\n"),
+	}
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"a.tmp", false, true},
+		{"project/a.tmp", false, true},
+		{"project/important.tmp", false, false},
+		{"project/sub/important.tmp", false, false},
+		{"important.tmp", false, true},
+		{"top.txt", false, true},
+		{"project/top.txt", false, false},
+		{"project/out", true, true},
+		{"out", true, false},
+		{"project2/out", true, false},
+	}
+
+	for _, tt := range tests {
+		if got := Ignored(rules, tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Ignored(%q, dir=%v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
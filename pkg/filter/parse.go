@@ -0,0 +1,70 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseSize 解析文件大小，支持 B、K/KB/KiB、M/MB/MiB、G/GB/GiB、T/TB/TiB（均按 1024 进制），空字符串返回 0
+func ParseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	upper := strings.ToUpper(s)
+	i := len(upper)
+	for i > 0 && (upper[i-1] < '0' || upper[i-1] > '9') && upper[i-1] != '.' {
+		i--
+	}
+
+	number, unit := strings.TrimSpace(upper[:i]), strings.TrimSpace(upper[i:])
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("无效的文件大小: %s", s)
+	}
+
+	var multiplier float64
+	switch strings.TrimSuffix(strings.TrimSuffix(unit, "B"), "I") {
+	case "":
+		multiplier = 1
+	case "K":
+		multiplier = 1 << 10
+	case "M":
+		multiplier = 1 << 20
+	case "G":
+		multiplier = 1 << 30
+	case "T":
+		multiplier = 1 << 40
+	default:
+		return 0, fmt.Errorf("无效的文件大小单位: %s", s)
+	}
+
+	return int64(value * multiplier), nil
+}
+
+// ParseAge 解析时间长度，在 time.ParseDuration 基础上支持 d（天）和 w（周），空字符串返回 0
+func ParseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if number, ok := strings.CutSuffix(s, suffix); ok {
+			value, err := strconv.ParseFloat(number, 64)
+			if err != nil || value < 0 {
+				return 0, fmt.Errorf("无效的时间长度: %s", s)
+			}
+			return time.Duration(value * float64(unit)), nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("无效的时间长度: %s", s)
+	}
+	return d, nil
+}
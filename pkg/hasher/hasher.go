@@ -3,6 +3,7 @@ package hasher
 import (
 	"github.com/cespare/xxhash/v2"
 	"io"
+	"io/fs"
 	"os"
 
 	"github.com/moyu-x/classified-file/pkg/logger"
@@ -18,13 +19,39 @@ func CalculateHash(filePath string) (uint64, error) {
 	}
 	defer file.Close()
 
+	return hashFile(file, filePath)
+}
+
+// CalculateHashFS 计算 fsys 中文件的哈希值，name 为 fs 路径
+func CalculateHashFS(fsys fs.FS, name string) (uint64, error) {
+	logger.Get().Debug().Msgf("计算文件哈希: %s", name)
+
+	file, err := fsys.Open(name)
+	if err != nil {
+		logger.Get().Error().Err(err).Msgf("无法打开文件: %s", name)
+		return 0, err
+	}
+	defer file.Close()
+
+	return hashFile(file, name)
+}
+
+// HashReader 计算数据流的哈希值，与对同样内容的文件调用 CalculateHash 结果相同
+func HashReader(r io.Reader) (uint64, error) {
 	hash := xxhash.New()
-	if _, err := io.Copy(hash, file); err != nil {
-		logger.Get().Error().Err(err).Msgf("计算哈希失败: %s", filePath)
+	if _, err := io.Copy(hash, r); err != nil {
+		return 0, err
+	}
+	return hash.Sum64(), nil
+}
+
+func hashFile(file io.Reader, name string) (uint64, error) {
+	result, err := HashReader(file)
+	if err != nil {
+		logger.Get().Error().Err(err).Msgf("计算哈希失败: %s", name)
 		return 0, err
 	}
 
-	result := hash.Sum64()
-	logger.Get().Trace().Msgf("文件哈希计算完成: %s -> %x", filePath, result)
+	logger.Get().Trace().Msgf("文件哈希计算完成: %s -> %x", name, result)
 	return result, nil
 }
@@ -1,9 +1,11 @@
 package hasher
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
 	"testing"
+	"testing/fstest"
 )
 
 func TestCalculateHash(t *testing.T) {
@@ -102,3 +104,39 @@ func TestCalculateHash_LargeFile(t *testing.T) {
 		t.Error("Expected non-zero hash for large file")
 	}
 }
+
+func TestCalculateHashFS(t *testing.T) {
+	content := []byte("test content for hashing")
+
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "test.txt")
+	if err := os.WriteFile(testFile, content, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	want, err := CalculateHash(testFile)
+	if err != nil {
+		t.Fatalf("CalculateHash() error = %v", err)
+	}
+
+	fsys := fstest.MapFS{"dir/test.txt": {Data: content}}
+	got, err := CalculateHashFS(fsys, "dir/test.txt")
+	if err != nil {
+		t.Fatalf("CalculateHashFS() error = %v", err)
+	}
+	if got != want {
+		t.Errorf("CalculateHashFS() = %x, want %x", got, want)
+	}
+
+	fromReader, err := HashReader(bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("HashReader() error = %v", err)
+	}
+	if fromReader != want {
+		t.Errorf("HashReader() = %x, want %x", fromReader, want)
+	}
+
+	if _, err := CalculateHashFS(fsys, "missing.txt"); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
@@ -0,0 +1,138 @@
+package lock
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/moyu-x/classified-file/pkg/logger"
+)
+
+// FileLock 基于文件的进程间排他锁（advisory lock）
+type FileLock struct {
+	path string
+	file *os.File
+}
+
+// LockedError 锁已被其他进程持有
+type LockedError struct {
+	Path string
+	PID  int
+}
+
+func (e *LockedError) Error() string {
+	if e.PID > 0 {
+		return fmt.Sprintf("%s 已被进程 %d 锁定", e.Path, e.PID)
+	}
+	return fmt.Sprintf("%s 已被其他进程锁定", e.Path)
+}
+
+// Acquire 获取 path 上的排他锁，并在锁文件中写入当前进程 PID
+// wait 为 false 时若锁被占用立即返回 *LockedError，为 true 时阻塞直到锁释放
+func Acquire(path string, wait bool) (*FileLock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	for {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+		if err != nil {
+			return nil, err
+		}
+
+		locked, err := tryLock(file)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		if !locked {
+			pid := readPID(file)
+			if !wait {
+				file.Close()
+				return nil, &LockedError{Path: path, PID: pid}
+			}
+
+			logger.Get().Warn().Msgf("等待锁释放: %s (持有进程: %d)", path, pid)
+			if err := waitLock(file); err != nil {
+				file.Close()
+				return nil, err
+			}
+		}
+
+		// 持有者释放时会删除锁文件，需确认锁住的仍是当前路径上的文件
+		if !isCurrent(file, path) {
+			unlock(file)
+			file.Close()
+			continue
+		}
+
+		if err := writePID(file); err != nil {
+			unlock(file)
+			file.Close()
+			return nil, err
+		}
+
+		logger.Get().Debug().Msgf("已获取锁: %s", path)
+		return &FileLock{path: path, file: file}, nil
+	}
+}
+
+// Release 释放锁并删除锁文件
+func (l *FileLock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+
+	removeErr := os.Remove(l.path)
+	unlock(l.file)
+	err := l.file.Close()
+	l.file = nil
+
+	if removeErr != nil && !os.IsNotExist(removeErr) {
+		// Windows 下无法删除已打开的文件，关闭后重试
+		if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+			logger.Get().Warn().Err(err).Msgf("删除锁文件失败: %s", l.path)
+		}
+	}
+
+	logger.Get().Debug().Msgf("已释放锁: %s", l.path)
+	return err
+}
+
+// Path 返回锁文件路径
+func (l *FileLock) Path() string {
+	return l.path
+}
+
+func isCurrent(file *os.File, path string) bool {
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	pathInfo, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(fileInfo, pathInfo)
+}
+
+func readPID(file *os.File) int {
+	buf := make([]byte, 32)
+	n, _ := file.ReadAt(buf, 0)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(buf[:n])))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
+
+func writePID(file *os.File) error {
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+	_, err := file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	return err
+}
@@ -0,0 +1,83 @@
+package lock
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAcquire_Release(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "test.lock")
+
+	fileLock, err := Acquire(lockPath, false)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	if _, err := os.Stat(lockPath); err != nil {
+		t.Errorf("Expected lock file to exist: %v", err)
+	}
+
+	if err := fileLock.Release(); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Error("Expected lock file to be removed after release")
+	}
+
+	if err := fileLock.Release(); err != nil {
+		t.Errorf("Second Release() error = %v", err)
+	}
+}
+
+func TestAcquire_AlreadyLocked(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "test.lock")
+
+	fileLock, err := Acquire(lockPath, false)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	defer fileLock.Release()
+
+	_, err = Acquire(lockPath, false)
+	var lockedErr *LockedError
+	if !errors.As(err, &lockedErr) {
+		t.Fatalf("Expected LockedError, got %v", err)
+	}
+
+	if lockedErr.PID != os.Getpid() {
+		t.Errorf("Expected PID %d in error, got %d", os.Getpid(), lockedErr.PID)
+	}
+}
+
+func TestAcquire_Wait(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "test.lock")
+
+	fileLock, err := Acquire(lockPath, false)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		fileLock.Release()
+	}()
+
+	start := time.Now()
+	waited, err := Acquire(lockPath, true)
+	if err != nil {
+		t.Fatalf("Acquire() with wait error = %v", err)
+	}
+	defer waited.Release()
+
+	if time.Since(start) < 50*time.Millisecond {
+		t.Error("Expected Acquire() to block until the lock was released")
+	}
+
+	if _, err := os.Stat(lockPath); err != nil {
+		t.Errorf("Expected lock file to be recreated by the new holder: %v", err)
+	}
+}
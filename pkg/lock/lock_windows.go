@@ -0,0 +1,38 @@
+//go:build windows
+
+package lock
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// Windows 的字节范围锁是强制锁，锁定文件末尾之外的区域，保证 PID 内容仍可被读取
+const lockOffsetHigh = 0x7fffffff
+
+func lockRange(file *os.File, flags uint32) error {
+	overlapped := &windows.Overlapped{OffsetHigh: lockOffsetHigh}
+	return windows.LockFileEx(windows.Handle(file.Fd()), flags, 0, 1, 0, overlapped)
+}
+
+func tryLock(file *os.File) (bool, error) {
+	err := lockRange(file, windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY)
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return false, nil
+	}
+	return false, err
+}
+
+func waitLock(file *os.File) error {
+	return lockRange(file, windows.LOCKFILE_EXCLUSIVE_LOCK)
+}
+
+func unlock(file *os.File) {
+	overlapped := &windows.Overlapped{OffsetHigh: lockOffsetHigh}
+	windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, overlapped)
+}
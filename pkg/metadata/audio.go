@@ -0,0 +1,126 @@
+package metadata
+
+import (
+	"strconv"
+	"strings"
+)
+
+// maxTagSize 限制读入内存的标签数据大小，内嵌封面等更大的数据会被截断或跳过
+const maxTagSize = 16 << 20
+
+// maxTextSize 限制单个文本标签的大小
+const maxTextSize = 64 << 10
+
+// 标签字段名，各格式的标签名统一映射到这些字段
+const (
+	tagTitle       = "title"
+	tagArtist      = "artist"
+	tagAlbumArtist = "albumartist"
+	tagAlbum       = "album"
+	tagTrack       = "track"
+	tagDisc        = "disc"
+)
+
+// AudioTags 是音频文件中的标签，支持 ID3v1/ID3v2、FLAC 和 Ogg 的 Vorbis 注释以及 MP4 元数据
+type AudioTags struct {
+	Title       string
+	Artist      string
+	AlbumArtist string
+	Album       string
+	Track       int // 音轨号，0 表示未知
+	Disc        int // 碟号，0 表示未知
+}
+
+func (t *AudioTags) isZero() bool {
+	return *t == AudioTags{}
+}
+
+// set 设置统一字段名对应的标签，已有的值不会被覆盖
+func (t *AudioTags) set(field, value string) {
+	value = strings.TrimSpace(strings.TrimRight(value, "\x00"))
+	if value == "" {
+		return
+	}
+
+	switch field {
+	case tagTitle:
+		setString(&t.Title, value)
+	case tagArtist:
+		setString(&t.Artist, value)
+	case tagAlbumArtist:
+		setString(&t.AlbumArtist, value)
+	case tagAlbum:
+		setString(&t.Album, value)
+	case tagTrack:
+		setNumber(&t.Track, value)
+	case tagDisc:
+		setNumber(&t.Disc, value)
+	}
+}
+
+func setString(dst *string, value string) {
+	if *dst == "" {
+		*dst = value
+	}
+}
+
+// setNumber 解析 "3" 或 "3/12" 形式的序号
+func setNumber(dst *int, value string) {
+	if *dst != 0 {
+		return
+	}
+	value, _, _ = strings.Cut(value, "/")
+	if n, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && n > 0 {
+		*dst = n
+	}
+}
+
+// audio 返回用于写入的音频标签
+func (m *Metadata) audio() *AudioTags {
+	if m.Audio == nil {
+		m.Audio = &AudioTags{}
+	}
+	return m.Audio
+}
+
+// vorbisFields 是 Vorbis 注释（FLAC、Ogg Vorbis、Opus）中的标签名
+var vorbisFields = map[string]string{
+	"TITLE":        tagTitle,
+	"ARTIST":       tagArtist,
+	"ALBUMARTIST":  tagAlbumArtist,
+	"ALBUM ARTIST": tagAlbumArtist,
+	"ALBUM":        tagAlbum,
+	"TRACKNUMBER":  tagTrack,
+	"DISCNUMBER":   tagDisc,
+}
+
+// parseVorbisComment 解析小端编码的 Vorbis 注释，数据被截断时保留已读取的标签
+func parseVorbisComment(data []byte, tags *AudioTags) {
+	u32 := func() (int, bool) {
+		if len(data) < 4 {
+			return 0, false
+		}
+		n := int(uint32(data[0]) | uint32(data[1])<<8 | uint32(data[2])<<16 | uint32(data[3])<<24)
+		data = data[4:]
+		return n, n >= 0
+	}
+
+	vendor, ok := u32()
+	if !ok || vendor > len(data) {
+		return
+	}
+	data = data[vendor:]
+
+	count, ok := u32()
+	for i := 0; ok && i < count; i++ {
+		n, ok := u32()
+		if !ok || n > len(data) {
+			return
+		}
+		key, value, found := strings.Cut(string(data[:n]), "=")
+		data = data[n:]
+		if field := vorbisFields[strings.ToUpper(key)]; found && field != "" {
+			tags.set(field, value)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// id3Frame 构造 ID3v2.3/v2.4 帧，v2.4 的大小使用 synchsafe 编码
+func id3Frame(major byte, id string, flags uint16, data []byte) []byte {
+	size := uint32(len(data))
+	if major == 4 {
+		size = size&0x7F | (size>>7&0x7F)<<8 | (size>>14&0x7F)<<16 | (size>>21&0x7F)<<24
+	}
+	return append(append(append([]byte(id), be32(size)...), be16(flags)...), data...)
+}
+
+// buildID3 构造 ID3v2 标签，padding 为标签末尾的填充字节数
+func buildID3(major, flags byte, padding int, frames ...[]byte) []byte {
+	body := append(bytes.Join(frames, nil), make([]byte, padding)...)
+	n := len(body)
+	return append([]byte{'I', 'D', '3', major, 0, flags,
+		byte(n >> 21 & 0x7F), byte(n >> 14 & 0x7F), byte(n >> 7 & 0x7F), byte(n & 0x7F)}, body...)
+}
+
+// utf16Text 构造带 BOM 的 UTF-16LE 文本帧内容
+func utf16Text(s string) []byte {
+	data := []byte{1, 0xFF, 0xFE}
+	for _, r := range s {
+		data = binary.LittleEndian.AppendUint16(data, uint16(r))
+	}
+	return data
+}
+
+// vorbisComment 构造小端编码的 Vorbis 注释
+func vorbisComment(comments ...string) []byte {
+	le := binary.LittleEndian
+	data := le.AppendUint32(nil, 6)
+	data = append(data, "vendor"...)
+	data = le.AppendUint32(data, uint32(len(comments)))
+	for _, c := range comments {
+		data = le.AppendUint32(data, uint32(len(c)))
+		data = append(data, c...)
+	}
+	return data
+}
+
+// buildFLAC 构造包含 STREAMINFO 和 VORBIS_COMMENT 元数据块的 FLAC
+func buildFLAC(comments ...string) []byte {
+	comment := vorbisComment(comments...)
+	data := []byte("fLaC")
+	data = append(data, 0, 0, 0, 34)
+	data = append(data, make([]byte, 34)...)
+	data = append(data, 0x80|flacVorbisComment, byte(len(comment)>>16), byte(len(comment)>>8), byte(len(comment)))
+	return append(data, comment...)
+}
+
+// oggPage 构造 Ogg 页，body 按 255 字节分段
+func oggPage(serial uint32, body []byte, complete bool) []byte {
+	var segments []byte
+	for n := len(body); ; n -= 255 {
+		if n < 255 {
+			if complete {
+				segments = append(segments, byte(n))
+			}
+			break
+		}
+		segments = append(segments, 255)
+	}
+	header := []byte("OggS\x00\x00")
+	header = append(header, make([]byte, 8)...)
+	header = binary.LittleEndian.AppendUint32(header, serial)
+	header = append(header, make([]byte, 8)...)
+	header = append(header, byte(len(segments)))
+	return append(append(header, segments...), body...)
+}
+
+// buildM4A 构造 moov/udta/meta/ilst 中带标签的 M4A
+func buildM4A(items ...[]byte) []byte {
+	return bytes.Join([][]byte{
+		mkbox("ftyp", []byte("M4A \x00\x00\x00\x00M4A isom")),
+		mkbox("moov", mkbox("udta", mkbox("meta", be32(0),
+			mkbox("hdlr", be32(0), be32(0), []byte("mdirappl"), make([]byte, 9)),
+			mkbox("ilst", items...),
+		))),
+	}, nil)
+}
+
+func m4aItem(typ string, value []byte) []byte {
+	return mkbox(typ, mkbox("data", be32(1), be32(0), value))
+}
+
+func readAudio(t *testing.T, data []byte) AudioTags {
+	t.Helper()
+	meta := read(t, data)
+	if meta.Audio == nil {
+		t.Fatal("Expected audio tags")
+	}
+	return *meta.Audio
+}
+
+func TestRead_ID3v23(t *testing.T) {
+	data := buildID3(3, 0, 64,
+		id3Frame(3, "TIT2", 0, utf16Text("Café del Mar")),
+		id3Frame(3, "TPE1", 0, []byte("\x00Artist\x00")),
+		id3Frame(3, "APIC", 0, make([]byte, 100)),
+		id3Frame(3, "TALB", 0, []byte("\x03Album")),
+		id3Frame(3, "TRCK", 0, []byte("\x003/12")),
+		id3Frame(3, "TPOS", 0, []byte("\x002")),
+	)
+	data = append(data, 0xFF, 0xFB, 0x90, 0x00)
+
+	want := AudioTags{Title: "Café del Mar", Artist: "Artist", Album: "Album", Track: 3, Disc: 2}
+	if got := readAudio(t, data); got != want {
+		t.Errorf("Read() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRead_ID3v24(t *testing.T) {
+	long := strings.Repeat("x", 200)
+	data := buildID3(4, 0, 0,
+		id3Frame(4, "TIT2", 0, []byte("\x03"+long)),
+		// 带数据长度指示的帧
+		id3Frame(4, "TPE2", id3v4FrameLength, append(be32(0), []byte("\x03Band\x00Other")...)),
+		id3Frame(4, "TRCK", 0, []byte("\x037")),
+	)
+
+	want := AudioTags{Title: long, AlbumArtist: "Band", Track: 7}
+	if got := readAudio(t, data); got != want {
+		t.Errorf("Read() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRead_ID3Unsync(t *testing.T) {
+	// 反同步后 TIT2 中的 0xFF 之后插入了 0x00
+	frame := id3Frame(3, "TIT2", 0, []byte("\x00a\xFFb"))
+	// 帧大小为还原后的长度
+	frame = append(frame[:len(frame)-1], 0x00, 'b')
+	data := buildID3(3, id3Unsync, 0, frame)
+
+	if got := readAudio(t, data); got.Title != "aÿb" {
+		t.Errorf("Read() title = %q", got.Title)
+	}
+}
+
+func TestRead_ID3v1(t *testing.T) {
+	tag := make([]byte, 128)
+	copy(tag, "TAG")
+	copy(tag[3:], "Title")
+	copy(tag[33:], "Artist")
+	copy(tag[63:], "Album")
+	tag[126] = 5
+	data := append([]byte{0xFF, 0xFB, 0x90, 0x00}, make([]byte, 100)...)
+
+	want := AudioTags{Title: "Title", Artist: "Artist", Album: "Album", Track: 5}
+	if got := readAudio(t, append(data, tag...)); got != want {
+		t.Errorf("Read() = %+v, want %+v", got, want)
+	}
+	// 空的 ID3v2 标签同样回退到 ID3v1
+	if got := readAudio(t, append(append(buildID3(3, 0, 16), data...), tag...)); got != want {
+		t.Errorf("Read() with empty ID3v2 = %+v, want %+v", got, want)
+	}
+}
+
+func TestRead_FLAC(t *testing.T) {
+	flac := buildFLAC("title=Song", "ARTIST=Artist", "ALBUM ARTIST=Various", "Album=Album", "TRACKNUMBER=04/10", "DISCNUMBER=1", "ARTIST=Second")
+	want := AudioTags{Title: "Song", Artist: "Artist", AlbumArtist: "Various", Album: "Album", Track: 4, Disc: 1}
+
+	if got := readAudio(t, flac); got != want {
+		t.Errorf("Read() = %+v, want %+v", got, want)
+	}
+	// 以 ID3v2 标签开头的 FLAC
+	if got := readAudio(t, append(buildID3(3, 0, 8), flac...)); got != want {
+		t.Errorf("Read() with ID3v2 = %+v, want %+v", got, want)
+	}
+}
+
+func TestRead_Ogg(t *testing.T) {
+	comment := append([]byte("\x03vorbis"), vorbisComment("TITLE=Song", "ARTIST="+strings.Repeat("a", 300))...)
+	// 注释头跨越两页，另一个逻辑流的页夹在中间
+	data := bytes.Join([][]byte{
+		oggPage(1, []byte("\x01vorbis header"), true),
+		oggPage(1, comment[:255], false),
+		oggPage(2, []byte("other"), true),
+		oggPage(1, comment[255:], true),
+	}, nil)
+
+	got := readAudio(t, data)
+	if got.Title != "Song" || got.Artist != strings.Repeat("a", 300) {
+		t.Errorf("Read() = %+v", got)
+	}
+
+	opus := bytes.Join([][]byte{
+		oggPage(7, []byte("OpusHead"), true),
+		oggPage(7, append([]byte("OpusTags"), vorbisComment("ALBUM=Album")...), true),
+	}, nil)
+	if got := readAudio(t, opus); got.Album != "Album" {
+		t.Errorf("Read() opus = %+v", got)
+	}
+}
+
+func TestRead_M4A(t *testing.T) {
+	data := buildM4A(
+		m4aItem("\xa9nam", []byte("Song")),
+		m4aItem("\xa9ART", []byte("Artist")),
+		m4aItem("aART", []byte("Band")),
+		m4aItem("\xa9alb", []byte("Album")),
+		m4aItem("trkn", []byte{0, 0, 0, 9, 0, 12, 0, 0}),
+		m4aItem("disk", []byte{0, 0, 0, 2, 0, 2}),
+		m4aItem("covr", make([]byte, 64)),
+	)
+
+	want := AudioTags{Title: "Song", Artist: "Artist", AlbumArtist: "Band", Album: "Album", Track: 9, Disc: 2}
+	if got := readAudio(t, data); got != want {
+		t.Errorf("Read() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRead_Untagged(t *testing.T) {
+	for _, data := range [][]byte{
+		buildFLAC(),
+		append([]byte{0xFF, 0xFB, 0x90, 0x00}, make([]byte, 200)...),
+		buildID3(4, 0, 32),
+	} {
+		if meta := read(t, data); meta.Audio != nil {
+			t.Errorf("Expected no audio tags, got %+v", *meta.Audio)
+		}
+	}
+}
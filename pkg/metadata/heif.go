@@ -0,0 +1,350 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// maxMetaBoxSize 限制读入内存的 HEIF meta box 大小
+const maxMetaBoxSize = 16 << 20
+
+// byteReader 按大端顺序读取字节切片，越界后所有读取返回零值并记录 ErrInvalid
+type byteReader struct {
+	buf []byte
+	pos int
+	err error
+}
+
+func (b *byteReader) bytes(n int) []byte {
+	if b.err != nil {
+		return nil
+	}
+	if n < 0 || b.pos+n > len(b.buf) {
+		b.err = ErrInvalid
+		return nil
+	}
+	v := b.buf[b.pos : b.pos+n]
+	b.pos += n
+	return v
+}
+
+func (b *byteReader) u8() uint8 {
+	if v := b.bytes(1); v != nil {
+		return v[0]
+	}
+	return 0
+}
+
+func (b *byteReader) u16() uint16 {
+	if v := b.bytes(2); v != nil {
+		return binary.BigEndian.Uint16(v)
+	}
+	return 0
+}
+
+func (b *byteReader) u32() uint32 {
+	if v := b.bytes(4); v != nil {
+		return binary.BigEndian.Uint32(v)
+	}
+	return 0
+}
+
+// uintN 读取 n 字节的无符号整数，n 只能是 0、4 或 8
+func (b *byteReader) uintN(n int) uint64 {
+	switch n {
+	case 0:
+		return 0
+	case 4:
+		return uint64(b.u32())
+	case 8:
+		if v := b.bytes(8); v != nil {
+			return binary.BigEndian.Uint64(v)
+		}
+		return 0
+	}
+	b.err = ErrInvalid
+	return 0
+}
+
+// fullBox 读取 full box 的版本和标志
+func (b *byteReader) fullBox() (byte, uint32) {
+	v := b.u32()
+	return byte(v >> 24), v & 0xFFFFFF
+}
+
+// memBox 是已读入内存的 box
+type memBox struct {
+	typ  string
+	data []byte
+}
+
+// parseMemBoxes 解析内存中的同级 box
+func parseMemBoxes(data []byte) ([]memBox, error) {
+	var boxes []memBox
+	for b := (&byteReader{buf: data}); b.pos+8 <= len(data); {
+		if len(boxes) >= maxBoxes {
+			return nil, ErrInvalid
+		}
+		start := b.pos
+		size := uint64(b.u32())
+		typ := string(b.bytes(4))
+		switch size {
+		case 0:
+			size = uint64(len(data) - start)
+		case 1:
+			size = b.uintN(8)
+		}
+		if b.err != nil || size < uint64(b.pos-start) || uint64(start)+size > uint64(len(data)) {
+			return nil, ErrInvalid
+		}
+		boxes = append(boxes, memBox{typ: typ, data: data[b.pos : start+int(size)]})
+		b.pos = start + int(size)
+	}
+	return boxes, nil
+}
+
+func findMemBox(boxes []memBox, typ string) (memBox, bool) {
+	for _, b := range boxes {
+		if b.typ == typ {
+			return b, true
+		}
+	}
+	return memBox{}, false
+}
+
+// extent 是项数据在文件中的位置
+type extent struct {
+	offset uint64
+	length uint64
+}
+
+// readHEIF 读取 HEIF 图片 meta box 中的 Exif 项和主图像尺寸
+func readHEIF(r io.ReaderAt, top []box, meta *Metadata) error {
+	metaBox, ok := findBox(top, "meta")
+	if !ok {
+		return nil
+	}
+	if metaBox.size < 4 || metaBox.size > maxMetaBoxSize {
+		return ErrInvalid
+	}
+	data := make([]byte, metaBox.size)
+	if _, err := r.ReadAt(data, metaBox.offset); err != nil {
+		return err
+	}
+
+	// meta 是 full box，子 box 从版本和标志之后开始
+	children, err := parseMemBoxes(data[4:])
+	if err != nil {
+		return err
+	}
+
+	var primary uint32
+	if pitm, ok := findMemBox(children, "pitm"); ok {
+		b := &byteReader{buf: pitm.data}
+		if version, _ := b.fullBox(); version == 0 {
+			primary = uint32(b.u16())
+		} else {
+			primary = b.u32()
+		}
+		if b.err != nil {
+			return b.err
+		}
+	}
+
+	if err := readHEIFExif(r, children, meta); err != nil {
+		return err
+	}
+
+	if iprp, ok := findMemBox(children, "iprp"); ok {
+		width, height, err := primarySize(iprp.data, primary)
+		if err != nil {
+			return err
+		}
+		if width > 0 && height > 0 {
+			meta.Width, meta.Height = width, height
+		}
+	}
+
+	return nil
+}
+
+// readHEIFExif 通过 iinf 找到 Exif 项，按 iloc 中的位置读取 EXIF
+func readHEIFExif(r io.ReaderAt, children []memBox, meta *Metadata) error {
+	iinf, ok := findMemBox(children, "iinf")
+	if !ok {
+		return nil
+	}
+	id, ok, err := exifItem(iinf.data)
+	if err != nil || !ok {
+		return err
+	}
+
+	iloc, ok := findMemBox(children, "iloc")
+	if !ok {
+		return nil
+	}
+	locations, err := itemLocations(iloc.data)
+	if err != nil {
+		return err
+	}
+	loc, ok := locations[id]
+	if !ok || loc.length < 4+8 {
+		return nil
+	}
+
+	// Exif 项以 4 字节的 TIFF 头偏移开始，之后通常是 "Exif\0\0" 和 TIFF 结构
+	buf := make([]byte, 4)
+	if _, err := r.ReadAt(buf, int64(loc.offset)); err != nil {
+		return err
+	}
+	headerOffset := uint64(binary.BigEndian.Uint32(buf))
+	if 4+headerOffset >= loc.length {
+		return ErrInvalid
+	}
+	return readTIFF(r, int64(loc.offset+4+headerOffset), int64(loc.length-4-headerOffset), meta)
+}
+
+// exifItem 在 iinf 中查找类型为 Exif 的项
+func exifItem(data []byte) (uint32, bool, error) {
+	b := &byteReader{buf: data}
+	if version, _ := b.fullBox(); version == 0 {
+		b.u16()
+	} else {
+		b.u32()
+	}
+	if b.err != nil {
+		return 0, false, b.err
+	}
+
+	entries, err := parseMemBoxes(data[b.pos:])
+	if err != nil {
+		return 0, false, err
+	}
+	for _, entry := range entries {
+		if entry.typ != "infe" {
+			continue
+		}
+		e := &byteReader{buf: entry.data}
+		version, _ := e.fullBox()
+		if version < 2 {
+			continue
+		}
+		var id uint32
+		if version == 2 {
+			id = uint32(e.u16())
+		} else {
+			id = e.u32()
+		}
+		e.u16() // item_protection_index
+		if typ := e.bytes(4); e.err == nil && string(typ) == "Exif" {
+			return id, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// itemLocations 解析 iloc，返回存放在文件中且只有一个区段的项的位置
+func itemLocations(data []byte) (map[uint32]extent, error) {
+	b := &byteReader{buf: data}
+	version, _ := b.fullBox()
+	sizes := b.u16()
+	offsetSize := int(sizes >> 12)
+	lengthSize := int(sizes >> 8 & 0xF)
+	baseOffsetSize := int(sizes >> 4 & 0xF)
+	indexSize := 0
+	if version == 1 || version == 2 {
+		indexSize = int(sizes & 0xF)
+	}
+
+	var count uint32
+	if version < 2 {
+		count = uint32(b.u16())
+	} else {
+		count = b.u32()
+	}
+
+	locations := make(map[uint32]extent)
+	for i := uint32(0); i < count && b.err == nil; i++ {
+		var id uint32
+		if version < 2 {
+			id = uint32(b.u16())
+		} else {
+			id = b.u32()
+		}
+		method := 0
+		if version == 1 || version == 2 {
+			method = int(b.u16() & 0xF)
+		}
+		b.u16() // data_reference_index
+		base := b.uintN(baseOffsetSize)
+
+		extents := b.u16()
+		var first extent
+		for j := uint16(0); j < extents && b.err == nil; j++ {
+			b.uintN(indexSize)
+			offset := b.uintN(offsetSize)
+			length := b.uintN(lengthSize)
+			if j == 0 {
+				first = extent{offset: base + offset, length: length}
+			}
+		}
+		// 只支持直接存放在文件中（construction_method 0）且连续的项
+		if method == 0 && extents == 1 {
+			locations[id] = first
+		}
+	}
+
+	return locations, b.err
+}
+
+// primarySize 从 iprp 中读取主图像关联的 ispe 尺寸
+func primarySize(data []byte, primary uint32) (int, int, error) {
+	children, err := parseMemBoxes(data)
+	if err != nil {
+		return 0, 0, err
+	}
+	ipco, ok1 := findMemBox(children, "ipco")
+	ipma, ok2 := findMemBox(children, "ipma")
+	if !ok1 || !ok2 {
+		return 0, 0, nil
+	}
+	properties, err := parseMemBoxes(ipco.data)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	b := &byteReader{buf: ipma.data}
+	version, flags := b.fullBox()
+	count := b.u32()
+	for i := uint32(0); i < count && b.err == nil; i++ {
+		var id uint32
+		if version < 1 {
+			id = uint32(b.u16())
+		} else {
+			id = b.u32()
+		}
+		n := int(b.u8())
+		for j := 0; j < n && b.err == nil; j++ {
+			// 最高位是 essential 标志，其余为从 1 开始的属性索引
+			var index int
+			if flags&1 != 0 {
+				index = int(b.u16() & 0x7FFF)
+			} else {
+				index = int(b.u8() & 0x7F)
+			}
+			if id != primary || index < 1 || index > len(properties) || properties[index-1].typ != "ispe" {
+				continue
+			}
+
+			p := &byteReader{buf: properties[index-1].data}
+			p.fullBox()
+			width, height := p.u32(), p.u32()
+			if p.err != nil {
+				return 0, 0, p.err
+			}
+			return int(width), int(height), nil
+		}
+	}
+
+	return 0, 0, b.err
+}
@@ -0,0 +1,275 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// id3Fields 是 ID3v2 文本帧，v2.2 使用 3 字符的帧 ID
+var id3Fields = map[string]string{
+	"TIT2": tagTitle, "TT2": tagTitle,
+	"TPE1": tagArtist, "TP1": tagArtist,
+	"TPE2": tagAlbumArtist, "TP2": tagAlbumArtist,
+	"TALB": tagAlbum, "TAL": tagAlbum,
+	"TRCK": tagTrack, "TRK": tagTrack,
+	"TPOS": tagDisc, "TPA": tagDisc,
+}
+
+// ID3v2 标签头和帧的标志
+const (
+	id3Unsync         = 0x80
+	id3ExtendedHeader = 0x40
+	id3Footer         = 0x10
+
+	id3v4FrameGrouping   = 0x40
+	id3v4FrameCompressed = 0x08
+	id3v4FrameEncrypted  = 0x04
+	id3v4FrameUnsync     = 0x02
+	id3v4FrameLength     = 0x01
+
+	id3v3FrameCompressed = 0x80
+	id3v3FrameEncrypted  = 0x40
+	id3v3FrameGrouping   = 0x20
+)
+
+// synchsafe 解码每字节只用低 7 位的整数
+func synchsafe(b []byte) (int64, bool) {
+	var n int64
+	for _, c := range b {
+		if c&0x80 != 0 {
+			return 0, false
+		}
+		n = n<<7 | int64(c)
+	}
+	return n, true
+}
+
+// unsynchronise 还原反同步处理：去掉 0xFF 之后插入的 0x00
+func unsynchronise(data []byte) []byte {
+	out := data[:0:0]
+	for i := 0; i < len(data); i++ {
+		out = append(out, data[i])
+		if data[i] == 0xFF && i+1 < len(data) && data[i+1] == 0x00 {
+			i++
+		}
+	}
+	return out
+}
+
+// readID3 读取文件开头的 ID3v2 标签，标签之后是 FLAC 流时继续读取 Vorbis 注释，
+// 没有 ID3v2 文本帧时尝试文件末尾的 ID3v1 标签
+func readID3(r io.ReaderAt, size int64, meta *Metadata) error {
+	header := make([]byte, 10)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return err
+	}
+	major, flags := header[3], header[5]
+	tagSize, ok := synchsafe(header[6:10])
+	if !ok || 10+tagSize > size {
+		return ErrInvalid
+	}
+	end := 10 + tagSize
+
+	tags := meta.audio()
+	if major >= 2 && major <= 4 {
+		var src io.ReaderAt = r
+		start, stop := int64(10), end
+		if flags&id3Unsync != 0 && major < 4 {
+			// v2.2 和 v2.3 对整个标签做反同步处理，需要读入内存还原
+			if tagSize > maxTagSize {
+				return nil
+			}
+			buf := make([]byte, tagSize)
+			if _, err := r.ReadAt(buf, 10); err != nil {
+				return err
+			}
+			buf = unsynchronise(buf)
+			src, start, stop = bytes.NewReader(buf), 0, int64(len(buf))
+		}
+
+		if flags&id3ExtendedHeader != 0 && major >= 3 {
+			buf := make([]byte, 4)
+			if _, err := src.ReadAt(buf, start); err != nil {
+				return err
+			}
+			if major == 3 {
+				// v2.3 的扩展头大小不含自身的 4 字节
+				start += 4 + int64(binary.BigEndian.Uint32(buf))
+			} else {
+				n, ok := synchsafe(buf)
+				if !ok {
+					return ErrInvalid
+				}
+				start += n
+			}
+		}
+
+		if err := readID3Frames(src, start, stop, major, tags); err != nil {
+			return err
+		}
+	}
+
+	if flags&id3Footer != 0 && major == 4 {
+		end += 10
+	}
+	magic := make([]byte, 4)
+	if end+4 <= size {
+		if _, err := r.ReadAt(magic, end); err != nil {
+			return err
+		}
+		if string(magic) == "fLaC" {
+			return readFLAC(r, end, size, meta)
+		}
+	}
+
+	if tags.isZero() {
+		return readID3v1(r, size, meta)
+	}
+	return nil
+}
+
+// readID3Frames 读取 [start, stop) 范围内的 ID3v2 帧
+func readID3Frames(r io.ReaderAt, start, stop int64, major byte, tags *AudioTags) error {
+	idLen, headerLen := 4, int64(10)
+	if major == 2 {
+		idLen, headerLen = 3, 6
+	}
+
+	header := make([]byte, headerLen)
+	for offset := start; offset+headerLen <= stop; {
+		if _, err := r.ReadAt(header, offset); err != nil {
+			return err
+		}
+		if header[0] == 0 {
+			// 填充区
+			return nil
+		}
+
+		id := string(header[:idLen])
+		var n int64
+		switch major {
+		case 2:
+			n = int64(header[3])<<16 | int64(header[4])<<8 | int64(header[5])
+		case 3:
+			n = int64(binary.BigEndian.Uint32(header[4:8]))
+		default:
+			var ok bool
+			if n, ok = synchsafe(header[4:8]); !ok {
+				return ErrInvalid
+			}
+		}
+		body := offset + headerLen
+		if n <= 0 || body+n > stop {
+			return ErrInvalid
+		}
+		offset = body + n
+
+		field := id3Fields[id]
+		if field == "" || n > maxTextSize {
+			continue
+		}
+		data := make([]byte, n)
+		if _, err := r.ReadAt(data, body); err != nil {
+			return err
+		}
+
+		switch frameFlags := header[headerLen-1]; major {
+		case 3:
+			if frameFlags&(id3v3FrameCompressed|id3v3FrameEncrypted) != 0 {
+				continue
+			}
+			if frameFlags&id3v3FrameGrouping != 0 && len(data) > 0 {
+				data = data[1:]
+			}
+		case 4:
+			if frameFlags&(id3v4FrameCompressed|id3v4FrameEncrypted) != 0 {
+				continue
+			}
+			if frameFlags&id3v4FrameGrouping != 0 && len(data) > 0 {
+				data = data[1:]
+			}
+			if frameFlags&id3v4FrameLength != 0 && len(data) >= 4 {
+				data = data[4:]
+			}
+			if frameFlags&id3v4FrameUnsync != 0 {
+				data = unsynchronise(data)
+			}
+		}
+
+		if len(data) > 0 {
+			tags.set(field, decodeID3Text(data[0], data[1:]))
+		}
+	}
+
+	return nil
+}
+
+// decodeID3Text 按编码字节解码文本帧，多个值时只取第一个
+func decodeID3Text(encoding byte, data []byte) string {
+	var s string
+	switch encoding {
+	case 1, 2:
+		// 1 为带 BOM 的 UTF-16，2 为 UTF-16BE
+		order := binary.ByteOrder(binary.BigEndian)
+		if encoding == 1 && len(data) >= 2 {
+			switch {
+			case data[0] == 0xFF && data[1] == 0xFE:
+				order, data = binary.LittleEndian, data[2:]
+			case data[0] == 0xFE && data[1] == 0xFF:
+				data = data[2:]
+			}
+		}
+		units := make([]uint16, 0, len(data)/2)
+		for i := 0; i+1 < len(data); i += 2 {
+			units = append(units, order.Uint16(data[i:]))
+		}
+		s = string(utf16.Decode(units))
+	case 3:
+		s = string(data)
+	default:
+		s = latin1(data)
+	}
+
+	s, _, _ = strings.Cut(s, "\x00")
+	return s
+}
+
+// latin1 将 ISO-8859-1 编码的字节转换为字符串
+func latin1(data []byte) string {
+	runes := make([]rune, len(data))
+	for i, c := range data {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// readID3v1 读取文件末尾 128 字节的 ID3v1 标签
+func readID3v1(r io.ReaderAt, size int64, meta *Metadata) error {
+	if size < 128 {
+		return nil
+	}
+	buf := make([]byte, 128)
+	if _, err := r.ReadAt(buf, size-128); err != nil {
+		return err
+	}
+	if string(buf[:3]) != "TAG" {
+		return nil
+	}
+
+	text := func(b []byte) string {
+		s, _, _ := strings.Cut(latin1(b), "\x00")
+		return s
+	}
+	tags := meta.audio()
+	tags.set(tagTitle, text(buf[3:33]))
+	tags.set(tagArtist, text(buf[33:63]))
+	tags.set(tagAlbum, text(buf[63:93]))
+	// ID3v1.1 在注释的最后两个字节中保存音轨号
+	if buf[125] == 0 && buf[126] != 0 && tags.Track == 0 {
+		tags.Track = int(buf[126])
+	}
+	return nil
+}
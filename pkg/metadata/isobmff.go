@@ -0,0 +1,236 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"strconv"
+	"time"
+)
+
+// maxBoxes 限制遍历的 box 数量，避免损坏的文件导致死循环
+const maxBoxes = 4096
+
+// mp4EpochOffset 是 MP4/MOV 时间起点 1904-01-01 与 Unix 时间起点相差的秒数
+const mp4EpochOffset = 2082844800
+
+// box 是 ISO 基础媒体文件格式中的一个 box，offset 和 size 不含头部
+type box struct {
+	typ    string
+	offset int64
+	size   int64
+}
+
+// readBoxes 读取 [start, end) 范围内的同级 box
+func readBoxes(r io.ReaderAt, start, end int64) ([]box, error) {
+	var boxes []box
+	header := make([]byte, 16)
+
+	for offset := start; offset+8 <= end; {
+		if len(boxes) >= maxBoxes {
+			return nil, ErrInvalid
+		}
+		if _, err := r.ReadAt(header[:8], offset); err != nil {
+			return nil, err
+		}
+
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		typ := string(header[4:8])
+		headerSize := int64(8)
+		switch size {
+		case 0:
+			// box 延伸到文件末尾
+			size = end - offset
+		case 1:
+			if _, err := r.ReadAt(header[8:16], offset+8); err != nil {
+				return nil, err
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerSize = 16
+		}
+		if size < headerSize || offset+size > end {
+			return nil, ErrInvalid
+		}
+
+		boxes = append(boxes, box{typ: typ, offset: offset + headerSize, size: size - headerSize})
+		offset += size
+	}
+
+	return boxes, nil
+}
+
+// findBox 返回第一个指定类型的 box
+func findBox(boxes []box, typ string) (box, bool) {
+	for _, b := range boxes {
+		if b.typ == typ {
+			return b, true
+		}
+	}
+	return box{}, false
+}
+
+// heifBrands 是 HEIF 图片（HEIC、AVIF 等）的 ftyp 品牌
+var heifBrands = map[string]bool{
+	"heic": true, "heix": true, "heim": true, "heis": true,
+	"hevc": true, "hevx": true, "mif1": true, "msf1": true, "avif": true,
+}
+
+// readISOBMFF 按 ftyp 品牌区分 HEIF 图片和 MP4/MOV 视频
+func readISOBMFF(r io.ReaderAt, size int64, meta *Metadata) error {
+	top, err := readBoxes(r, 0, size)
+	if err != nil {
+		return err
+	}
+
+	ftyp, ok := findBox(top, "ftyp")
+	if ok && ftyp.size >= 8 && ftyp.size <= 1024 {
+		buf := make([]byte, ftyp.size)
+		if _, err := r.ReadAt(buf, ftyp.offset); err != nil {
+			return err
+		}
+		// major_brand(4) minor_version(4) compatible_brands(4*n)
+		brands := [][]byte{buf[:4]}
+		for i := 8; i+4 <= len(buf); i += 4 {
+			brands = append(brands, buf[i:i+4])
+		}
+		for _, brand := range brands {
+			if heifBrands[string(brand)] {
+				return readHEIF(r, top, meta)
+			}
+		}
+	}
+
+	return readMovie(r, top, meta)
+}
+
+// readMovie 读取 moov/mvhd 中的创建时间和 moov/udta 中的 iTunes 风格标签
+func readMovie(r io.ReaderAt, top []box, meta *Metadata) error {
+	moov, ok := findBox(top, "moov")
+	if !ok {
+		return nil
+	}
+	children, err := readBoxes(r, moov.offset, moov.offset+moov.size)
+	if err != nil {
+		return err
+	}
+	if err := readMP4Tags(r, children, meta); err != nil {
+		return err
+	}
+	mvhd, ok := findBox(children, "mvhd")
+	if !ok {
+		return nil
+	}
+
+	// version(1) flags(3)，版本 1 使用 64 位时间
+	buf := make([]byte, 12)
+	if mvhd.size < 8 {
+		return ErrInvalid
+	}
+	n := 8
+	if mvhd.size >= 12 {
+		n = 12
+	}
+	if _, err := r.ReadAt(buf[:n], mvhd.offset); err != nil {
+		return err
+	}
+
+	var seconds uint64
+	if buf[0] == 1 {
+		if n < 12 {
+			return ErrInvalid
+		}
+		seconds = binary.BigEndian.Uint64(buf[4:12])
+	} else {
+		seconds = uint64(binary.BigEndian.Uint32(buf[4:8]))
+	}
+	if seconds == 0 {
+		// 未设置创建时间
+		return nil
+	}
+	if seconds > math.MaxInt64 {
+		return ErrInvalid
+	}
+
+	meta.Created = time.Unix(int64(seconds)-mp4EpochOffset, 0).UTC()
+	meta.CreatedSource = SourceContainer
+	return nil
+}
+
+// mp4Fields 是 ilst 中的标签，© 编码为 0xA9
+var mp4Fields = map[string]string{
+	"\xa9nam": tagTitle,
+	"\xa9ART": tagArtist,
+	"aART":    tagAlbumArtist,
+	"\xa9alb": tagAlbum,
+	"trkn":    tagTrack,
+	"disk":    tagDisc,
+}
+
+// readMP4Tags 读取 moov/udta/meta/ilst 中的标签
+func readMP4Tags(r io.ReaderAt, moovChildren []box, meta *Metadata) error {
+	udta, ok := findBox(moovChildren, "udta")
+	if !ok {
+		return nil
+	}
+	children, err := readBoxes(r, udta.offset, udta.offset+udta.size)
+	if err != nil {
+		return err
+	}
+	metaBox, ok := findBox(children, "meta")
+	if !ok || metaBox.size < 12 {
+		return nil
+	}
+
+	// ISO 格式的 meta 是 full box，QuickTime 格式没有版本和标志
+	start := metaBox.offset
+	head := make([]byte, 12)
+	if _, err := r.ReadAt(head, start); err != nil {
+		return err
+	}
+	if string(head[4:8]) != "hdlr" {
+		start += 4
+	}
+	children, err = readBoxes(r, start, metaBox.offset+metaBox.size)
+	if err != nil {
+		return err
+	}
+	ilst, ok := findBox(children, "ilst")
+	if !ok {
+		return nil
+	}
+	items, err := readBoxes(r, ilst.offset, ilst.offset+ilst.size)
+	if err != nil {
+		return err
+	}
+
+	tags := meta.audio()
+	for _, item := range items {
+		field := mp4Fields[item.typ]
+		if field == "" || item.size > maxTextSize {
+			continue
+		}
+		data := make([]byte, item.size)
+		if _, err := r.ReadAt(data, item.offset); err != nil {
+			return err
+		}
+		boxes, err := parseMemBoxes(data)
+		if err != nil {
+			return err
+		}
+		value, ok := findMemBox(boxes, "data")
+		// 类型指示(4) 语言(4) 值
+		if !ok || len(value.data) < 8 {
+			continue
+		}
+		v := value.data[8:]
+		if field == tagTrack || field == tagDisc {
+			// 保留(2) 序号(2) 总数(2)
+			if len(v) >= 4 {
+				tags.set(field, strconv.Itoa(int(binary.BigEndian.Uint16(v[2:4]))))
+			}
+			continue
+		}
+		tags.set(field, string(v))
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// JPEG 标记
+const (
+	markerSOI  = 0xD8
+	markerEOI  = 0xD9
+	markerSOS  = 0xDA
+	markerAPP1 = 0xE1
+)
+
+var exifHeader = []byte("Exif\x00\x00")
+
+// isSOF 判断是否为帧起始标记 SOF0-SOF15，排除 DHT、JPG 和 DAC
+func isSOF(marker byte) bool {
+	return marker >= 0xC0 && marker <= 0xCF && marker != 0xC4 && marker != 0xC8 && marker != 0xCC
+}
+
+// readJPEG 读取 SOS 之前的 APP1 EXIF 段和帧起始段中的图像尺寸
+func readJPEG(r io.ReaderAt, size int64, meta *Metadata) error {
+	buf := make([]byte, 4)
+	if _, err := r.ReadAt(buf[:2], 0); err != nil {
+		return err
+	}
+	if buf[0] != 0xFF || buf[1] != markerSOI {
+		return ErrInvalid
+	}
+
+	offset := int64(2)
+	for offset+4 <= size {
+		if _, err := r.ReadAt(buf, offset); err != nil {
+			return err
+		}
+		if buf[0] != 0xFF {
+			return ErrInvalid
+		}
+
+		marker := buf[1]
+		switch {
+		case marker == 0xFF:
+			// 填充字节
+			offset++
+			continue
+		case marker == markerSOS || marker == markerEOI:
+			return nil
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7):
+			// 没有长度字段的独立标记
+			offset += 2
+			continue
+		}
+
+		length := int64(binary.BigEndian.Uint16(buf[2:4]))
+		if length < 2 || offset+2+length > size {
+			return ErrInvalid
+		}
+
+		switch {
+		case marker == markerAPP1 && length >= 2+int64(len(exifHeader))+8:
+			header := make([]byte, len(exifHeader))
+			if _, err := r.ReadAt(header, offset+4); err != nil {
+				return err
+			}
+			if bytes.Equal(header, exifHeader) {
+				base := offset + 4 + int64(len(exifHeader))
+				if err := readTIFF(r, base, length-2-int64(len(exifHeader)), meta); err != nil {
+					return err
+				}
+			}
+		case isSOF(marker) && length >= 7:
+			// 精度(1) 高度(2) 宽度(2)，以实际编码的尺寸为准
+			frame := make([]byte, 5)
+			if _, err := r.ReadAt(frame, offset+4); err != nil {
+				return err
+			}
+			meta.Height = int(binary.BigEndian.Uint16(frame[1:3]))
+			meta.Width = int(binary.BigEndian.Uint16(frame[3:5]))
+		}
+
+		offset += 2 + length
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+// Package metadata 从图片和视频文件中读取拍摄时间、相机、方向、GPS 和尺寸等元数据，
+// 支持 JPEG、TIFF（含 TIFF 类 RAW）、HEIC/HEIF 和 MP4/MOV；从音频文件中读取艺术家、专辑等标签，
+// 支持 MP3（ID3v1/ID3v2）、FLAC、Ogg Vorbis/Opus 和 M4A，只使用标准库解析
+package metadata
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Source 表示时间信息的来源
+type Source string
+
+const (
+	// SourceEXIF 表示来自 EXIF 的 DateTimeOriginal
+	SourceEXIF Source = "exif"
+	// SourceContainer 表示来自 MP4/MOV 容器的创建时间
+	SourceContainer Source = "container"
+)
+
+// ErrInvalid 表示文件结构损坏或不符合格式
+var ErrInvalid = errors.New("元数据格式无效")
+
+// Metadata 是从文件内容读取的元数据，未找到的字段为零值
+type Metadata struct {
+	Created       time.Time  // 拍摄或创建时间
+	CreatedSource Source     // Created 的来源
+	Make          string     // 相机厂商
+	Model         string     // 相机型号
+	Orientation   int        // EXIF 方向（1-8），0 表示未知
+	Width         int        // 图像宽度（像素）
+	Height        int        // 图像高度（像素）
+	GPS           *GPS       // 拍摄位置，没有时为 nil
+	Audio         *AudioTags // 音频标签，没有时为 nil
+}
+
+// GPS 是拍摄位置，经纬度为十进制度数，南纬和西经为负
+type GPS struct {
+	Latitude  float64
+	Longitude float64
+	Altitude  float64 // 海拔（米），低于海平面为负
+}
+
+// IsZero 判断是否没有读取到任何元数据
+func (m *Metadata) IsZero() bool {
+	return m.Created.IsZero() && m.Make == "" && m.Model == "" && m.Orientation == 0 &&
+		m.Width == 0 && m.Height == 0 && m.GPS == nil && m.Audio == nil
+}
+
+// Camera 返回相机名称，型号中已包含厂商名（如 NIKON CORPORATION 的 NIKON D750）时不重复厂商
+func (m *Metadata) Camera() string {
+	maker, model := strings.TrimSpace(m.Make), strings.TrimSpace(m.Model)
+	if model == "" || maker == "" {
+		return maker + model
+	}
+	if brand := strings.Fields(maker)[0]; strings.HasPrefix(strings.ToLower(model), strings.ToLower(brand)) {
+		return model
+	}
+	return maker + " " + model
+}
+
+// Read 按文件开头的魔数识别格式并读取元数据，不支持的格式返回空的 Metadata
+func Read(r io.ReaderAt, size int64) (*Metadata, error) {
+	meta := &Metadata{}
+
+	head := make([]byte, 12)
+	n, err := r.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return meta, err
+	}
+	head, err = head[:n], nil
+
+	switch {
+	case bytes.HasPrefix(head, []byte{0xFF, 0xD8, 0xFF}):
+		err = readJPEG(r, size, meta)
+	case bytes.HasPrefix(head, []byte("II*\x00")) || bytes.HasPrefix(head, []byte("MM\x00*")):
+		err = readTIFF(r, 0, size, meta)
+	case len(head) >= 8 && string(head[4:8]) == "ftyp":
+		err = readISOBMFF(r, size, meta)
+	case bytes.HasPrefix(head, []byte("ID3")) && len(head) >= 10:
+		err = readID3(r, size, meta)
+	case bytes.HasPrefix(head, []byte("fLaC")):
+		err = readFLAC(r, 0, size, meta)
+	case bytes.HasPrefix(head, []byte("OggS")):
+		err = readOgg(r, size, meta)
+	case len(head) >= 2 && head[0] == 0xFF && head[1]&0xE0 == 0xE0:
+		// 没有 ID3v2 标签的 MPEG 音频帧
+		err = readID3v1(r, size, meta)
+	}
+
+	if meta.Audio != nil && meta.Audio.isZero() {
+		meta.Audio = nil
+	}
+	return meta, err
+}
+
+// ReadFile 读取文件的元数据
+func ReadFile(path string) (*Metadata, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return Read(file, info.Size())
+}
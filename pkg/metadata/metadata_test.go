@@ -0,0 +1,327 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// tiffEntry 是测试用的 IFD 条目，data 为按小端编码的值
+type tiffEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	data  []byte
+}
+
+func asciiEntry(tag uint16, s string) tiffEntry {
+	return tiffEntry{tag, typeASCII, uint32(len(s) + 1), []byte(s + "\x00")}
+}
+
+func shortEntry(tag uint16, v uint16) tiffEntry {
+	return tiffEntry{tag, typeShort, 1, binary.LittleEndian.AppendUint16(nil, v)}
+}
+
+func rationalEntry(tag uint16, values ...[2]uint32) tiffEntry {
+	var data []byte
+	for _, v := range values {
+		data = binary.LittleEndian.AppendUint32(data, v[0])
+		data = binary.LittleEndian.AppendUint32(data, v[1])
+	}
+	return tiffEntry{tag, typeRational, uint32(len(values)), data}
+}
+
+// buildTIFF 构造小端 TIFF 结构，exif 和 gps 不为 nil 时在 IFD0 中添加指向它们的指针
+func buildTIFF(ifd0, exif, gps []tiffEntry) []byte {
+	le := binary.LittleEndian
+	ifds := [][]tiffEntry{append([]tiffEntry{}, ifd0...)}
+	pointers := map[uint16]int{}
+	for _, sub := range []struct {
+		tag     uint16
+		entries []tiffEntry
+	}{{tagExifIFD, exif}, {tagGPSIFD, gps}} {
+		if sub.entries != nil {
+			pointers[sub.tag] = len(ifds)
+			ifds[0] = append(ifds[0], tiffEntry{sub.tag, typeLong, 1, nil})
+			ifds = append(ifds, sub.entries)
+		}
+	}
+
+	offsets := make([]int, len(ifds))
+	offset := 8
+	for i, ifd := range ifds {
+		offsets[i] = offset
+		offset += 2 + 12*len(ifd) + 4
+	}
+
+	var buf, data bytes.Buffer
+	buf.WriteString("II")
+	binary.Write(&buf, le, uint16(42))
+	binary.Write(&buf, le, uint32(8))
+	for _, ifd := range ifds {
+		binary.Write(&buf, le, uint16(len(ifd)))
+		for _, e := range ifd {
+			value := e.data
+			if i, ok := pointers[e.tag]; ok && e.typ == typeLong && e.data == nil {
+				value = le.AppendUint32(nil, uint32(offsets[i]))
+			}
+			binary.Write(&buf, le, []uint16{e.tag, e.typ})
+			binary.Write(&buf, le, e.count)
+			if len(value) > 4 {
+				binary.Write(&buf, le, uint32(offset+data.Len()))
+				data.Write(value)
+			} else {
+				buf.Write(append(value, make([]byte, 4-len(value))...))
+			}
+		}
+		binary.Write(&buf, le, uint32(0))
+	}
+	buf.Write(data.Bytes())
+	return buf.Bytes()
+}
+
+// dateTIFF 构造只包含拍摄时间的 TIFF 结构
+func dateTIFF(date, offset string) []byte {
+	exif := []tiffEntry{asciiEntry(tagDateTimeOriginal, date)}
+	if offset != "" {
+		exif = append(exif, asciiEntry(tagOffsetTimeOriginal, offset))
+	}
+	return buildTIFF(nil, exif, nil)
+}
+
+// buildJPEG 构造带 APP1 EXIF 段和 SOF0 段的 JPEG
+func buildJPEG(tiff []byte, width, height uint16) []byte {
+	var buf bytes.Buffer
+	buf.Write([]byte{0xFF, markerSOI})
+	// 一个无关的 APP0 段
+	buf.Write([]byte{0xFF, 0xE0, 0x00, 0x06, 'J', 'F', 'I', 'F'})
+	buf.Write([]byte{0xFF, markerAPP1})
+	binary.Write(&buf, binary.BigEndian, uint16(2+len(exifHeader)+len(tiff)))
+	buf.Write(exifHeader)
+	buf.Write(tiff)
+	buf.Write([]byte{0xFF, 0xC0, 0x00, 0x08, 0x08})
+	binary.Write(&buf, binary.BigEndian, []uint16{height, width})
+	buf.WriteByte(0)
+	buf.Write([]byte{0xFF, markerSOS, 0x00, 0x02, 0xFF, markerEOI})
+	return buf.Bytes()
+}
+
+// box 构造 ISO 基础媒体文件格式的 box
+func mkbox(typ string, parts ...[]byte) []byte {
+	data := bytes.Join(parts, nil)
+	return append(append(binary.BigEndian.AppendUint32(nil, uint32(8+len(data))), typ...), data...)
+}
+
+func be16(v uint16) []byte { return binary.BigEndian.AppendUint16(nil, v) }
+func be32(v uint32) []byte { return binary.BigEndian.AppendUint32(nil, v) }
+
+// buildMP4 构造包含 moov/mvhd 的 MP4
+func buildMP4(version byte, seconds uint64) []byte {
+	mvhd := []byte{version, 0, 0, 0}
+	if version == 1 {
+		mvhd = binary.BigEndian.AppendUint64(mvhd, seconds)
+	} else {
+		mvhd = append(mvhd, be32(uint32(seconds))...)
+	}
+	mvhd = append(mvhd, make([]byte, 16)...)
+
+	return bytes.Join([][]byte{
+		mkbox("ftyp", []byte("isom\x00\x00\x02\x00")),
+		mkbox("mdat", make([]byte, 32)),
+		mkbox("moov", mkbox("mvhd", mvhd)),
+	}, nil)
+}
+
+// buildHEIC 构造主图像为项 1、Exif 为项 2 的 HEIC
+func buildHEIC(tiff []byte, width, height uint32) []byte {
+	exif := append(append(be32(6), exifHeader...), tiff...)
+	ftyp := mkbox("ftyp", []byte("heic\x00\x00\x00\x00mif1heic"))
+
+	metaBox := func(exifOffset uint32) []byte {
+		return mkbox("meta", be32(0),
+			mkbox("pitm", be32(0), be16(1)),
+			mkbox("iinf", be32(0), be16(2),
+				mkbox("infe", be32(2<<24), be16(1), be16(0), []byte("hvc1\x00")),
+				mkbox("infe", be32(2<<24), be16(2), be16(0), []byte("Exif\x00")),
+			),
+			// offset_size=4 length_size=4 base_offset_size=0
+			mkbox("iloc", be32(0), be16(0x4400), be16(1), be16(2), be16(0), be16(1), be32(exifOffset), be32(uint32(len(exif)))),
+			mkbox("iprp",
+				mkbox("ipco", mkbox("ispe", be32(0), be32(width), be32(height))),
+				mkbox("ipma", be32(0), be32(1), be16(1), []byte{1, 0x81}),
+			),
+		)
+	}
+
+	offset := uint32(len(ftyp) + len(metaBox(0)) + 8)
+	return bytes.Join([][]byte{ftyp, metaBox(offset), mkbox("mdat", exif)}, nil)
+}
+
+func read(t *testing.T, data []byte) *Metadata {
+	t.Helper()
+	meta, err := Read(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	return meta
+}
+
+func TestRead_JPEG(t *testing.T) {
+	meta := read(t, buildJPEG(dateTIFF("2023:07:14 09:30:05", "+08:00"), 640, 480))
+
+	want := time.Date(2023, 7, 14, 9, 30, 5, 0, time.FixedZone("", 8*3600))
+	if !meta.Created.Equal(want) || meta.CreatedSource != SourceEXIF {
+		t.Errorf("Read() = %v (%s), want %v (exif)", meta.Created, meta.CreatedSource, want)
+	}
+	if meta.Width != 640 || meta.Height != 480 {
+		t.Errorf("Expected 640x480 from SOF, got %dx%d", meta.Width, meta.Height)
+	}
+}
+
+func TestRead_TIFF(t *testing.T) {
+	meta := read(t, buildTIFF(
+		[]tiffEntry{
+			asciiEntry(tagMake, "NIKON CORPORATION"),
+			asciiEntry(tagModel, "NIKON D750"),
+			shortEntry(tagOrientation, 6),
+			shortEntry(tagImageWidth, 160),
+			shortEntry(tagImageLength, 120),
+		},
+		[]tiffEntry{
+			asciiEntry(tagDateTimeOriginal, "2021:01:02 03:04:05"),
+			shortEntry(tagPixelXDimension, 6016),
+			shortEntry(tagPixelYDimension, 4016),
+		},
+		[]tiffEntry{
+			asciiEntry(tagGPSLatitudeRef, "S"),
+			rationalEntry(tagGPSLatitude, [2]uint32{33, 1}, [2]uint32{51, 1}, [2]uint32{3600, 100}),
+			asciiEntry(tagGPSLongitudeRef, "E"),
+			rationalEntry(tagGPSLongitude, [2]uint32{151, 1}, [2]uint32{12, 1}, [2]uint32{0, 1}),
+			{tagGPSAltitudeRef, typeByte, 1, []byte{1}},
+			rationalEntry(tagGPSAltitude, [2]uint32{105, 10}),
+		},
+	))
+
+	want := time.Date(2021, 1, 2, 3, 4, 5, 0, time.Local)
+	if !meta.Created.Equal(want) {
+		t.Errorf("Created = %v, want %v", meta.Created, want)
+	}
+	if meta.Camera() != "NIKON D750" || meta.Orientation != 6 {
+		t.Errorf("Camera = %q, Orientation = %d", meta.Camera(), meta.Orientation)
+	}
+	if meta.Width != 6016 || meta.Height != 4016 {
+		t.Errorf("Expected EXIF pixel dimensions 6016x4016, got %dx%d", meta.Width, meta.Height)
+	}
+	if meta.GPS == nil {
+		t.Fatal("Expected GPS position")
+	}
+	if math.Abs(meta.GPS.Latitude-(-33.86)) > 1e-9 || math.Abs(meta.GPS.Longitude-151.2) > 1e-9 || meta.GPS.Altitude != -10.5 {
+		t.Errorf("GPS = %+v", *meta.GPS)
+	}
+}
+
+func TestRead_HEIC(t *testing.T) {
+	tiff := buildTIFF(
+		[]tiffEntry{asciiEntry(tagMake, "Apple"), asciiEntry(tagModel, "iPhone 12"), shortEntry(tagOrientation, 1)},
+		[]tiffEntry{asciiEntry(tagDateTimeOriginal, "2022:08:09 10:11:12"), asciiEntry(tagOffsetTimeOriginal, "-05:00")},
+		nil,
+	)
+	meta := read(t, buildHEIC(tiff, 4032, 3024))
+
+	want := time.Date(2022, 8, 9, 10, 11, 12, 0, time.FixedZone("", -5*3600))
+	if !meta.Created.Equal(want) || meta.CreatedSource != SourceEXIF {
+		t.Errorf("Created = %v (%s), want %v", meta.Created, meta.CreatedSource, want)
+	}
+	if meta.Camera() != "Apple iPhone 12" || meta.Orientation != 1 {
+		t.Errorf("Camera = %q, Orientation = %d", meta.Camera(), meta.Orientation)
+	}
+	if meta.Width != 4032 || meta.Height != 3024 {
+		t.Errorf("Expected 4032x3024 from ispe, got %dx%d", meta.Width, meta.Height)
+	}
+}
+
+func TestRead_EmptyDate(t *testing.T) {
+	meta := read(t, buildJPEG(dateTIFF("0000:00:00 00:00:00", ""), 1, 1))
+	if !meta.Created.IsZero() {
+		t.Errorf("Expected no date, got %v", meta.Created)
+	}
+}
+
+func TestRead_MP4(t *testing.T) {
+	want := time.Date(2022, 5, 6, 7, 8, 9, 0, time.UTC)
+	seconds := uint64(want.Unix() + mp4EpochOffset)
+
+	for _, version := range []byte{0, 1} {
+		meta := read(t, buildMP4(version, seconds))
+		if !meta.Created.Equal(want) || meta.CreatedSource != SourceContainer {
+			t.Errorf("Read() version %d = %v (%s), want %v", version, meta.Created, meta.CreatedSource, want)
+		}
+	}
+}
+
+func TestRead_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"truncated segment", []byte{0xFF, markerSOI, 0xFF, markerAPP1, 0xFF, 0xFF}},
+		{"ifd past end", []byte("II*\x00\xFF\x00\x00\x00")},
+		{"box past end", append(mkbox("ftyp", []byte("isom\x00\x00\x00\x00")), []byte("\x00\x00\x01\x00moov")...)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Read(bytes.NewReader(tt.data), int64(len(tt.data))); err == nil {
+				t.Errorf("Read() should fail for %q", tt.data)
+			}
+		})
+	}
+}
+
+func TestRead_Unsupported(t *testing.T) {
+	for _, data := range [][]byte{nil, []byte("%PDF-1.4")} {
+		meta, err := Read(bytes.NewReader(data), int64(len(data)))
+		if err != nil || !meta.IsZero() {
+			t.Errorf("Read(%q) = %+v, %v; want empty metadata", data, meta, err)
+		}
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.jpg")
+	if err := os.WriteFile(path, buildJPEG(dateTIFF("2023:07:14 09:30:05", ""), 2, 3), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	meta, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if meta.CreatedSource != SourceEXIF || meta.Width != 2 || meta.Height != 3 {
+		t.Errorf("ReadFile() = %+v", meta)
+	}
+}
+
+func TestMetadata_Camera(t *testing.T) {
+	tests := []struct {
+		make, model, want string
+	}{
+		{"Canon", "Canon EOS R5", "Canon EOS R5"},
+		{"NIKON CORPORATION", "NIKON D750", "NIKON D750"},
+		{"Apple", "iPhone 12", "Apple iPhone 12"},
+		{"", "X100V", "X100V"},
+		{"FUJIFILM", "", "FUJIFILM"},
+		{"", "", ""},
+	}
+
+	for _, tt := range tests {
+		m := &Metadata{Make: tt.make, Model: tt.model}
+		if got := m.Camera(); got != tt.want {
+			t.Errorf("Camera(%q, %q) = %q, want %q", tt.make, tt.model, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// TIFF 和 EXIF 标签
+const (
+	tagImageWidth         = 0x0100
+	tagImageLength        = 0x0101
+	tagMake               = 0x010F
+	tagModel              = 0x0110
+	tagOrientation        = 0x0112
+	tagExifIFD            = 0x8769
+	tagGPSIFD             = 0x8825
+	tagDateTimeOriginal   = 0x9003
+	tagOffsetTimeOriginal = 0x9011
+	tagPixelXDimension    = 0xA002
+	tagPixelYDimension    = 0xA003
+)
+
+// GPS IFD 中的标签
+const (
+	tagGPSLatitudeRef  = 0x01
+	tagGPSLatitude     = 0x02
+	tagGPSLongitudeRef = 0x03
+	tagGPSLongitude    = 0x04
+	tagGPSAltitudeRef  = 0x05
+	tagGPSAltitude     = 0x06
+)
+
+// TIFF 字段类型
+const (
+	typeByte     = 1
+	typeASCII    = 2
+	typeShort    = 3
+	typeLong     = 4
+	typeRational = 5
+)
+
+// maxIFDEntries 限制单个 IFD 的条目数，避免损坏的文件导致大量读取
+const maxIFDEntries = 1024
+
+// exifTimeLayout 是 EXIF 日期时间格式
+const exifTimeLayout = "2006:01:02 15:04:05"
+
+// tiffReader 读取从 base 开始、长度为 size 的 TIFF 结构
+type tiffReader struct {
+	r     io.ReaderAt
+	base  int64
+	size  int64
+	order binary.ByteOrder
+}
+
+// ifdEntry 是 IFD 中的一个条目，value 为原始的 4 字节值或偏移
+type ifdEntry struct {
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+// newTIFFReader 解析 TIFF 头，返回读取器和第一个 IFD 的偏移
+func newTIFFReader(r io.ReaderAt, base, size int64) (*tiffReader, uint32, error) {
+	header := make([]byte, 8)
+	if size < 8 {
+		return nil, 0, ErrInvalid
+	}
+	if _, err := r.ReadAt(header, base); err != nil {
+		return nil, 0, err
+	}
+
+	t := &tiffReader{r: r, base: base, size: size}
+	switch string(header[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return nil, 0, ErrInvalid
+	}
+	if t.order.Uint16(header[2:4]) != 42 {
+		return nil, 0, ErrInvalid
+	}
+
+	return t, t.order.Uint32(header[4:8]), nil
+}
+
+// readAt 读取相对 TIFF 头偏移 offset 处的 n 字节
+func (t *tiffReader) readAt(offset uint32, n int) ([]byte, error) {
+	if int64(offset)+int64(n) > t.size {
+		return nil, ErrInvalid
+	}
+	buf := make([]byte, n)
+	if _, err := t.r.ReadAt(buf, t.base+int64(offset)); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// readIFD 读取 offset 处的 IFD，返回按标签索引的条目和下一个 IFD 的偏移
+func (t *tiffReader) readIFD(offset uint32) (map[uint16]ifdEntry, uint32, error) {
+	buf, err := t.readAt(offset, 2)
+	if err != nil {
+		return nil, 0, err
+	}
+	count := int(t.order.Uint16(buf))
+	if count > maxIFDEntries {
+		return nil, 0, ErrInvalid
+	}
+
+	buf, err = t.readAt(offset+2, count*12+4)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	entries := make(map[uint16]ifdEntry, count)
+	for i := 0; i < count; i++ {
+		e := buf[i*12 : i*12+12]
+		entries[t.order.Uint16(e[0:2])] = ifdEntry{
+			typ:   t.order.Uint16(e[2:4]),
+			count: t.order.Uint32(e[4:8]),
+			value: e[8:12],
+		}
+	}
+
+	return entries, t.order.Uint32(buf[count*12:]), nil
+}
+
+// data 返回条目的数据，不超过 4 字节时数据直接存放在条目中
+func (t *tiffReader) data(e ifdEntry, unit int) ([]byte, error) {
+	n := int64(e.count) * int64(unit)
+	if n > t.size {
+		return nil, ErrInvalid
+	}
+	if n <= 4 {
+		return e.value[:n], nil
+	}
+	return t.readAt(t.order.Uint32(e.value), int(n))
+}
+
+// ascii 读取 ASCII 类型的条目，去掉结尾的 NUL 和空白
+func (t *tiffReader) ascii(e ifdEntry) (string, error) {
+	if e.typ != typeASCII {
+		return "", fmt.Errorf("%w: 标签类型 %d 不是 ASCII", ErrInvalid, e.typ)
+	}
+	buf, err := t.data(e, 1)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(strings.TrimRight(string(buf), "\x00")), nil
+}
+
+// uint 读取 SHORT 或 LONG 类型条目的第一个值
+func (t *tiffReader) uint(e ifdEntry) (uint32, error) {
+	switch e.typ {
+	case typeShort:
+		return uint32(t.order.Uint16(e.value)), nil
+	case typeLong:
+		return t.order.Uint32(e.value), nil
+	}
+	return 0, fmt.Errorf("%w: 标签类型 %d 不是整数", ErrInvalid, e.typ)
+}
+
+// rationals 读取 RATIONAL 类型条目的所有值，分母为 0 的值视为 0
+func (t *tiffReader) rationals(e ifdEntry) ([]float64, error) {
+	if e.typ != typeRational {
+		return nil, fmt.Errorf("%w: 标签类型 %d 不是分数", ErrInvalid, e.typ)
+	}
+	buf, err := t.data(e, 8)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]float64, e.count)
+	for i := range values {
+		num := t.order.Uint32(buf[i*8:])
+		den := t.order.Uint32(buf[i*8+4:])
+		if den != 0 {
+			values[i] = float64(num) / float64(den)
+		}
+	}
+	return values, nil
+}
+
+// subIFD 读取指针标签指向的子 IFD，标签不存在时返回 nil
+func (t *tiffReader) subIFD(ifd map[uint16]ifdEntry, tag uint16) (map[uint16]ifdEntry, error) {
+	entry, ok := ifd[tag]
+	if !ok {
+		return nil, nil
+	}
+	offset, err := t.uint(entry)
+	if err != nil {
+		return nil, err
+	}
+	sub, _, err := t.readIFD(offset)
+	return sub, err
+}
+
+// readTIFF 读取 TIFF 结构中的 EXIF 信息，用于 TIFF 类 RAW 文件、JPEG 的 APP1 段和 HEIC 的 Exif 项
+func readTIFF(r io.ReaderAt, base, size int64, meta *Metadata) error {
+	t, offset, err := newTIFFReader(r, base, size)
+	if err != nil {
+		return err
+	}
+
+	ifd0, _, err := t.readIFD(offset)
+	if err != nil {
+		return err
+	}
+
+	if entry, ok := ifd0[tagMake]; ok {
+		meta.Make, _ = t.ascii(entry)
+	}
+	if entry, ok := ifd0[tagModel]; ok {
+		meta.Model, _ = t.ascii(entry)
+	}
+	if entry, ok := ifd0[tagOrientation]; ok {
+		if v, err := t.uint(entry); err == nil && v >= 1 && v <= 8 {
+			meta.Orientation = int(v)
+		}
+	}
+	t.readSize(ifd0, tagImageWidth, tagImageLength, meta)
+
+	// GPS 信息损坏时不影响其他字段
+	if gps, err := t.subIFD(ifd0, tagGPSIFD); err == nil && gps != nil {
+		meta.GPS = t.readGPS(gps)
+	}
+
+	exif, err := t.subIFD(ifd0, tagExifIFD)
+	if err != nil || exif == nil {
+		return err
+	}
+	t.readSize(exif, tagPixelXDimension, tagPixelYDimension, meta)
+
+	return t.readDate(exif, meta)
+}
+
+// readSize 读取宽高标签，两者都存在时覆盖已有的尺寸
+func (t *tiffReader) readSize(ifd map[uint16]ifdEntry, widthTag, heightTag uint16, meta *Metadata) {
+	we, ok1 := ifd[widthTag]
+	he, ok2 := ifd[heightTag]
+	if !ok1 || !ok2 {
+		return
+	}
+	width, err1 := t.uint(we)
+	height, err2 := t.uint(he)
+	if err1 == nil && err2 == nil && width > 0 && height > 0 {
+		meta.Width, meta.Height = int(width), int(height)
+	}
+}
+
+// readGPS 读取 GPS IFD 中的经纬度和海拔，缺少经纬度时返回 nil
+func (t *tiffReader) readGPS(gps map[uint16]ifdEntry) *GPS {
+	lat, err1 := t.coordinate(gps, tagGPSLatitude, tagGPSLatitudeRef, "S")
+	lon, err2 := t.coordinate(gps, tagGPSLongitude, tagGPSLongitudeRef, "W")
+	if err1 != nil || err2 != nil {
+		return nil
+	}
+
+	result := &GPS{Latitude: lat, Longitude: lon}
+	if entry, ok := gps[tagGPSAltitude]; ok {
+		if values, err := t.rationals(entry); err == nil && len(values) > 0 {
+			result.Altitude = values[0]
+			// AltitudeRef 为 1 表示低于海平面
+			if ref, ok := gps[tagGPSAltitudeRef]; ok && ref.typ == typeByte && ref.value[0] == 1 {
+				result.Altitude = -result.Altitude
+			}
+		}
+	}
+	return result
+}
+
+// coordinate 读取以度、分、秒表示的坐标，参考方向为 negative 时取负
+func (t *tiffReader) coordinate(gps map[uint16]ifdEntry, tag, refTag uint16, negative string) (float64, error) {
+	entry, ok := gps[tag]
+	if !ok {
+		return 0, ErrInvalid
+	}
+	values, err := t.rationals(entry)
+	if err != nil {
+		return 0, err
+	}
+	if len(values) != 3 {
+		return 0, ErrInvalid
+	}
+
+	value := values[0] + values[1]/60 + values[2]/3600
+	if ref, ok := gps[refTag]; ok {
+		if s, err := t.ascii(ref); err == nil && strings.EqualFold(s, negative) {
+			value = -value
+		}
+	}
+	return value, nil
+}
+
+// readDate 读取 DateTimeOriginal，存在 OffsetTimeOriginal 时使用其中的时区，否则按本地时间解析
+func (t *tiffReader) readDate(exif map[uint16]ifdEntry, meta *Metadata) error {
+	entry, ok := exif[tagDateTimeOriginal]
+	if !ok {
+		return nil
+	}
+	value, err := t.ascii(entry)
+	if err != nil {
+		return err
+	}
+
+	loc := time.Local
+	if entry, ok := exif[tagOffsetTimeOriginal]; ok {
+		if offset, err := t.ascii(entry); err == nil {
+			if zone, err := time.Parse("-07:00", offset); err == nil {
+				loc = zone.Location()
+			}
+		}
+	}
+
+	created, err := time.ParseInLocation(exifTimeLayout, value, loc)
+	if err != nil {
+		// 部分设备未设置时间时写入 0000:00:00 00:00:00，视为没有拍摄时间
+		return nil
+	}
+
+	meta.Created = created
+	meta.CreatedSource = SourceEXIF
+	return nil
+}
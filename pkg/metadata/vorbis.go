@@ -0,0 +1,117 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// FLAC 元数据块类型
+const flacVorbisComment = 4
+
+// maxOggPages 限制查找注释头时读取的 Ogg 页数
+const maxOggPages = 1024
+
+// readFLAC 读取 start 处 FLAC 流的 VORBIS_COMMENT 元数据块
+func readFLAC(r io.ReaderAt, start, size int64, meta *Metadata) error {
+	header := make([]byte, 4)
+	offset := start + 4
+	for i := 0; i < maxBoxes && offset+4 <= size; i++ {
+		if _, err := r.ReadAt(header, offset); err != nil {
+			return err
+		}
+		last, typ := header[0]&0x80 != 0, header[0]&0x7F
+		length := int64(header[1])<<16 | int64(header[2])<<8 | int64(header[3])
+		if offset+4+length > size {
+			return ErrInvalid
+		}
+
+		if typ == flacVorbisComment && length <= maxTagSize {
+			data := make([]byte, length)
+			if _, err := r.ReadAt(data, offset+4); err != nil {
+				return err
+			}
+			parseVorbisComment(data, meta.audio())
+			return nil
+		}
+		if last {
+			return nil
+		}
+		offset += 4 + length
+	}
+	return nil
+}
+
+// readOgg 读取 Ogg 第一个逻辑流的第二个包，即 Vorbis 或 Opus 的注释头
+func readOgg(r io.ReaderAt, size int64, meta *Metadata) error {
+	header := make([]byte, 27)
+	segments := make([]byte, 255)
+
+	var (
+		serial  uint32
+		packets int
+		packet  []byte
+	)
+	offset := int64(0)
+	for page := 0; page < maxOggPages && offset+27 <= size; page++ {
+		if _, err := r.ReadAt(header, offset); err != nil {
+			return err
+		}
+		if string(header[:4]) != "OggS" {
+			return ErrInvalid
+		}
+		pageSerial := binary.LittleEndian.Uint32(header[14:18])
+		if page == 0 {
+			serial = pageSerial
+		}
+
+		n := int(header[26])
+		if _, err := r.ReadAt(segments[:n], offset+27); err != nil {
+			return err
+		}
+		bodySize := 0
+		for _, l := range segments[:n] {
+			bodySize += int(l)
+		}
+		bodyStart := offset + 27 + int64(n)
+		if bodyStart+int64(bodySize) > size {
+			return ErrInvalid
+		}
+		offset = bodyStart + int64(bodySize)
+		if pageSerial != serial {
+			// 多路复用的其他逻辑流
+			continue
+		}
+
+		body := make([]byte, bodySize)
+		if _, err := r.ReadAt(body, bodyStart); err != nil {
+			return err
+		}
+		for _, l := range segments[:n] {
+			if packets == 1 && len(packet)+int(l) <= maxTagSize {
+				packet = append(packet, body[:l]...)
+			}
+			body = body[l:]
+			// 长度小于 255 的段结束一个包
+			if l < 255 {
+				packets++
+				if packets == 2 {
+					parseOggComment(packet, meta.audio())
+					return nil
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseOggComment 解析 Vorbis 或 Opus 的注释头包
+func parseOggComment(packet []byte, tags *AudioTags) {
+	for _, prefix := range [][]byte{[]byte("\x03vorbis"), []byte("OpusTags")} {
+		if bytes.HasPrefix(packet, prefix) {
+			parseVorbisComment(packet[len(prefix):], tags)
+			return
+		}
+	}
+}
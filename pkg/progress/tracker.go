@@ -12,6 +12,7 @@ import (
 
 const (
 	ProgressFileName = ".classified-file-progress.txt"
+	LockFileName     = ".classified-file-progress.lock"
 )
 
 type Tracker struct {
@@ -160,6 +161,18 @@ func (t *Tracker) Close() error {
 	return nil
 }
 
+// Release 刷新并关闭进度文件但不删除，用于扫描未完成时保留恢复状态
+func (t *Tracker) Release() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := t.writer.Flush(); err != nil {
+		t.file.Close()
+		return err
+	}
+	return t.file.Close()
+}
+
 // Clean 清理进度文件（用于重置）
 func (t *Tracker) Clean() error {
 	t.mu.Lock()
@@ -211,6 +211,33 @@ func TestClose(t *testing.T) {
 	}
 }
 
+func TestRelease(t *testing.T) {
+	tempDir := t.TempDir()
+
+	tracker, err := NewTracker(tempDir)
+	if err != nil {
+		t.Fatalf("NewTracker() error = %v", err)
+	}
+
+	filePath := "/path/to/file.txt"
+	if err := tracker.MarkProcessed(filePath); err != nil {
+		t.Fatalf("MarkProcessed() error = %v", err)
+	}
+	if err := tracker.Release(); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+
+	reopened, err := NewTracker(tempDir)
+	if err != nil {
+		t.Fatalf("NewTracker() error = %v", err)
+	}
+	defer reopened.Close()
+
+	if !reopened.IsProcessed(filePath) {
+		t.Error("Progress should be kept after release")
+	}
+}
+
 func TestClean(t *testing.T) {
 	tempDir := t.TempDir()
 	defer os.RemoveAll(tempDir)
@@ -0,0 +1,119 @@
+package scanner
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"sync"
+	"syscall"
+
+	"github.com/moyu-x/classified-file/pkg/logger"
+)
+
+// 出错的操作类型
+const (
+	OpStat       = "stat"
+	OpLstat      = "lstat"
+	OpReadDir    = "readdir"
+	OpReadIgnore = "read-ignore"
+	OpDangling   = "dangling-symlink"
+	OpRead       = "read"
+	OpRemove     = "remove"
+	OpMove       = "move"
+	OpClassify   = "classify"
+	OpArchive    = "read-archive"
+)
+
+// ScanError 遍历或处理文件时被跳过的路径
+type ScanError struct {
+	Path  string `json:"path"`
+	Op    string `json:"op"`
+	Errno int    `json:"errno,omitempty"`
+	Err   string `json:"error"`
+}
+
+// ErrorReport 收集遍历和处理过程中的错误，同一路径的同一操作只记录一次
+type ErrorReport struct {
+	errors []ScanError
+	seen   map[string]bool
+	mu     sync.Mutex
+}
+
+func NewErrorReport() *ErrorReport {
+	return &ErrorReport{
+		seen: make(map[string]bool),
+	}
+}
+
+// Add 记录错误，report 为 nil 时忽略
+func (r *ErrorReport) Add(path, op string, err error) {
+	if r == nil || err == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := op + "\x00" + path
+	if r.seen[key] {
+		return
+	}
+	r.seen[key] = true
+
+	scanErr := ScanError{Path: path, Op: op, Err: err.Error()}
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		scanErr.Errno = int(errno)
+	}
+	r.errors = append(r.errors, scanErr)
+
+	logger.Get().Warn().Err(err).Msgf("跳过 %s (%s)", path, op)
+}
+
+// Len 返回错误数量
+func (r *ErrorReport) Len() int {
+	if r == nil {
+		return 0
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.errors)
+}
+
+// Errors 返回按路径排序的错误列表
+func (r *ErrorReport) Errors() []ScanError {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	result := make([]ScanError, len(r.errors))
+	copy(result, r.errors)
+	r.mu.Unlock()
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Path != result[j].Path {
+			return result[i].Path < result[j].Path
+		}
+		return result[i].Op < result[j].Op
+	})
+	return result
+}
+
+// WriteFile 将错误报告以 JSON 格式写入文件
+func (r *ErrorReport) WriteFile(path string) error {
+	errs := r.Errors()
+	if errs == nil {
+		errs = []ScanError{}
+	}
+
+	data, err := json.MarshalIndent(errs, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	logger.Get().Info().Msgf("写入错误报告: %s (%d 条)", path, len(errs))
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
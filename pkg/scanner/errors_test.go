@@ -0,0 +1,113 @@
+package scanner
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestErrorReport_Add(t *testing.T) {
+	report := NewErrorReport()
+
+	report.Add("/b", OpRead, &os.PathError{Op: "open", Path: "/b", Err: syscall.EACCES})
+	report.Add("/a", OpReadDir, errors.New("boom"))
+	report.Add("/b", OpRead, errors.New("same path and op"))
+	report.Add("/c", OpRead, nil)
+
+	if report.Len() != 2 {
+		t.Fatalf("Expected 2 errors, got %d", report.Len())
+	}
+
+	errs := report.Errors()
+	if errs[0].Path != "/a" || errs[1].Path != "/b" {
+		t.Errorf("Expected errors sorted by path, got %+v", errs)
+	}
+
+	if errs[1].Errno != int(syscall.EACCES) {
+		t.Errorf("Expected errno %d, got %d", int(syscall.EACCES), errs[1].Errno)
+	}
+
+	var nilReport *ErrorReport
+	nilReport.Add("/x", OpRead, errors.New("ignored"))
+	if nilReport.Len() != 0 {
+		t.Error("Expected nil report to ignore errors")
+	}
+}
+
+func TestErrorReport_WriteFile(t *testing.T) {
+	tempDir := t.TempDir()
+	reportPath := filepath.Join(tempDir, "errors.json")
+
+	report := NewErrorReport()
+	report.Add("/a", OpStat, errors.New("boom"))
+
+	if err := report.WriteFile(reportPath); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("Failed to read report: %v", err)
+	}
+
+	var errs []ScanError
+	if err := json.Unmarshal(data, &errs); err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+
+	if len(errs) != 1 || errs[0].Path != "/a" || errs[0].Op != OpStat || errs[0].Err != "boom" {
+		t.Errorf("Unexpected report content: %+v", errs)
+	}
+}
+
+func TestFileWalker_Walk_ReportsErrors(t *testing.T) {
+	tempDir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(tempDir, "file.txt"), []byte("test"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	if err := os.Symlink(filepath.Join(tempDir, "missing"), filepath.Join(tempDir, "dangling")); err != nil {
+		t.Skipf("Skipping symlink test: %v", err)
+	}
+
+	locked := filepath.Join(tempDir, "locked")
+	if err := os.MkdirAll(filepath.Join(locked, "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.Chmod(locked, 0000); err != nil {
+		t.Fatalf("Chmod() error = %v", err)
+	}
+	defer os.Chmod(locked, 0755)
+
+	walker := NewFileWalker()
+	walker.FollowSymlinks = true
+
+	count, err := walker.CountFiles([]string{tempDir, filepath.Join(tempDir, "nonexistent")})
+	if err != nil {
+		t.Fatalf("CountFiles() error = %v", err)
+	}
+
+	if count != 1 {
+		t.Errorf("Expected 1 file, got %d", count)
+	}
+
+	ops := make(map[string]string)
+	for _, scanErr := range walker.Errors.Errors() {
+		rel, _ := filepath.Rel(tempDir, scanErr.Path)
+		ops[rel] = scanErr.Op
+	}
+
+	if ops["dangling"] != OpDangling {
+		t.Errorf("Expected dangling symlink to be reported, got %v", ops)
+	}
+	if ops["nonexistent"] != OpStat {
+		t.Errorf("Expected missing root to be reported, got %v", ops)
+	}
+	if os.Geteuid() != 0 && ops["locked"] != OpReadDir {
+		t.Errorf("Expected unreadable directory to be reported, got %v", ops)
+	}
+}
@@ -0,0 +1,24 @@
+package scanner
+
+import "os"
+
+// FileID 文件的设备号和 inode，用于识别硬链接和同一文件
+type FileID struct {
+	Device uint64
+	Inode  uint64
+}
+
+// GetFileID 从文件信息中获取设备号和 inode，平台不支持时返回 false
+func GetFileID(info os.FileInfo) (FileID, bool) {
+	id, _, ok := statInfo(info)
+	return id, ok
+}
+
+// LinkCount 返回文件的硬链接数，平台不支持时返回 1
+func LinkCount(info os.FileInfo) uint64 {
+	_, nlink, ok := statInfo(info)
+	if !ok || nlink == 0 {
+		return 1
+	}
+	return nlink
+}
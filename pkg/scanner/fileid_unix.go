@@ -0,0 +1,16 @@
+//go:build unix
+
+package scanner
+
+import (
+	"os"
+	"syscall"
+)
+
+func statInfo(info os.FileInfo) (FileID, uint64, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return FileID{}, 0, false
+	}
+	return FileID{Device: uint64(st.Dev), Inode: uint64(st.Ino)}, uint64(st.Nlink), true
+}
@@ -0,0 +1,10 @@
+//go:build windows
+
+package scanner
+
+import "os"
+
+// Windows 的 os.FileInfo 不携带文件索引号，此时退化为按路径判断
+func statInfo(info os.FileInfo) (FileID, uint64, bool) {
+	return FileID{}, 0, false
+}
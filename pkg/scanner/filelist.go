@@ -0,0 +1,150 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/moyu-x/classified-file/pkg/logger"
+)
+
+// FileListStdin 表示从标准输入读取文件列表
+const FileListStdin = "-"
+
+// ReadFileList 读取文件列表，nul 为 true 时按 NUL 分隔（find -print0），否则按行分隔
+// 空条目会被忽略，按行分隔时去掉行尾的 \r
+func ReadFileList(r io.Reader, nul bool) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	if nul {
+		scanner.Split(splitNul)
+	}
+
+	var paths []string
+	for scanner.Scan() {
+		path := scanner.Text()
+		if !nul {
+			path = strings.TrimSuffix(path, "\r")
+		}
+		if path == "" {
+			continue
+		}
+		paths = append(paths, path)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取文件列表失败: %w", err)
+	}
+	return paths, nil
+}
+
+// LoadFileList 从文件或标准输入（source 为 "-"）读取文件列表
+func LoadFileList(source string, nul bool) ([]string, error) {
+	if source == FileListStdin {
+		return ReadFileList(os.Stdin, nul)
+	}
+
+	file, err := os.Open(source)
+	if err != nil {
+		return nil, fmt.Errorf("打开文件列表失败: %w", err)
+	}
+	defer file.Close()
+
+	return ReadFileList(file, nul)
+}
+
+func splitNul(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// WalkList 依次处理列表中的路径而不遍历目录，用于 --files-from
+// 目录条目会被跳过，隐藏文件、符号链接、特殊文件和过滤条件的规则与 Walk 相同，
+// 过滤条件中的相对路径模式相对当前工作目录匹配
+func (w *FileWalker) WalkList(paths []string, callback func(path string, info os.FileInfo) error) error {
+	root, err := os.Getwd()
+	if err != nil {
+		root = ""
+	}
+	return w.WalkListIn(root, paths, callback)
+}
+
+// WalkListIn 与 WalkList 相同，但过滤条件中的相对路径模式相对 root 匹配，
+// 与从 root 开始遍历时一致，用于监视模式等已知扫描根目录的场景
+func (w *FileWalker) WalkListIn(root string, paths []string, callback func(path string, info os.FileInfo) error) error {
+	for _, path := range paths {
+		if !w.IncludeHidden && isHidden(filepath.Base(path)) {
+			continue
+		}
+
+		info, err := os.Lstat(path)
+		if err != nil {
+			w.Errors.Add(path, OpLstat, err)
+			continue
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Stat(path)
+			if err != nil {
+				linkTarget, _ := os.Readlink(path)
+				w.reportDangling(path, linkTarget, err)
+				continue
+			}
+			if w.FollowSymlinks {
+				info = target
+			} else if !w.linkTargetAllowed(path, target) {
+				continue
+			}
+		}
+
+		if info.IsDir() {
+			logger.Get().Debug().Msgf("文件列表中的目录不会被遍历，跳过: %s", path)
+			continue
+		}
+
+		if kind, ok := specialType(info.Mode()); ok {
+			logger.Get().Debug().Msgf("跳过%s: %s", SkipLabel(kind), path)
+			w.reportSkipped(kind)
+			continue
+		}
+
+		if !w.Filter.AllowFile(path, relativePath(root, path), info) {
+			continue
+		}
+
+		if err := callback(path, info); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// relativePath 返回 path 相对 root 的路径，path 不在 root 中时返回文件名，与单独遍历该文件时相同
+func relativePath(root, path string) string {
+	if root == "" {
+		return filepath.Base(path)
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return filepath.Base(path)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Base(path)
+	}
+	rel, err := filepath.Rel(absRoot, abs)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filepath.Base(path)
+	}
+	return rel
+}
@@ -0,0 +1,121 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/moyu-x/classified-file/pkg/filter"
+)
+
+func TestReadFileList(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		nul   bool
+		want  []string
+	}{
+		{"newline", "a.txt\nb c.txt\n\nd.txt", false, []string{"a.txt", "b c.txt", "d.txt"}},
+		{"crlf", "a.txt\r\nb.txt\r\n", false, []string{"a.txt", "b.txt"}},
+		{"nul", "a.txt\x00line\nbreak.txt\x00\x00d.txt", true, []string{"a.txt", "line\nbreak.txt", "d.txt"}},
+		{"nul trailing", "a.txt\x00", true, []string{"a.txt"}},
+		{"empty", "", false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadFileList(strings.NewReader(tt.input), tt.nul)
+			if err != nil {
+				t.Fatalf("ReadFileList() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadFileList() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileWalker_WalkList(t *testing.T) {
+	tempDir := t.TempDir()
+
+	file := filepath.Join(tempDir, "a.txt")
+	hidden := filepath.Join(tempDir, ".hidden")
+	for _, path := range []string{file, hidden} {
+		if err := os.WriteFile(path, []byte("content"), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+	missing := filepath.Join(tempDir, "missing.txt")
+
+	walker := NewFileWalker()
+	walker.IncludeHidden = false
+
+	var visited []string
+	err := walker.WalkList([]string{file, tempDir, missing, hidden}, func(path string, info os.FileInfo) error {
+		visited = append(visited, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkList() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(visited, []string{file}) {
+		t.Errorf("Expected only %s, got %v", file, visited)
+	}
+
+	errs := walker.Errors.Errors()
+	if len(errs) != 1 || errs[0].Path != missing || errs[0].Op != OpLstat {
+		t.Errorf("Expected one lstat error for %s, got %+v", missing, errs)
+	}
+}
+
+func TestFileWalker_WalkListIn_RelativePatterns(t *testing.T) {
+	root := t.TempDir()
+	files := []string{
+		filepath.Join(root, "build", "out.txt"),
+		filepath.Join(root, "src", "build", "keep.txt"),
+		filepath.Join(root, "photos", "2024", "a.raw"),
+		filepath.Join(root, "b.raw"),
+	}
+	for _, path := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("content"), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+
+	fileFilter, err := filter.New(filter.Options{Exclude: []string{"/build/*", "photos/**/*.raw"}})
+	if err != nil {
+		t.Fatalf("filter.New() error = %v", err)
+	}
+	walker := NewFileWalker()
+	walker.Filter = fileFilter
+
+	// 与从根目录遍历时的结果一致
+	var walked []string
+	walker.Walk(root, func(path string, info os.FileInfo) error {
+		walked = append(walked, path)
+		return nil
+	})
+
+	var listed []string
+	err = walker.WalkListIn(root, files, func(path string, info os.FileInfo) error {
+		listed = append(listed, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkListIn() error = %v", err)
+	}
+
+	want := []string{files[3], files[1]}
+	if !reflect.DeepEqual(walked, want) {
+		t.Errorf("Walk() = %v, want %v", walked, want)
+	}
+	if !reflect.DeepEqual(listed, []string{files[1], files[3]}) {
+		t.Errorf("WalkListIn() = %v, want %v", listed, []string{files[1], files[3]})
+	}
+}
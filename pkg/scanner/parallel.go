@@ -0,0 +1,234 @@
+package scanner
+
+import (
+	"io/fs"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/moyu-x/classified-file/pkg/filter"
+	"github.com/moyu-x/classified-file/pkg/logger"
+)
+
+// fileBufferSize 是无序并发遍历中等待交付的文件条目缓冲数量
+const fileBufferSize = 1024
+
+// dirJob 是并发遍历中等待读取的目录
+type dirJob struct {
+	// name 是目录的 fs 路径
+	name    string
+	info    fs.FileInfo
+	ignores []*filter.IgnoreRules
+	// ancestors 是有序遍历中祖先目录的标识，用于检测符号链接循环
+	ancestors []string
+	// result 仅在有序遍历中使用
+	result *dirResult
+}
+
+// dirResult 保存有序遍历中一个目录的读取结果，done 关闭后 items 可用
+type dirResult struct {
+	items []walkItem
+	done  chan struct{}
+}
+
+func newDirResult() *dirResult {
+	return &dirResult{done: make(chan struct{})}
+}
+
+// jobQueue 是目录任务栈，后进先出使遍历接近深度优先，有序交付时等待更少
+type jobQueue struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	jobs []*dirJob
+	// pending 是排队中和正在读取的任务总数，为 0 时遍历结束
+	pending int
+}
+
+func newJobQueue() *jobQueue {
+	q := &jobQueue{}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// push 按给定顺序入栈，第一个任务最后出栈
+func (q *jobQueue) push(jobs ...*dirJob) {
+	if len(jobs) == 0 {
+		return
+	}
+	q.mu.Lock()
+	q.jobs = append(q.jobs, jobs...)
+	q.pending += len(jobs)
+	q.mu.Unlock()
+	q.cond.Broadcast()
+}
+
+// pop 取出一个任务，所有任务完成后返回 false
+func (q *jobQueue) pop() (*dirJob, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.jobs) == 0 && q.pending > 0 {
+		q.cond.Wait()
+	}
+	if len(q.jobs) == 0 {
+		return nil, false
+	}
+
+	job := q.jobs[len(q.jobs)-1]
+	q.jobs = q.jobs[:len(q.jobs)-1]
+	return job, true
+}
+
+// done 标记一个任务完成，必须在该任务的子任务入栈之后调用
+func (q *jobQueue) done() {
+	q.mu.Lock()
+	q.pending--
+	finished := q.pending == 0
+	q.mu.Unlock()
+
+	if finished {
+		q.cond.Broadcast()
+	}
+}
+
+// walkParallel 使用 Workers 个 goroutine 并发读取子目录，回调始终在调用方 goroutine 中串行执行
+// Ordered 为 true 时按顺序遍历的顺序交付，否则按读取完成的顺序交付
+func (w *FileWalker) walkParallel(state *walkState, info fs.FileInfo) error {
+	queue := newJobQueue()
+	var stopped atomic.Bool
+	var files chan walkItem
+
+	rootJob := &dirJob{name: ".", info: info}
+	if w.Ordered {
+		rootJob.result = newDirResult()
+	} else {
+		files = make(chan walkItem, fileBufferSize)
+	}
+	queue.push(rootJob)
+
+	var workers sync.WaitGroup
+	for i := 0; i < w.Workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for {
+				job, ok := queue.pop()
+				if !ok {
+					return
+				}
+				w.readJob(state, queue, job, files, &stopped)
+				queue.done()
+			}
+		}()
+	}
+
+	var err error
+	if w.Ordered {
+		err = w.deliverOrdered(state, ".", info, rootJob.result)
+		if err != nil {
+			stopped.Store(true)
+		}
+	} else {
+		go func() {
+			workers.Wait()
+			close(files)
+		}()
+
+		for item := range files {
+			if err != nil {
+				continue
+			}
+			if err = state.callback(item.path, item.entry); err != nil {
+				stopped.Store(true)
+			}
+		}
+	}
+
+	workers.Wait()
+	return err
+}
+
+// readJob 读取一个目录，子目录入栈，文件在无序模式下直接交付，有序模式下保存到 result
+func (w *FileWalker) readJob(state *walkState, queue *jobQueue, job *dirJob, files chan<- walkItem, stopped *atomic.Bool) {
+	if job.result != nil {
+		defer close(job.result.done)
+	}
+	if stopped.Load() {
+		return
+	}
+
+	key := state.dirKey(job.name, job.info)
+	if job.result != nil {
+		// 有序遍历中重复目录由交付方按顺序判断，这里只阻止循环
+		if slices.Contains(job.ancestors, key) {
+			logger.Get().Debug().Msgf("检测到符号链接循环: %s", state.path(job.name))
+			return
+		}
+	} else if !state.visit(job.name, key) {
+		return
+	}
+
+	items := w.readDir(state, job.name, job.ignores)
+
+	var ancestors []string
+	if job.result != nil {
+		ancestors = append(job.ancestors[:len(job.ancestors):len(job.ancestors)], key)
+	}
+
+	var children []*dirJob
+	for i := range items {
+		item := &items[i]
+		if !item.isDir {
+			continue
+		}
+		child := &dirJob{name: item.name, info: item.info, ignores: item.ignores, ancestors: ancestors}
+		if job.result != nil {
+			child.result = newDirResult()
+			item.result = child.result
+		}
+		children = append(children, child)
+	}
+	slices.Reverse(children)
+	queue.push(children...)
+
+	if job.result != nil {
+		job.result.items = items
+		return
+	}
+
+	for _, item := range items {
+		if item.isDir {
+			continue
+		}
+		if stopped.Load() {
+			return
+		}
+		files <- item
+	}
+}
+
+// deliverOrdered 按深度优先顺序等待并交付各目录的读取结果
+func (w *FileWalker) deliverOrdered(state *walkState, dir string, info fs.FileInfo, result *dirResult) error {
+	if !state.visit(dir, state.dirKey(dir, info)) {
+		return nil
+	}
+
+	<-result.done
+	items := result.items
+	result.items = nil
+
+	for _, item := range items {
+		if item.isDir {
+			if err := w.deliverOrdered(state, item.name, item.info, item.result); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := state.callback(item.path, item.entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package scanner
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func createTree(t *testing.T, root string) []string {
+	t.Helper()
+
+	var files []string
+	for _, dir := range []string{"a", "a/x", "a/y/z", "b", "c/d", "c/e"} {
+		for _, name := range []string{"1.txt", "2.txt", "3.txt"} {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	files = append(files, "top.txt")
+
+	for _, file := range files {
+		fullPath := filepath.Join(root, file)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(file), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	return files
+}
+
+func collectPaths(t *testing.T, walker *FileWalker, root string) []string {
+	t.Helper()
+
+	var paths []string
+	err := walker.Walk(root, func(path string, info os.FileInfo) error {
+		relPath, _ := filepath.Rel(root, path)
+		paths = append(paths, relPath)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+	return paths
+}
+
+func TestFileWalker_Walk_ParallelOrdered(t *testing.T) {
+	tempDir := t.TempDir()
+	createTree(t, tempDir)
+
+	sequential := collectPaths(t, NewFileWalker(), tempDir)
+
+	for _, workers := range []int{2, 4, 16} {
+		walker := NewFileWalker()
+		walker.Workers = workers
+
+		got := collectPaths(t, walker, tempDir)
+		if !reflect.DeepEqual(got, sequential) {
+			t.Errorf("Workers=%d: got order %v, want %v", workers, got, sequential)
+		}
+	}
+}
+
+func TestFileWalker_Walk_ParallelUnordered(t *testing.T) {
+	tempDir := t.TempDir()
+	files := createTree(t, tempDir)
+
+	walker := NewFileWalker()
+	walker.Workers = 4
+	walker.Ordered = false
+
+	got := collectPaths(t, walker, tempDir)
+	sort.Strings(got)
+	sort.Strings(files)
+
+	if !reflect.DeepEqual(got, files) {
+		t.Errorf("got %v, want %v", got, files)
+	}
+}
+
+func TestFileWalker_Walk_ParallelSymlinkLoop(t *testing.T) {
+	tempDir := t.TempDir()
+	createTree(t, tempDir)
+
+	if err := os.Symlink(tempDir, filepath.Join(tempDir, "a", "loop")); err != nil {
+		t.Skipf("Skipping symlink test: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(tempDir, "b"), filepath.Join(tempDir, "c", "b-link")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	sequentialWalker := NewFileWalker()
+	sequentialWalker.FollowSymlinks = true
+	sequential := collectPaths(t, sequentialWalker, tempDir)
+
+	walker := NewFileWalker()
+	walker.FollowSymlinks = true
+	walker.Workers = 4
+
+	got := collectPaths(t, walker, tempDir)
+	if !reflect.DeepEqual(got, sequential) {
+		t.Errorf("got order %v, want %v", got, sequential)
+	}
+
+	walker = NewFileWalker()
+	walker.FollowSymlinks = true
+	walker.Workers = 4
+	walker.Ordered = false
+
+	unordered := collectPaths(t, walker, tempDir)
+	if len(unordered) != len(sequential) {
+		t.Errorf("Expected %d files, got %d", len(sequential), len(unordered))
+	}
+}
+
+func TestFileWalker_Walk_ParallelCallbackError(t *testing.T) {
+	tempDir := t.TempDir()
+	createTree(t, tempDir)
+
+	stop := errors.New("stop")
+
+	for _, ordered := range []bool{true, false} {
+		walker := NewFileWalker()
+		walker.Workers = 4
+		walker.Ordered = ordered
+
+		calls := 0
+		err := walker.Walk(tempDir, func(path string, info os.FileInfo) error {
+			calls++
+			if calls == 3 {
+				return stop
+			}
+			return nil
+		})
+
+		if !errors.Is(err, stop) {
+			t.Errorf("Ordered=%v: expected callback error, got %v", ordered, err)
+		}
+		if calls != 3 {
+			t.Errorf("Ordered=%v: expected callback to stop after 3 calls, got %d", ordered, calls)
+		}
+	}
+}
+
+func TestFileWalker_WalkDir(t *testing.T) {
+	tempDir := t.TempDir()
+	files := createTree(t, tempDir)
+
+	for _, workers := range []int{1, 4} {
+		walker := NewFileWalker()
+		walker.Workers = workers
+
+		count := 0
+		err := walker.WalkDir(tempDir, func(path string, entry fs.DirEntry) error {
+			count++
+			if !entry.Type().IsRegular() {
+				t.Errorf("Expected regular file type for %s, got %v", path, entry.Type())
+			}
+			if entry.Name() != filepath.Base(path) {
+				t.Errorf("Expected entry name %s, got %s", filepath.Base(path), entry.Name())
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("WalkDir() error = %v", err)
+		}
+
+		if count != len(files) {
+			t.Errorf("Workers=%d: expected %d files, got %d", workers, len(files), count)
+		}
+	}
+}
@@ -1,34 +1,461 @@
 package scanner
 
 import (
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 
+	"github.com/moyu-x/classified-file/pkg/filter"
 	"github.com/moyu-x/classified-file/pkg/logger"
 )
 
+const (
+	IgnoreFileName    = ".classifiedignore"
+	GitIgnoreFileName = ".gitignore"
+)
+
 type FileWalker struct {
-	IncludeHidden bool
+	IncludeHidden  bool
+	FollowSymlinks bool
+	Filter         *filter.Filter
+	// UseGitIgnore 为 true 时除 .classifiedignore 外还读取 .gitignore
+	UseGitIgnore bool
+	// Errors 收集遍历过程中被跳过的路径，调用方处理文件失败时也可记录到这里
+	Errors *ErrorReport
+	// Workers 并发读取目录的数量，小于等于 1 时在当前 goroutine 中顺序遍历
+	Workers int
+	// Ordered 为 true 时并发遍历仍按顺序遍历的顺序（深度优先、按文件名排序）交付条目
+	Ordered bool
+	// OneFileSystem 为 true 时不进入与根目录设备号不同的目录（挂载点）
+	OneFileSystem bool
+	// SkipPaths 是遍历本地目录时不进入的目录（绝对路径），由 SkipPath 添加
+	SkipPaths []string
+
+	dangling   map[string]bool
+	danglingMu sync.Mutex
+
+	skipped   map[string]int
+	skippedMu sync.Mutex
 }
 
 func NewFileWalker() *FileWalker {
 	return &FileWalker{
 		IncludeHidden: true,
+		Errors:        NewErrorReport(),
+		Workers:       1,
+		Ordered:       true,
+	}
+}
+
+// Clone 返回配置相同的 FileWalker，共享错误报告，悬空链接和跳过统计单独计数
+// 用于在正式处理前额外遍历一次而不重复统计
+func (w *FileWalker) Clone() *FileWalker {
+	return &FileWalker{
+		IncludeHidden:  w.IncludeHidden,
+		FollowSymlinks: w.FollowSymlinks,
+		Filter:         w.Filter,
+		UseGitIgnore:   w.UseGitIgnore,
+		Errors:         w.Errors,
+		Workers:        w.Workers,
+		Ordered:        w.Ordered,
+		OneFileSystem:  w.OneFileSystem,
+		SkipPaths:      w.SkipPaths,
+	}
+}
+
+// SkipPath 添加遍历时不进入的本地目录，用于排除位于扫描目录中的输出目录（如分类目标目录），
+// 避免处理刚写入的结果
+func (w *FileWalker) SkipPath(path string) {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	w.SkipPaths = append(w.SkipPaths, filepath.Clean(path))
+}
+
+// skipPath 判断本地目录是否在 SkipPaths 中
+func (w *FileWalker) skipPath(path string) bool {
+	if len(w.SkipPaths) == 0 {
+		return false
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	for _, skip := range w.SkipPaths {
+		if path == skip {
+			return true
+		}
 	}
+	return false
 }
 
+// Walk 遍历 root 下的所有文件（不包括目录），root 本身为符号链接时总是跟随
+// FollowSymlinks 为 true 时跟随指向目录的符号链接，并通过设备号/inode 检测循环
 func (w *FileWalker) Walk(root string, callback func(path string, info os.FileInfo) error) error {
-	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+	return w.WalkDir(root, func(path string, entry fs.DirEntry) error {
+		info, err := entry.Info()
 		if err != nil {
+			w.Errors.Add(path, OpLstat, err)
 			return nil
 		}
+		return callback(path, info)
+	})
+}
+
+// WalkDir 与 Walk 相同，但交付 fs.DirEntry 而不是 os.FileInfo
+// entry.Type() 来自目录项的 d_type，只有过滤条件需要大小或时间、或者跟随符号链接时才会 stat，
+// 调用方可以据此在不 stat 的情况下跳过非普通文件；需要时再调用 entry.Info()
+func (w *FileWalker) WalkDir(root string, callback func(path string, entry fs.DirEntry) error) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		w.Errors.Add(root, OpStat, err)
+		return nil
+	}
 
-		if info.IsDir() {
+	if !info.IsDir() {
+		if kind, ok := specialType(info.Mode()); ok {
+			logger.Get().Warn().Msgf("跳过%s: %s", SkipLabel(kind), root)
+			w.reportSkipped(kind)
+			return nil
+		}
+		if !w.Filter.AllowFile(root, filepath.Base(root), info) {
 			return nil
 		}
+		return callback(root, fs.FileInfoToDirEntry(info))
+	}
 
-		return callback(path, info)
-	})
+	return w.walk(os.DirFS(root), root, info, callback)
+}
+
+// WalkFS 遍历 fsys 中的所有文件，回调参数为相对 fsys 根目录、以 / 分隔的 fs 路径
+// 规则与 WalkDir 相同，可用于压缩包、内存文件系统等非本地目录树
+// fsys 实现 fs.ReadLinkFS 时才能跟随符号链接；无法获取设备号时忽略 OneFileSystem
+func (w *FileWalker) WalkFS(fsys fs.FS, callback func(name string, entry fs.DirEntry) error) error {
+	info, err := fs.Stat(fsys, ".")
+	if err != nil {
+		w.Errors.Add(".", OpStat, err)
+		return nil
+	}
+
+	return w.walk(fsys, "", info, callback)
+}
+
+// walk 遍历 fsys，root 为本地目录时错误报告和回调使用本地路径，为空时使用 fs 路径
+func (w *FileWalker) walk(fsys fs.FS, root string, info fs.FileInfo, callback func(path string, entry fs.DirEntry) error) error {
+	state := &walkState{
+		fsys:     fsys,
+		root:     root,
+		visited:  make(map[string]bool),
+		callback: callback,
+	}
+	if w.OneFileSystem {
+		if id, ok := GetFileID(info); ok {
+			state.device = id.Device
+			state.sameDevice = true
+		} else {
+			logger.Get().Warn().Msgf("无法获取设备号，忽略 --one-file-system: %s", state.path("."))
+		}
+	}
+	if w.Workers > 1 {
+		return w.walkParallel(state, info)
+	}
+	return w.walkDir(state, ".", info, nil)
+}
+
+type walkState struct {
+	fsys fs.FS
+	// root 为本地扫描根目录，遍历任意 fs.FS 时为空
+	root     string
+	callback func(path string, entry fs.DirEntry) error
+	// sameDevice 为 true 时只进入设备号为 device 的目录
+	sameDevice bool
+	device     uint64
+
+	visitedMu sync.Mutex
+	visited   map[string]bool
+}
+
+// path 将 fs 路径转换为交付给调用方的路径：本地目录为操作系统路径，其他 fs.FS 为 fs 路径本身
+func (s *walkState) path(name string) string {
+	if s.root == "" {
+		return name
+	}
+	if name == "." {
+		return s.root
+	}
+	return filepath.Join(s.root, filepath.FromSlash(name))
+}
+
+// visit 标记目录已遍历，目录已遍历过（符号链接循环或重复链接）时返回 false
+func (s *walkState) visit(name, key string) bool {
+	s.visitedMu.Lock()
+	defer s.visitedMu.Unlock()
+
+	if s.visited[key] {
+		logger.Get().Warn().Msgf("跳过已遍历的目录（符号链接循环或重复链接）: %s", s.path(name))
+		return false
+	}
+	s.visited[key] = true
+	return true
+}
+
+// dirKey 返回目录的唯一标识，优先使用设备号/inode，
+// 不支持时本地目录使用解析后的真实路径，其他 fs.FS 使用 fs 路径
+func (s *walkState) dirKey(name string, info fs.FileInfo) string {
+	if id, ok := GetFileID(info); ok {
+		return fmt.Sprintf("%d:%d", id.Device, id.Inode)
+	}
+	if s.root == "" {
+		return name
+	}
+
+	path := s.path(name)
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return path
+	}
+	return resolved
+}
+
+// walkItem 是读取目录得到的一个条目
+// 文件的 info 只在跟随了符号链接或过滤条件需要时才会填充；目录的 info 总是填充
+type walkItem struct {
+	// name 是 fs 路径，path 是交付给调用方的路径
+	name  string
+	path  string
+	entry fs.DirEntry
+	info  fs.FileInfo
+	isDir bool
+	// ignores 是子目录继承的忽略规则
+	ignores []*filter.IgnoreRules
+	// result 是有序并发遍历中子目录的读取结果
+	result *dirResult
+}
+
+// ignoreFileNames 返回需要读取的忽略文件名，后读取的文件优先级更高
+func (w *FileWalker) ignoreFileNames() []string {
+	if w.UseGitIgnore {
+		return []string{GitIgnoreFileName, IgnoreFileName}
+	}
+	return []string{IgnoreFileName}
+}
+
+// loadIgnoreRules 读取目录中的忽略文件，追加到继承自上级目录的规则之后
+func (w *FileWalker) loadIgnoreRules(state *walkState, dir string, inherited []*filter.IgnoreRules) []*filter.IgnoreRules {
+	rules := inherited
+	for _, ignoreName := range w.ignoreFileNames() {
+		name := path.Join(dir, ignoreName)
+		file, err := state.fsys.Open(name)
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				w.Errors.Add(state.path(name), OpReadIgnore, err)
+			}
+			continue
+		}
+
+		parsed, err := filter.ParseIgnore(file, dir)
+		file.Close()
+		if err != nil {
+			w.Errors.Add(state.path(name), OpReadIgnore, err)
+			continue
+		}
+
+		logger.Get().Debug().Msgf("加载忽略文件: %s", state.path(name))
+		rules = append(rules[:len(rules):len(rules)], parsed)
+	}
+	return rules
+}
+
+// Ignored 判断 root 下的 target 是否被 root 到 target 之间各级目录中的忽略文件排除，
+// 规则的加载和匹配与遍历时相同，忽略文件本身同样视为被排除。用于监视模式等不经过完整遍历的路径
+func (w *FileWalker) Ignored(root, target string, isDir bool) bool {
+	if w.isIgnoreFile(filepath.Base(target)) {
+		return true
+	}
+
+	rel, err := filepath.Rel(root, target)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+
+	state := &walkState{fsys: os.DirFS(root), root: root}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	var ignores []*filter.IgnoreRules
+	dir := "."
+	for i, part := range parts {
+		ignores = w.loadIgnoreRules(state, dir, ignores)
+		name := path.Join(dir, part)
+		if filter.Ignored(ignores, name, isDir || i < len(parts)-1) {
+			return true
+		}
+		dir = name
+	}
+	return false
+}
+
+func (w *FileWalker) isIgnoreFile(name string) bool {
+	for _, ignoreName := range w.ignoreFileNames() {
+		if name == ignoreName {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *FileWalker) walkDir(state *walkState, dir string, dirInfo fs.FileInfo, ignores []*filter.IgnoreRules) error {
+	if !state.visit(dir, state.dirKey(dir, dirInfo)) {
+		return nil
+	}
+
+	for _, item := range w.readDir(state, dir, ignores) {
+		if item.isDir {
+			if err := w.walkDir(state, item.name, item.info, item.ignores); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := state.callback(item.path, item.entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readDir 读取 fs 路径为 dir 的目录，应用隐藏文件、忽略文件和过滤条件，按文件名顺序返回需要处理的文件和子目录
+// 可以被多个 goroutine 并发调用
+func (w *FileWalker) readDir(state *walkState, dir string, ignores []*filter.IgnoreRules) []walkItem {
+	entries, err := fs.ReadDir(state.fsys, dir)
+	if err != nil {
+		w.Errors.Add(state.path(dir), OpReadDir, err)
+		if len(entries) == 0 {
+			return nil
+		}
+	}
+
+	ignores = w.loadIgnoreRules(state, dir, ignores)
+
+	items := make([]walkItem, 0, len(entries))
+	for _, entry := range entries {
+		baseName := entry.Name()
+		if !w.IncludeHidden && isHidden(baseName) {
+			continue
+		}
+
+		// 忽略文件本身是控制文件，不参与去重和分类
+		if w.isIgnoreFile(baseName) {
+			continue
+		}
+
+		name := path.Join(dir, baseName)
+		fullPath := state.path(name)
+		var info fs.FileInfo
+
+		if entry.Type()&fs.ModeSymlink != 0 {
+			target, err := fs.Stat(state.fsys, name)
+			if err != nil {
+				linkTarget, _ := fs.ReadLink(state.fsys, name)
+				w.reportDangling(fullPath, linkTarget, err)
+				continue
+			}
+			if w.FollowSymlinks {
+				info = target
+				entry = fs.FileInfoToDirEntry(target)
+			} else if !w.linkTargetAllowed(fullPath, target) {
+				continue
+			}
+		}
+
+		if kind, ok := specialType(entry.Type()); ok {
+			logger.Get().Debug().Msgf("跳过%s: %s", SkipLabel(kind), fullPath)
+			w.reportSkipped(kind)
+			continue
+		}
+
+		isDir := entry.IsDir()
+
+		if filter.Ignored(ignores, name, isDir) {
+			logger.Get().Debug().Msgf("忽略文件规则匹配: %s", fullPath)
+			continue
+		}
+
+		if isDir {
+			if !w.Filter.AllowDir(name) {
+				logger.Get().Debug().Msgf("过滤目录: %s", fullPath)
+				continue
+			}
+			if state.root != "" && w.skipPath(fullPath) {
+				logger.Get().Debug().Msgf("跳过输出目录: %s", fullPath)
+				continue
+			}
+		}
+
+		// 目录需要设备号/inode 检测循环，文件只有过滤条件需要时才 stat
+		if info == nil && (isDir || w.Filter.NeedsInfo()) {
+			info, err = entry.Info()
+			if err != nil {
+				w.Errors.Add(fullPath, OpLstat, err)
+				continue
+			}
+		}
+
+		if isDir {
+			if state.sameDevice {
+				if id, ok := GetFileID(info); ok && id.Device != state.device {
+					logger.Get().Info().Msgf("跳过其他文件系统上的目录: %s", fullPath)
+					w.reportSkipped(SkipOtherFS)
+					continue
+				}
+			}
+			items = append(items, walkItem{name: name, path: fullPath, entry: entry, info: info, isDir: true, ignores: ignores})
+			continue
+		}
+
+		if !w.Filter.AllowFile(fullPath, name, info) {
+			continue
+		}
+
+		if info != nil {
+			entry = fs.FileInfoToDirEntry(info)
+		}
+		items = append(items, walkItem{name: name, path: fullPath, entry: entry, info: info})
+	}
+
+	return items
+}
+
+func (w *FileWalker) reportDangling(path, target string, err error) {
+	w.danglingMu.Lock()
+	if w.dangling == nil {
+		w.dangling = make(map[string]bool)
+	}
+	w.dangling[path] = true
+	w.danglingMu.Unlock()
+
+	w.Errors.Add(path, OpDangling, fmt.Errorf("悬空符号链接 -> %s: %w", target, err))
+}
+
+// DanglingLinks 返回遍历过程中发现的悬空符号链接
+func (w *FileWalker) DanglingLinks() []string {
+	w.danglingMu.Lock()
+	defer w.danglingMu.Unlock()
+
+	links := make([]string, 0, len(w.dangling))
+	for path := range w.dangling {
+		links = append(links, path)
+	}
+	sort.Strings(links)
+	return links
+}
+
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, ".") && name != "." && name != ".."
 }
 
 func (w *FileWalker) CountFiles(dirs []string) (int, error) {
@@ -37,7 +464,8 @@ func (w *FileWalker) CountFiles(dirs []string) (int, error) {
 	count := 0
 	for _, dir := range dirs {
 		logger.Get().Debug().Msgf("扫描目录: %s", dir)
-		err := w.Walk(dir, func(path string, info os.FileInfo) error {
+		// 只计数不需要文件信息，使用 WalkDir 避免逐个 stat
+		err := w.WalkDir(dir, func(path string, entry fs.DirEntry) error {
 			count++
 			return nil
 		})
@@ -2,9 +2,15 @@ package scanner
 
 import (
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/fstest"
+
+	"github.com/moyu-x/classified-file/pkg/filter"
 )
 
 func TestFileWalker_Walk(t *testing.T) {
@@ -158,3 +164,351 @@ func TestFileWalker_Walk_WithSymlinks(t *testing.T) {
 		t.Errorf("Expected 2 files (original + symlink), got %d", count)
 	}
 }
+
+func TestGetFileID_HardLink(t *testing.T) {
+	tempDir := t.TempDir()
+
+	filePath := filepath.Join(tempDir, "file.txt")
+	if err := os.WriteFile(filePath, []byte("test content"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	linkPath := filepath.Join(tempDir, "link.txt")
+	if err := os.Link(filePath, linkPath); err != nil {
+		t.Skipf("Skipping hard link test: %v", err)
+	}
+
+	fileInfo, err := os.Lstat(filePath)
+	if err != nil {
+		t.Fatalf("Lstat() error = %v", err)
+	}
+	linkInfo, err := os.Lstat(linkPath)
+	if err != nil {
+		t.Fatalf("Lstat() error = %v", err)
+	}
+
+	fileID, ok := GetFileID(fileInfo)
+	if !ok {
+		t.Skip("Skipping: file IDs not supported on this platform")
+	}
+	linkID, _ := GetFileID(linkInfo)
+
+	if fileID != linkID {
+		t.Errorf("Expected hard links to share a file ID, got %v and %v", fileID, linkID)
+	}
+
+	if LinkCount(fileInfo) != 2 {
+		t.Errorf("Expected link count 2, got %d", LinkCount(fileInfo))
+	}
+}
+
+func TestFileWalker_Walk_ExcludeHidden(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFiles := []string{
+		"file1.txt",
+		".hidden_file",
+		"subdir/file2.txt",
+		".hidden_dir/file3.txt",
+	}
+
+	for _, file := range testFiles {
+		fullPath := filepath.Join(tempDir, file)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte("test content"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	walker := NewFileWalker()
+	walker.IncludeHidden = false
+
+	visitedFiles := []string{}
+	err := walker.Walk(tempDir, func(path string, info os.FileInfo) error {
+		relPath, _ := filepath.Rel(tempDir, path)
+		visitedFiles = append(visitedFiles, relPath)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+
+	expected := []string{"file1.txt", filepath.Join("subdir", "file2.txt")}
+	if len(visitedFiles) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, visitedFiles)
+	}
+	for i := range expected {
+		if visitedFiles[i] != expected[i] {
+			t.Errorf("Expected %s at position %d, got %s", expected[i], i, visitedFiles[i])
+		}
+	}
+}
+
+func TestFileWalker_Walk_FollowSymlinks(t *testing.T) {
+	tempDir := t.TempDir()
+	root := filepath.Join(tempDir, "root")
+	outside := filepath.Join(tempDir, "outside")
+
+	for _, dir := range []string{filepath.Join(root, "sub"), outside} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "sub", "file.txt"), []byte("test"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "other.txt"), []byte("test"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	links := map[string]string{
+		filepath.Join(root, "outside_link"):  outside,
+		filepath.Join(root, "sub", "loop"):   root,
+		filepath.Join(root, "dangling_link"): filepath.Join(tempDir, "missing"),
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("Skipping symlink test: %v", err)
+		}
+	}
+
+	walker := NewFileWalker()
+	walker.FollowSymlinks = true
+
+	visitedFiles := []string{}
+	err := walker.Walk(root, func(path string, info os.FileInfo) error {
+		relPath, _ := filepath.Rel(root, path)
+		visitedFiles = append(visitedFiles, relPath)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+
+	expected := []string{
+		filepath.Join("outside_link", "other.txt"),
+		filepath.Join("sub", "file.txt"),
+	}
+	if len(visitedFiles) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, visitedFiles)
+	}
+	for i := range expected {
+		if visitedFiles[i] != expected[i] {
+			t.Errorf("Expected %s at position %d, got %s", expected[i], i, visitedFiles[i])
+		}
+	}
+
+	dangling := walker.DanglingLinks()
+	if len(dangling) != 1 || dangling[0] != filepath.Join(root, "dangling_link") {
+		t.Errorf("Expected 1 dangling link, got %v", dangling)
+	}
+}
+
+func TestFileWalker_Walk_WithFilter(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFiles := []string{
+		"photo.jpg",
+		"notes.txt",
+		"album/photo2.jpg",
+		"node_modules/lib.jpg",
+	}
+
+	for _, file := range testFiles {
+		fullPath := filepath.Join(tempDir, file)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte("test content"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	fileFilter, err := filter.New(filter.Options{
+		Include:     []string{"*.jpg"},
+		ExcludeDirs: []string{"node_modules"},
+	})
+	if err != nil {
+		t.Fatalf("filter.New() error = %v", err)
+	}
+
+	walker := NewFileWalker()
+	walker.Filter = fileFilter
+
+	count, err := walker.CountFiles([]string{tempDir})
+	if err != nil {
+		t.Fatalf("CountFiles() error = %v", err)
+	}
+
+	if count != 2 {
+		t.Errorf("Expected 2 files, got %d", count)
+	}
+}
+
+func TestFileWalker_Walk_IgnoreFiles(t *testing.T) {
+	tempDir := t.TempDir()
+
+	testFiles := map[string]string{
+		IgnoreFileName:              "*.tmp\nbuild/\n",
+		"keep.txt":                  "x",
+		"skip.tmp":                  "x",
+		"build/out.bin":             "x",
+		"project/" + IgnoreFileName: "!wanted.tmp\n",
+		"project/wanted.tmp":        "x",
+		"project/other.tmp":         "x",
+		"repo/" + GitIgnoreFileName: "*.o\n",
+		"repo/main.o":               "x",
+		"repo/main.c":               "x",
+	}
+
+	for file, content := range testFiles {
+		fullPath := filepath.Join(tempDir, file)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	walk := func(walker *FileWalker) []string {
+		visited := []string{}
+		err := walker.Walk(tempDir, func(path string, info os.FileInfo) error {
+			relPath, _ := filepath.Rel(tempDir, path)
+			visited = append(visited, filepath.ToSlash(relPath))
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Walk() error = %v", err)
+		}
+		return visited
+	}
+
+	expected := []string{"keep.txt", "project/wanted.tmp", "repo/.gitignore", "repo/main.c", "repo/main.o"}
+	if got := walk(NewFileWalker()); strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	walker := NewFileWalker()
+	walker.UseGitIgnore = true
+	expected = []string{"keep.txt", "project/wanted.tmp", "repo/main.c"}
+	if got := walk(walker); strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Errorf("With gitignore expected %v, got %v", expected, got)
+	}
+}
+
+func TestFileWalker_readDir_OneFileSystem(t *testing.T) {
+	tempDir := t.TempDir()
+
+	if err := os.MkdirAll(filepath.Join(tempDir, "mnt"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	info, err := os.Stat(tempDir)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	id, ok := GetFileID(info)
+	if !ok {
+		t.Skip("Skipping: device numbers not supported on this platform")
+	}
+
+	walker := NewFileWalker()
+
+	// 模拟根目录位于另一个文件系统上，子目录的设备号与之不同
+	state := &walkState{fsys: os.DirFS(tempDir), root: tempDir, sameDevice: true, device: id.Device + 1}
+	items := walker.readDir(state, ".", nil)
+
+	if len(items) != 1 || items[0].isDir {
+		t.Errorf("Expected only the file to be kept, got %v", items)
+	}
+
+	if got := walker.Skipped()[SkipOtherFS]; got != 1 {
+		t.Errorf("Expected 1 skipped mount point, got %d", got)
+	}
+
+	// 同一文件系统上的子目录正常进入
+	state = &walkState{fsys: os.DirFS(tempDir), root: tempDir, sameDevice: true, device: id.Device}
+	if items := walker.readDir(state, ".", nil); len(items) != 2 {
+		t.Errorf("Expected 2 entries on the same filesystem, got %d", len(items))
+	}
+}
+
+func TestFileWalker_WalkFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":                  {Data: []byte("a")},
+		".hidden":                {Data: []byte("h")},
+		"docs/b.md":              {Data: []byte("b")},
+		"docs/skip.log":          {Data: []byte("log")},
+		"docs/.classifiedignore": {Data: []byte("*.log\n")},
+		"photos/c.jpg":           {Data: []byte("c")},
+		"photos/raw/d.raw":       {Data: []byte("d")},
+	}
+
+	fileFilter, err := filter.New(filter.Options{Exclude: []string{"photos/raw/**"}})
+	if err != nil {
+		t.Fatalf("filter.New() error = %v", err)
+	}
+
+	want := []string{"a.txt", "docs/b.md", "photos/c.jpg"}
+
+	for _, workers := range []int{1, 4} {
+		walker := NewFileWalker()
+		walker.IncludeHidden = false
+		walker.Filter = fileFilter
+		walker.Workers = workers
+
+		var got []string
+		err := walker.WalkFS(fsys, func(name string, entry fs.DirEntry) error {
+			got = append(got, name)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("WalkFS() error = %v", err)
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Workers=%d: WalkFS() = %v, want %v", workers, got, want)
+		}
+	}
+}
+
+func TestFileWalker_Ignored(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "src", "build"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, IgnoreFileName), []byte("build/\n*.log\n"), 0644); err != nil {
+		t.Fatalf("Failed to create ignore file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "src", IgnoreFileName), []byte("!keep.log\n"), 0644); err != nil {
+		t.Fatalf("Failed to create ignore file: %v", err)
+	}
+
+	walker := NewFileWalker()
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"a.txt", false, false},
+		{"a.log", false, true},
+		{"src/keep.log", false, false},
+		{"src/other.log", false, true},
+		{"src/build", true, true},
+		{"src/build/new.txt", false, true},
+		{"src/" + IgnoreFileName, false, true},
+	}
+	for _, tt := range tests {
+		target := filepath.Join(root, filepath.FromSlash(tt.path))
+		if got := walker.Ignored(root, target, tt.isDir); got != tt.want {
+			t.Errorf("Ignored(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
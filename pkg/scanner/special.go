@@ -0,0 +1,102 @@
+package scanner
+
+import (
+	"io/fs"
+	"os"
+	"sort"
+
+	"github.com/moyu-x/classified-file/pkg/logger"
+)
+
+// 遍历时自动跳过的条目类型
+const (
+	SkipFIFO       = "fifo"
+	SkipSocket     = "socket"
+	SkipDevice     = "device"
+	SkipCharDevice = "char_device"
+	SkipIrregular  = "irregular"
+	// SkipOtherFS 表示启用 OneFileSystem 时未进入的其他文件系统上的目录（挂载点）
+	SkipOtherFS = "other_filesystem"
+)
+
+// skipLabels 是跳过类型在日志和统计输出中的名称
+var skipLabels = map[string]string{
+	SkipFIFO:       "命名管道",
+	SkipSocket:     "套接字",
+	SkipDevice:     "块设备",
+	SkipCharDevice: "字符设备",
+	SkipIrregular:  "非普通文件",
+	SkipOtherFS:    "其他文件系统",
+}
+
+// SkipLabel 返回跳过类型的中文名称
+func SkipLabel(kind string) string {
+	if label, ok := skipLabels[kind]; ok {
+		return label
+	}
+	return kind
+}
+
+// specialType 根据 d_type 判断条目是否为需要跳过的特殊文件
+// 读取命名管道会一直阻塞，设备文件可能无限长，都不能交给哈希计算
+func specialType(mode fs.FileMode) (string, bool) {
+	switch {
+	case mode&os.ModeNamedPipe != 0:
+		return SkipFIFO, true
+	case mode&os.ModeSocket != 0:
+		return SkipSocket, true
+	case mode&os.ModeCharDevice != 0:
+		return SkipCharDevice, true
+	case mode&os.ModeDevice != 0:
+		return SkipDevice, true
+	case mode&os.ModeIrregular != 0:
+		return SkipIrregular, true
+	}
+	return "", false
+}
+
+// linkTargetAllowed 判断不跟随符号链接时是否把链接交给回调：只处理指向普通文件的链接，
+// 哈希计算会打开链接目标，指向命名管道或设备的链接同样会阻塞或读不完，按目标类型计入跳过数量
+func (w *FileWalker) linkTargetAllowed(path string, target fs.FileInfo) bool {
+	if target.Mode().IsRegular() {
+		return true
+	}
+	if kind, ok := specialType(target.Mode()); ok {
+		logger.Get().Debug().Msgf("跳过指向%s的符号链接: %s", SkipLabel(kind), path)
+		w.reportSkipped(kind)
+	} else {
+		logger.Get().Debug().Msgf("未启用跟随符号链接，跳过指向目录的链接: %s", path)
+	}
+	return false
+}
+
+func (w *FileWalker) reportSkipped(kind string) {
+	w.skippedMu.Lock()
+	if w.skipped == nil {
+		w.skipped = make(map[string]int)
+	}
+	w.skipped[kind]++
+	w.skippedMu.Unlock()
+}
+
+// Skipped 返回按类型统计的已跳过特殊文件和挂载点数量
+func (w *FileWalker) Skipped() map[string]int {
+	w.skippedMu.Lock()
+	defer w.skippedMu.Unlock()
+
+	skipped := make(map[string]int, len(w.skipped))
+	for kind, count := range w.skipped {
+		skipped[kind] = count
+	}
+	return skipped
+}
+
+// SkippedKinds 返回按名称排序的跳过类型，便于稳定输出
+func SkippedKinds(skipped map[string]int) []string {
+	kinds := make([]string, 0, len(skipped))
+	for kind := range skipped {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
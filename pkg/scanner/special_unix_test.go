@@ -0,0 +1,125 @@
+//go:build !windows
+
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestFileWalker_Walk_SkipsSpecialFiles(t *testing.T) {
+	tempDir := t.TempDir()
+
+	regular := filepath.Join(tempDir, "regular.txt")
+	if err := os.WriteFile(regular, []byte("content"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	fifo := filepath.Join(tempDir, "pipe")
+	if err := syscall.Mkfifo(fifo, 0644); err != nil {
+		t.Skipf("Skipping FIFO test: %v", err)
+	}
+
+	for _, workers := range []int{1, 4} {
+		walker := NewFileWalker()
+		walker.Workers = workers
+
+		var visited []string
+		err := walker.Walk(tempDir, func(path string, info os.FileInfo) error {
+			visited = append(visited, path)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Walk() error = %v", err)
+		}
+
+		if len(visited) != 1 || visited[0] != regular {
+			t.Errorf("Workers=%d: expected only %s, got %v", workers, regular, visited)
+		}
+
+		if got := walker.Skipped()[SkipFIFO]; got != 1 {
+			t.Errorf("Workers=%d: expected 1 skipped FIFO, got %d", workers, got)
+		}
+	}
+
+	walker := NewFileWalker()
+	count, err := walker.CountFiles([]string{fifo})
+	if err != nil {
+		t.Fatalf("CountFiles() error = %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected FIFO root to be skipped, got %d files", count)
+	}
+}
+
+func TestFileWalker_SkipsLinksToSpecialFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	root := filepath.Join(tempDir, "root")
+	if err := os.MkdirAll(filepath.Join(root, "dir"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	regular := filepath.Join(root, "regular.txt")
+	if err := os.WriteFile(regular, []byte("content"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	// 命名管道放在扫描范围外，只能通过符号链接访问
+	fifo := filepath.Join(tempDir, "pipe")
+	if err := syscall.Mkfifo(fifo, 0644); err != nil {
+		t.Skipf("Skipping FIFO test: %v", err)
+	}
+
+	links := map[string]string{
+		"to_regular": regular,
+		"to_fifo":    fifo,
+		"to_dir":     filepath.Join(root, "dir"),
+	}
+	var paths []string
+	for name, target := range links {
+		link := filepath.Join(root, name)
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("Skipping symlink test: %v", err)
+		}
+		paths = append(paths, link)
+	}
+	toRegular := filepath.Join(root, "to_regular")
+
+	for _, workers := range []int{1, 4} {
+		walker := NewFileWalker()
+		walker.Workers = workers
+
+		var visited []string
+		err := walker.Walk(root, func(path string, info os.FileInfo) error {
+			visited = append(visited, path)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Walk() error = %v", err)
+		}
+
+		if len(visited) != 2 || visited[0] != regular || visited[1] != toRegular {
+			t.Errorf("Workers=%d: expected %s and %s, got %v", workers, regular, toRegular, visited)
+		}
+		if got := walker.Skipped(); len(got) != 1 || got[SkipFIFO] != 1 {
+			t.Errorf("Workers=%d: expected 1 skipped FIFO, got %v", workers, got)
+		}
+	}
+
+	walker := NewFileWalker()
+	var listed []string
+	err := walker.WalkList(paths, func(path string, info os.FileInfo) error {
+		listed = append(listed, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkList() error = %v", err)
+	}
+	if len(listed) != 1 || listed[0] != toRegular {
+		t.Errorf("Expected only %s from list, got %v", toRegular, listed)
+	}
+	if got := walker.Skipped(); len(got) != 1 || got[SkipFIFO] != 1 {
+		t.Errorf("Expected 1 skipped FIFO from list, got %v", got)
+	}
+}
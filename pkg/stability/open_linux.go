@@ -0,0 +1,72 @@
+package stability
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/moyu-x/classified-file/pkg/scanner"
+)
+
+// scanWriters 扫描 /proc/*/fd，返回被以写方式打开的普通文件，跳过进程 exclude 和无权限查看的进程
+func scanWriters(exclude int) (map[scanner.FileID]bool, error) {
+	procs, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+
+	writers := make(map[scanner.FileID]bool)
+	for _, proc := range procs {
+		pid, err := strconv.Atoi(proc.Name())
+		if err != nil || pid == exclude {
+			continue
+		}
+
+		procDir := filepath.Join("/proc", proc.Name())
+		fds, err := os.ReadDir(filepath.Join(procDir, "fd"))
+		if err != nil {
+			continue
+		}
+
+		for _, fd := range fds {
+			info, err := os.Stat(filepath.Join(procDir, "fd", fd.Name()))
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			if !openedForWrite(filepath.Join(procDir, "fdinfo", fd.Name())) {
+				continue
+			}
+			if id, ok := scanner.GetFileID(info); ok {
+				writers[id] = true
+			}
+		}
+	}
+
+	return writers, nil
+}
+
+// openedForWrite 读取 fdinfo 中的 flags，判断是否以 O_WRONLY 或 O_RDWR 打开
+func openedForWrite(fdinfo string) bool {
+	file, err := os.Open(fdinfo)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	lines := bufio.NewScanner(file)
+	for lines.Scan() {
+		value, ok := strings.CutPrefix(lines.Text(), "flags:")
+		if !ok {
+			continue
+		}
+		flags, err := strconv.ParseUint(strings.TrimSpace(value), 8, 64)
+		if err != nil {
+			return false
+		}
+		return flags&(syscall.O_WRONLY|syscall.O_RDWR) != 0
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package stability
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/moyu-x/classified-file/pkg/scanner"
+)
+
+func TestScanWriters(t *testing.T) {
+	tempDir := t.TempDir()
+
+	writing := filepath.Join(tempDir, "writing.bin")
+	file, err := os.Create(writing)
+	if err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	defer file.Close()
+
+	reading := filepath.Join(tempDir, "reading.bin")
+	if err := os.WriteFile(reading, []byte("data"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	reader, err := os.Open(reading)
+	if err != nil {
+		t.Fatalf("Failed to open file: %v", err)
+	}
+	defer reader.Close()
+
+	// 不排除当前进程，使测试进程自己打开的文件可见
+	writers, err := scanWriters(-1)
+	if err != nil {
+		t.Fatalf("scanWriters() error = %v", err)
+	}
+
+	for path, want := range map[string]bool{writing: true, reading: false} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("Failed to stat file: %v", err)
+		}
+		id, _ := scanner.GetFileID(info)
+		if writers[id] != want {
+			t.Errorf("writers[%s] = %v, want %v", filepath.Base(path), writers[id], want)
+		}
+	}
+
+	// 当前进程打开的文件不算被其他进程写入
+	checker := &Checker{CheckOpen: true}
+	info, _ := os.Stat(writing)
+	if err := checker.Check(writing, info); err != nil {
+		t.Errorf("Files opened by this process should not be busy, got %v", err)
+	}
+}
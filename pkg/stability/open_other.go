@@ -0,0 +1,10 @@
+//go:build !linux
+
+package stability
+
+import "github.com/moyu-x/classified-file/pkg/scanner"
+
+// scanWriters 只在 Linux 上通过 /proc 实现，其他平台不检查打开的文件
+func scanWriters(exclude int) (map[scanner.FileID]bool, error) {
+	return nil, nil
+}
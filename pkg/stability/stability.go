@@ -0,0 +1,159 @@
+package stability
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/moyu-x/classified-file/pkg/logger"
+	"github.com/moyu-x/classified-file/pkg/scanner"
+)
+
+// openScanTTL 是打开文件快照的有效期，避免为每个文件重新扫描 /proc
+const openScanTTL = time.Second
+
+// ErrBusy 表示文件仍在被写入，暂时不能处理
+var ErrBusy = errors.New("文件仍在写入")
+
+// ErrRecent 表示文件最近被修改，稳定期尚未结束。调用方应将文件加入 Deferred，
+// 在本轮处理结束后用 Recheck 统一复查，而不是逐个等待
+var ErrRecent = fmt.Errorf("%w: 最近被修改", ErrBusy)
+
+// Checker 在删除、移动或分类文件之前判断文件是否已经写入完成
+type Checker struct {
+	// Interval 文件最后一次修改后必须保持不变的时间，为 0 时不检查
+	// 修改时间早于 Interval 的文件直接视为稳定，否则返回 ErrRecent，由 Recheck 到期后比较大小和修改时间
+	Interval time.Duration
+	// CheckOpen 为 true 时跳过被其他进程以写方式打开的文件，仅 Linux 支持（扫描 /proc/*/fd）
+	CheckOpen bool
+
+	mu        sync.Mutex
+	writers   map[scanner.FileID]bool
+	scannedAt time.Time
+}
+
+// Enabled 判断是否需要检查，checker 为 nil 时返回 false
+func (c *Checker) Enabled() bool {
+	return c != nil && (c.Interval > 0 || c.CheckOpen)
+}
+
+// Check 判断文件是否可以处理，文件仍在写入时返回包装了 ErrBusy 的错误
+func (c *Checker) Check(path string, info os.FileInfo) error {
+	if !c.Enabled() {
+		return nil
+	}
+
+	if c.Interval > 0 {
+		if err := c.checkStable(path, info); err != nil {
+			return err
+		}
+	}
+
+	if c.CheckOpen && c.openForWrite(info) {
+		return fmt.Errorf("%w: 被其他进程以写方式打开", ErrBusy)
+	}
+
+	return nil
+}
+
+// checkStable 确认文件最后一次修改已经超过 Interval，否则返回 ErrRecent
+func (c *Checker) checkStable(path string, info os.FileInfo) error {
+	if time.Since(info.ModTime()) >= c.Interval {
+		return nil
+	}
+
+	logger.Get().Debug().Msgf("文件最近被修改，本轮处理结束后复查: %s", path)
+	return ErrRecent
+}
+
+// Deferred 收集因最近被修改而推迟处理的文件
+type Deferred struct {
+	mu    sync.Mutex
+	files []deferredFile
+}
+
+type deferredFile struct {
+	path string
+	info os.FileInfo
+}
+
+// Add 记录推迟处理的文件，info 为首次检查时的文件信息
+func (d *Deferred) Add(path string, info os.FileInfo) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.files = append(d.files, deferredFile{path: path, info: info})
+}
+
+// Len 返回推迟处理的文件数
+func (d *Deferred) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.files)
+}
+
+func (d *Deferred) take() []deferredFile {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	files := d.files
+	d.files = nil
+	return files
+}
+
+// Recheck 等待推迟文件中最晚的稳定期结束后逐个复查并清空列表，整轮只等待一次。
+// 文件在等待期间没有变化时 fn 的 err 为 nil，info 为复查时的文件信息；
+// 仍有变化或被其他进程以写方式打开时 err 包装 ErrBusy
+func (c *Checker) Recheck(deferred *Deferred, fn func(path string, info os.FileInfo, err error)) {
+	files := deferred.take()
+	if len(files) == 0 {
+		return
+	}
+
+	var deadline time.Time
+	for _, file := range files {
+		if due := file.info.ModTime().Add(c.Interval); due.After(deadline) {
+			deadline = due
+		}
+	}
+	if wait := time.Until(deadline); wait > 0 {
+		logger.Get().Info().Msgf("%d 个文件最近被修改，等待 %v 后复查", len(files), wait.Round(time.Millisecond))
+		time.Sleep(wait)
+	}
+
+	for _, file := range files {
+		current, err := os.Stat(file.path)
+		switch {
+		case err != nil:
+			fn(file.path, file.info, err)
+		case current.Size() != file.info.Size() || !current.ModTime().Equal(file.info.ModTime()):
+			fn(file.path, current, fmt.Errorf("%w: %v 内仍有变化", ErrBusy, c.Interval))
+		case c.CheckOpen && c.openForWrite(current):
+			fn(file.path, current, fmt.Errorf("%w: 被其他进程以写方式打开", ErrBusy))
+		default:
+			fn(file.path, current, nil)
+		}
+	}
+}
+
+// openForWrite 判断文件是否被其他进程以写方式打开，平台不支持时返回 false
+func (c *Checker) openForWrite(info os.FileInfo) bool {
+	id, ok := scanner.GetFileID(info)
+	if !ok {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.writers == nil || time.Since(c.scannedAt) > openScanTTL {
+		writers, err := scanWriters(os.Getpid())
+		if err != nil {
+			logger.Get().Warn().Err(err).Msg("检查打开的文件失败")
+		}
+		c.writers = writers
+		c.scannedAt = time.Now()
+	}
+
+	return c.writers[id]
+}
@@ -0,0 +1,100 @@
+package stability
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestChecker_Check_Disabled(t *testing.T) {
+	var nilChecker *Checker
+	if err := nilChecker.Check("missing", nil); err != nil {
+		t.Errorf("nil checker should allow every file, got %v", err)
+	}
+	if (&Checker{}).Enabled() {
+		t.Errorf("Zero checker should be disabled")
+	}
+}
+
+func TestChecker_Check_Interval(t *testing.T) {
+	tempDir := t.TempDir()
+	checker := &Checker{Interval: 200 * time.Millisecond}
+
+	old := filepath.Join(tempDir, "old.txt")
+	if err := os.WriteFile(old, []byte("done"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(old, past, past); err != nil {
+		t.Fatalf("Failed to set times: %v", err)
+	}
+	info, _ := os.Stat(old)
+
+	start := time.Now()
+	if err := checker.Check(old, info); err != nil {
+		t.Errorf("Old file should be stable, got %v", err)
+	}
+	if time.Since(start) >= checker.Interval {
+		t.Errorf("Old file should not wait for the interval")
+	}
+
+	// 最近被修改的文件不在检查时等待，而是推迟到本轮结束后统一复查
+	var deferred Deferred
+	fresh := filepath.Join(tempDir, "fresh.txt")
+	if err := os.WriteFile(fresh, []byte("done"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	info, _ = os.Stat(fresh)
+	start = time.Now()
+	if err := checker.Check(fresh, info); !errors.Is(err, ErrRecent) || !errors.Is(err, ErrBusy) {
+		t.Errorf("Fresh file should be deferred, got %v", err)
+	}
+	if time.Since(start) >= checker.Interval {
+		t.Errorf("Check should not wait for the interval")
+	}
+	deferred.Add(fresh, info)
+
+	growing := filepath.Join(tempDir, "download.part")
+	if err := os.WriteFile(growing, []byte("part"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	info, _ = os.Stat(growing)
+	if err := checker.Check(growing, info); !errors.Is(err, ErrRecent) {
+		t.Errorf("Fresh file should be deferred, got %v", err)
+	}
+	deferred.Add(growing, info)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		os.WriteFile(growing, []byte("partial data"), 0644)
+	}()
+
+	if deferred.Len() != 2 {
+		t.Fatalf("Expected 2 deferred files, got %d", deferred.Len())
+	}
+
+	results := make(map[string]error)
+	start = time.Now()
+	checker.Recheck(&deferred, func(path string, info os.FileInfo, err error) {
+		results[path] = err
+	})
+	elapsed := time.Since(start)
+
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 rechecked files, got %d", len(results))
+	}
+	if err := results[fresh]; err != nil {
+		t.Errorf("Unchanged fresh file should be stable after the interval, got %v", err)
+	}
+	if err := results[growing]; !errors.Is(err, ErrBusy) {
+		t.Errorf("File written during the interval should be busy, got %v", err)
+	}
+	// 整轮只等待一次，而不是每个文件各等待一个稳定期
+	if elapsed >= 2*checker.Interval {
+		t.Errorf("Recheck should wait once for all files, took %v", elapsed)
+	}
+	if deferred.Len() != 0 {
+		t.Errorf("Recheck should clear deferred files, got %d", deferred.Len())
+	}
+}
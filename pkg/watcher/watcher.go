@@ -0,0 +1,270 @@
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/moyu-x/classified-file/pkg/logger"
+	"github.com/moyu-x/classified-file/pkg/scanner"
+)
+
+// DefaultSettle 是默认的稳定等待时间，文件在这段时间内没有变化才会被处理
+const DefaultSettle = 2 * time.Second
+
+// Watcher 监视目录中新建、写入或移入的文件，文件稳定后交给处理函数
+type Watcher struct {
+	// Settle 是文件最后一次变化后等待的时间，避免处理仍在写入的下载文件
+	Settle time.Duration
+
+	walker   *scanner.FileWalker
+	fsw      *fsnotify.Watcher
+	roots    []string
+	excludes []string
+	pending  map[string]*pendingFile
+}
+
+// pendingFile 记录等待稳定的文件最后一次变化的时间和当时的大小、修改时间
+type pendingFile struct {
+	seen    time.Time
+	size    int64
+	modTime time.Time
+	// notBefore 是尚未满足最小年龄的文件下次检查的时间
+	notBefore time.Time
+}
+
+// New 创建 Watcher，隐藏文件、忽略文件、符号链接、特殊文件和过滤条件的规则与 walker 相同
+func New(walker *scanner.FileWalker, settle time.Duration) *Watcher {
+	if settle <= 0 {
+		settle = DefaultSettle
+	}
+	return &Watcher{
+		Settle:  settle,
+		walker:  walker,
+		pending: make(map[string]*pendingFile),
+	}
+}
+
+// Exclude 忽略给定目录中的变化，用于排除处理结果的输出目录（分类目标目录、移动目标目录）
+func (w *Watcher) Exclude(paths ...string) {
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		w.excludes = append(w.excludes, filepath.Clean(path))
+	}
+}
+
+// Run 监视 roots 及其子目录直到 ctx 结束，handle 在调用方 goroutine 中串行执行
+func (w *Watcher) Run(ctx context.Context, roots []string, handle func(path string, info os.FileInfo)) error {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("创建文件监视器失败: %w", err)
+	}
+	defer fsw.Close()
+	w.fsw = fsw
+
+	for _, root := range roots {
+		if abs, err := filepath.Abs(root); err == nil {
+			root = abs
+		}
+		w.roots = append(w.roots, filepath.Clean(root))
+	}
+	for _, root := range w.roots {
+		w.addTree(root, false)
+	}
+	logger.Get().Info().Msgf("开始监视 %d 个目录，文件稳定 %v 后处理，按 Ctrl+C 结束", len(w.roots), w.Settle)
+
+	ticker := time.NewTicker(w.tickInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Get().Info().Msgf("停止监视，%d 个文件尚未稳定，未处理", len(w.pending))
+			return nil
+		case event, ok := <-fsw.Events:
+			if !ok {
+				return nil
+			}
+			w.handleEvent(event)
+		case err, ok := <-fsw.Errors:
+			if !ok {
+				return nil
+			}
+			logger.Get().Error().Err(err).Msg("文件监视出错")
+		case now := <-ticker.C:
+			w.flushSettled(now, handle)
+		}
+	}
+}
+
+func (w *Watcher) tickInterval() time.Duration {
+	interval := w.Settle / 4
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+	return interval
+}
+
+func (w *Watcher) handleEvent(event fsnotify.Event) {
+	path := filepath.Clean(event.Name)
+	if w.excluded(path) {
+		return
+	}
+
+	switch {
+	case event.Has(fsnotify.Remove), event.Has(fsnotify.Rename):
+		// 移出或删除，移入的新路径会收到单独的 Create 事件
+		delete(w.pending, path)
+	case event.Has(fsnotify.Create), event.Has(fsnotify.Write):
+		info, err := os.Lstat(path)
+		if err != nil {
+			delete(w.pending, path)
+			return
+		}
+		if info.IsDir() {
+			if event.Has(fsnotify.Create) {
+				// 新建或移入的目录中可能已经有文件
+				w.addTree(path, true)
+			}
+			return
+		}
+		w.touch(path, info)
+	}
+}
+
+// addTree 监视目录及其所有子目录，enqueue 为 true 时将其中已有的文件加入等待队列
+func (w *Watcher) addTree(dir string, enqueue bool) {
+	root := w.rootOf(dir)
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			w.walker.Errors.Add(path, scanner.OpReadDir, err)
+			if entry != nil && entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !entry.IsDir() {
+			if enqueue && !w.walker.Ignored(root, path, false) {
+				if info, err := entry.Info(); err == nil {
+					w.touch(path, info)
+				}
+			}
+			return nil
+		}
+
+		if path != root {
+			if w.excluded(path) || !w.allowDir(root, path) || w.walker.Ignored(root, path, true) {
+				return filepath.SkipDir
+			}
+		}
+		if err := w.fsw.Add(path); err != nil {
+			logger.Get().Error().Err(err).Msgf("监视目录失败: %s", path)
+			w.walker.Errors.Add(path, scanner.OpReadDir, err)
+			return filepath.SkipDir
+		}
+		logger.Get().Debug().Msgf("监视目录: %s", path)
+		return nil
+	})
+}
+
+func (w *Watcher) allowDir(root, path string) bool {
+	if !w.walker.IncludeHidden && strings.HasPrefix(filepath.Base(path), ".") {
+		return false
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return true
+	}
+	return w.walker.Filter.AllowDir(rel)
+}
+
+// touch 记录文件的一次变化，重新开始等待
+func (w *Watcher) touch(path string, info os.FileInfo) {
+	if w.excluded(path) {
+		return
+	}
+	w.pending[path] = &pendingFile{
+		seen:    time.Now(),
+		size:    info.Size(),
+		modTime: info.ModTime(),
+	}
+}
+
+// flushSettled 处理已经稳定的文件：距最后一次事件超过 Settle，且大小和修改时间都没有变化
+func (w *Watcher) flushSettled(now time.Time, handle func(path string, info os.FileInfo)) {
+	var settled []string
+	for path, file := range w.pending {
+		if now.Sub(file.seen) < w.Settle || now.Before(file.notBefore) {
+			continue
+		}
+
+		info, err := os.Lstat(path)
+		if err != nil {
+			delete(w.pending, path)
+			continue
+		}
+		if info.Size() != file.size || !info.ModTime().Equal(file.modTime) {
+			w.touch(path, info)
+			continue
+		}
+		// 尚未满足 --min-age 的文件留在队列中，到期后再处理
+		if wait := w.walker.Filter.MinAgeWait(info); wait > 0 {
+			file.notBefore = now.Add(wait)
+			continue
+		}
+		settled = append(settled, path)
+	}
+	sort.Strings(settled)
+
+	for _, path := range settled {
+		delete(w.pending, path)
+		root := w.rootOf(path)
+		// 忽略文件可能在文件加入队列后才创建或修改，处理前按最新的规则再检查一次
+		if w.walker.Ignored(root, path, false) {
+			logger.Get().Debug().Msgf("忽略文件规则匹配: %s", path)
+			continue
+		}
+		logger.Get().Debug().Msgf("文件已稳定: %s", path)
+		w.walker.WalkListIn(root, []string{path}, func(path string, info os.FileInfo) error {
+			handle(path, info)
+			return nil
+		})
+	}
+}
+
+// rootOf 返回包含 path 的监视根目录
+func (w *Watcher) rootOf(path string) string {
+	best := path
+	for _, root := range w.roots {
+		if isWithin(path, root) && (best == path || len(root) > len(best)) {
+			best = root
+		}
+	}
+	return best
+}
+
+func (w *Watcher) excluded(path string) bool {
+	for _, exclude := range w.excludes {
+		if isWithin(path, exclude) {
+			return true
+		}
+	}
+	return false
+}
+
+func isWithin(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
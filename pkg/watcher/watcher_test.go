@@ -0,0 +1,250 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/moyu-x/classified-file/pkg/filter"
+	"github.com/moyu-x/classified-file/pkg/scanner"
+)
+
+func TestWatcher_Run(t *testing.T) {
+	tempDir := t.TempDir()
+	output := filepath.Join(tempDir, "output")
+	if err := os.MkdirAll(output, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	walker := scanner.NewFileWalker()
+	walker.IncludeHidden = false
+	w := New(walker, 50*time.Millisecond)
+	w.Exclude(output)
+
+	handled := make(chan string, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := w.Run(ctx, []string{tempDir}, func(path string, info os.FileInfo) {
+			handled <- path
+		}); err != nil {
+			t.Errorf("Run() error = %v", err)
+		}
+	}()
+
+	// 等待监视开始
+	time.Sleep(100 * time.Millisecond)
+
+	newFile := filepath.Join(tempDir, "new.txt")
+	if err := os.WriteFile(newFile, []byte("new"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	// 在其他位置写好后整体移入的目录
+	staging := t.TempDir()
+	if err := os.WriteFile(filepath.Join(staging, "moved.txt"), []byte("moved"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	movedDir := filepath.Join(tempDir, "album")
+	if err := os.Rename(staging, movedDir); err != nil {
+		t.Skipf("Cannot move directory into watched root: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(output, "result.txt"), []byte("out"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, ".hidden"), []byte("hidden"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	want := map[string]bool{
+		newFile:                              false,
+		filepath.Join(movedDir, "moved.txt"): false,
+	}
+	timeout := time.After(5 * time.Second)
+	for remaining := len(want); remaining > 0; {
+		select {
+		case path := <-handled:
+			found, ok := want[path]
+			if !ok {
+				t.Errorf("Unexpected file handled: %s", path)
+				continue
+			}
+			if found {
+				t.Errorf("File handled twice: %s", path)
+			}
+			want[path] = true
+			remaining--
+		case <-timeout:
+			t.Fatalf("Timed out waiting for files, got %v", want)
+		}
+	}
+
+	// 排除目录和隐藏文件不会被处理
+	select {
+	case path := <-handled:
+		t.Errorf("Unexpected file handled: %s", path)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	cancel()
+	wg.Wait()
+}
+
+func TestWatcher_Run_IgnoreFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	build := filepath.Join(tempDir, "build")
+	if err := os.MkdirAll(build, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	ignore := filepath.Join(tempDir, scanner.IgnoreFileName)
+	if err := os.WriteFile(ignore, []byte("build/\ncache/\n*.tmp\n"), 0644); err != nil {
+		t.Fatalf("Failed to create ignore file: %v", err)
+	}
+
+	w := New(scanner.NewFileWalker(), 50*time.Millisecond)
+
+	handled := make(chan string, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := w.Run(ctx, []string{tempDir}, func(path string, info os.FileInfo) {
+			handled <- path
+		}); err != nil {
+			t.Errorf("Run() error = %v", err)
+		}
+	}()
+
+	// 等待监视开始
+	time.Sleep(100 * time.Millisecond)
+
+	// 被忽略的已有目录、新建的被忽略目录和被忽略的文件名都不会被处理
+	cache := filepath.Join(tempDir, "cache")
+	if err := os.MkdirAll(cache, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	for _, path := range []string{
+		filepath.Join(build, "protected_new.txt"),
+		filepath.Join(cache, "entry.txt"),
+		filepath.Join(tempDir, "download.tmp"),
+	} {
+		if err := os.WriteFile(path, []byte("ignored"), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+	keep := filepath.Join(tempDir, "keep.txt")
+	if err := os.WriteFile(keep, []byte("keep"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	select {
+	case path := <-handled:
+		if path != keep {
+			t.Errorf("Expected %s to be handled first, got %s", keep, path)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for %s", keep)
+	}
+
+	select {
+	case path := <-handled:
+		t.Errorf("Ignored file handled: %s", path)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	cancel()
+	wg.Wait()
+}
+
+func TestWatcher_flushSettled(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "download.part")
+	if err := os.WriteFile(path, []byte("partial"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat file: %v", err)
+	}
+
+	w := New(scanner.NewFileWalker(), time.Second)
+	w.touch(path, info)
+
+	var handled []string
+	handle := func(path string, info os.FileInfo) {
+		handled = append(handled, path)
+	}
+
+	// 未到稳定时间
+	w.flushSettled(time.Now(), handle)
+	if len(handled) != 0 {
+		t.Fatalf("File should not be handled before settle delay")
+	}
+
+	// 等待期间文件仍在写入，重新开始等待
+	if err := os.WriteFile(path, []byte("partial data"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	w.flushSettled(time.Now().Add(2*time.Second), handle)
+	if len(handled) != 0 {
+		t.Fatalf("File that changed while settling should not be handled")
+	}
+
+	w.flushSettled(time.Now().Add(2*time.Second), handle)
+	if len(handled) != 1 || handled[0] != path {
+		t.Fatalf("Expected settled file to be handled, got %v", handled)
+	}
+	if len(w.pending) != 0 {
+		t.Errorf("Handled file should be removed from pending")
+	}
+}
+
+func TestWatcher_flushSettled_MinAge(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "new.txt")
+	if err := os.WriteFile(path, []byte("new"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat file: %v", err)
+	}
+
+	minAge := 200 * time.Millisecond
+	fileFilter, err := filter.New(filter.Options{MinAge: minAge})
+	if err != nil {
+		t.Fatalf("filter.New() error = %v", err)
+	}
+	walker := scanner.NewFileWalker()
+	walker.Filter = fileFilter
+
+	w := New(walker, 10*time.Millisecond)
+	w.touch(path, info)
+
+	var handled []string
+	handle := func(path string, info os.FileInfo) {
+		handled = append(handled, path)
+	}
+
+	// 已稳定但尚未满足最小年龄，留在队列中
+	w.flushSettled(time.Now().Add(w.Settle), handle)
+	if len(handled) != 0 || len(w.pending) != 1 {
+		t.Fatalf("File younger than min age should stay pending, handled %v", handled)
+	}
+
+	time.Sleep(minAge)
+	w.flushSettled(time.Now().Add(w.Settle), handle)
+	if len(handled) != 1 || handled[0] != path {
+		t.Fatalf("Expected file to be handled once old enough, got %v", handled)
+	}
+	if len(w.pending) != 0 {
+		t.Errorf("Handled file should be removed from pending")
+	}
+}